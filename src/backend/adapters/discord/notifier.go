@@ -0,0 +1,259 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"growth-mvp/backend/adapters/egress"
+	"growth-mvp/backend/domain"
+)
+
+// SettingUsername overrides the name the webhook posts under.
+const SettingUsername = "username"
+
+// Discord limits; longer values make it reject the whole message.
+const (
+	maxUsernameLength   = 80
+	maxTitleLength      = 256
+	maxFieldNameLength  = 256
+	maxFieldValueLength = 1024
+)
+
+const embedColor = 0x2ecc71
+
+var webhookHosts = []string{"discord.com", "discordapp.com"}
+
+// flagSuppressNotifications posts the message without a push notification.
+const flagSuppressNotifications = 1 << 12
+
+// Notifier implements domain.Notifier for Discord webhooks. The webhook URL
+// carries its token and is the integration secret; "username" is the only,
+// optional setting.
+type Notifier struct {
+	httpClient *http.Client
+	outbound   egress.Config
+}
+
+// NewNotifier accepts webhook URLs on discord.com, discordapp.com and
+// cfg.AllowedHosts, and dials through the egress guard.
+func NewNotifier(cfg egress.Config) *Notifier {
+	return &Notifier{httpClient: egress.NewHTTPClient(cfg), outbound: cfg}
+}
+
+func (n *Notifier) Normalize(settings map[string]string, secret string) (map[string]string, string, error) {
+	var fields []domain.FieldError
+	out := map[string]string{}
+
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		if key != SettingUsername {
+			fields = append(fields, domain.FieldError{Field: "settings." + key, Code: "unknown", Message: fmt.Sprintf("unknown discord setting %q", key)})
+		}
+	}
+
+	if username := strings.TrimSpace(settings[SettingUsername]); username != "" {
+		if utf8.RuneCountInString(username) > maxUsernameLength {
+			fields = append(fields, domain.FieldError{Field: "settings.username", Code: "max", Message: fmt.Sprintf("username must be at most %d characters", maxUsernameLength)})
+		}
+
+		out[SettingUsername] = username
+	}
+
+	secret = strings.TrimSpace(secret)
+
+	if err := n.validateWebhookURL(secret); err != nil {
+		fields = append(fields, domain.FieldError{Field: "secret", Code: "url", Message: err.Error()})
+	}
+
+	if len(fields) > 0 {
+		return nil, "", domain.ValidationFailed(fields)
+	}
+
+	return out, secret, nil
+}
+
+func (n *Notifier) Mask(settings map[string]string) map[string]string {
+	return maps.Clone(settings)
+}
+
+// Send treats a 429 as a domain.RateLimitError carrying Discord's retry_after,
+// and any other non-2xx answer as a failed attempt.
+func (n *Notifier) Send(ctx context.Context, integration domain.Integration, notification domain.Notification) error {
+	body, err := json.Marshal(renderEmbed(integration.Settings[SettingUsername], notification))
+
+	if err != nil {
+		return fmt.Errorf("marshal discord payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, integration.Secret, bytes.NewReader(body))
+
+	if err != nil {
+		return fmt.Errorf("create discord request: %w", redactURLError(err))
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+
+	if err != nil {
+		return fmt.Errorf("discord webhook request failed: %w", redactURLError(err))
+	}
+
+	defer resp.Body.Close()
+	answer, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+
+	var out struct {
+		Message    string  `json:"message"`
+		RetryAfter float64 `json:"retry_after"`
+		Global     bool    `json:"global"`
+	}
+
+	_ = json.Unmarshal(answer, &out)
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &domain.RateLimitError{
+			RetryAfter: retryAfter(out.RetryAfter, resp.Header),
+			Err:        fmt.Errorf("discord webhook is rate limited (status=429, global=%t)", out.Global),
+		}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if out.Message == "" {
+			out.Message = "unknown discord error"
+		}
+		return fmt.Errorf("discord webhook failed (status=%d): %s", resp.StatusCode, out.Message)
+	}
+
+	return nil
+}
+
+type embedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type embed struct {
+	Title  string       `json:"title"`
+	Color  int          `json:"color"`
+	Fields []embedField `json:"fields,omitempty"`
+}
+
+type allowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+type payload struct {
+	Username        string          `json:"username,omitempty"`
+	Embeds          []embed         `json:"embeds"`
+	AllowedMentions allowedMentions `json:"allowed_mentions"`
+	Flags           int             `json:"flags,omitempty"`
+}
+
+// renderEmbed sends the notification as one embed. Mentions are disabled so a
+// customer name like "@everyone" cannot ping the server; a silent
+// notification is flagged SUPPRESS_NOTIFICATIONS.
+func renderEmbed(username string, notification domain.Notification) payload {
+	e := embed{Title: truncate(notification.Subject, maxTitleLength), Color: embedColor}
+
+	for _, field := range notification.Fields {
+		value := field.Value
+
+		if value == "" {
+			value = "-"
+		}
+
+		e.Fields = append(e.Fields, embedField{
+			Name:   truncate(field.Name, maxFieldNameLength),
+			Value:  truncate(value, maxFieldValueLength),
+			Inline: true,
+		})
+	}
+
+	out := payload{Username: username, Embeds: []embed{e}, AllowedMentions: allowedMentions{Parse: []string{}}}
+
+	if notification.Silent {
+		out.Flags = flagSuppressNotifications
+	}
+
+	return out
+}
+
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+
+	if len(runes) <= limit {
+		return text
+	}
+
+	return string(runes[:limit-1]) + "…"
+}
+
+// retryAfter prefers retry_after from the body, which Discord gives in
+// fractional seconds, over the whole-second Retry-After header.
+func retryAfter(seconds float64, header http.Header) time.Duration {
+	if seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	if v, err := strconv.Atoi(header.Get("Retry-After")); err == nil && v > 0 {
+		return time.Duration(v) * time.Second
+	}
+
+	return 0
+}
+
+// validateWebhookURL accepts the URLs Discord issues for webhooks,
+// https://discord.com/api/webhooks/<id>/<token>, also on discordapp.com and
+// on a host the operator allowed, e.g. a relay. The URL carries the token, so
+// it must be https.
+func (n *Notifier) validateWebhookURL(rawURL string) error {
+	if rawURL == "" {
+		return errors.New("discord webhook URL must be non-empty")
+	}
+
+	parsed, err := url.Parse(rawURL)
+
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return errors.New("discord webhook URL must be an absolute https URL")
+	}
+
+	if host := parsed.Hostname(); !slices.Contains(webhookHosts, host) && !n.outbound.Allowed(host) {
+		return errors.New("discord webhook URL must point at discord.com or discordapp.com")
+	}
+
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+
+	if len(parts) != 4 || parts[0] != "api" || parts[1] != "webhooks" || parts[2] == "" || parts[3] == "" {
+		return errors.New("discord webhook URL must look like https://discord.com/api/webhooks/<id>/<token>")
+	}
+
+	return nil
+}
+
+// The token is part of the URL path, and net/http reports the full URL in
+// *url.Error, so it is masked before the error is logged or stored.
+func redactURLError(err error) error {
+	var urlErr *url.Error
+
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+
+	return &url.Error{
+		Op:  urlErr.Op,
+		URL: domain.MaskURL(urlErr.URL),
+		Err: urlErr.Err,
+	}
+}
@@ -0,0 +1,119 @@
+package egress
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned when a connection would reach an address
+// inside the operator's network.
+var ErrBlockedAddress = errors.New("destination address is not allowed")
+
+// sharedAddressSpace is the carrier-grade NAT range, which is as internal as
+// RFC 1918 on most clouds.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Config describes outbound connections to addresses that shops supply.
+type Config struct {
+	Timeout time.Duration
+	// AllowedHosts are reached even when they resolve to an internal address;
+	// the operator lists relays or mail servers of its own network here.
+	AllowedHosts []string
+	// RootCAs replaces the system roots, e.g. for a private CA.
+	RootCAs *x509.CertPool
+}
+
+// Allowed reports whether host is in AllowedHosts.
+func (c Config) Allowed(host string) bool {
+	for _, allowed := range c.AllowedHosts {
+		if strings.EqualFold(strings.TrimSpace(allowed), host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Blocked reports whether addr is loopback, private, link-local (which
+// includes the 169.254.169.254 metadata service), multicast or unspecified.
+func Blocked(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	return !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() ||
+		addr.IsUnspecified() || sharedAddressSpace.Contains(addr)
+}
+
+// Control is a net.Dialer Control function that refuses blocked addresses.
+// It runs for the address actually dialed, after DNS resolution, so a name
+// that resolves or rebinds to an internal address is refused as well.
+func Control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+
+	if err != nil {
+		return err
+	}
+
+	addr, err := netip.ParseAddr(host)
+
+	if err != nil || Blocked(addr) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+	}
+
+	return nil
+}
+
+// DialContext dials like net.Dialer, refusing blocked addresses unless the
+// host is one of cfg.AllowedHosts.
+func (c Config) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: c.Timeout}
+	host, _, err := net.SplitHostPort(address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.Allowed(host) {
+		dialer.Control = Control
+	}
+
+	return dialer.DialContext(ctx, network, address)
+}
+
+// TLSConfig returns the TLS settings for serverName.
+func (c Config) TLSConfig(serverName string) *tls.Config {
+	return &tls.Config{ServerName: serverName, RootCAs: c.RootCAs, MinVersion: tls.VersionTLS12}
+}
+
+// NewHTTPClient returns a client that dials through DialContext, ignores
+// proxy settings, since a proxy would dial on its behalf, and does not follow
+// redirects.
+func NewHTTPClient(cfg Config) *http.Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+
+	return &http.Client{
+		Timeout: cfg.Timeout,
+		Transport: &http.Transport{
+			DialContext:         cfg.DialContext,
+			TLSClientConfig:     &tls.Config{RootCAs: cfg.RootCAs, MinVersion: tls.VersionTLS12},
+			TLSHandshakeTimeout: cfg.Timeout,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,80 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"time"
+
+	"growth-mvp/backend/domain"
+)
+
+// buildMessage renders a multipart/alternative message with the text and HTML
+// forms of the notification; clients show the HTML one when they can.
+func buildMessage(from string, notification domain.Notification, now time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	if err := writePart(parts, "text/plain; charset=utf-8", notification.Text); err != nil {
+		return nil, err
+	}
+
+	if notification.HTML != "" {
+		if err := writePart(parts, "text/html; charset=utf-8", notification.HTML); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, fmt.Errorf("build email: %w", err)
+	}
+
+	subject := notification.Subject
+
+	if subject == "" {
+		subject = notification.Text
+	}
+
+	sender, err := mail.ParseAddress(from)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", from, err)
+	}
+
+	var msg bytes.Buffer
+
+	fmt.Fprintf(&msg, "From: %s\r\n", sender.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", notification.Recipient)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <order-%d-%d.%d@growth-mvp>\r\n", notification.OrderID, notification.ID, now.UnixNano())
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", parts.Boundary())
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+func writePart(parts *multipart.Writer, contentType, content string) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	w, err := parts.CreatePart(header)
+
+	if err != nil {
+		return fmt.Errorf("build email: %w", err)
+	}
+
+	qp := quotedprintable.NewWriter(w)
+
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return fmt.Errorf("build email: %w", err)
+	}
+
+	return qp.Close()
+}
@@ -0,0 +1,356 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"growth-mvp/backend/adapters/egress"
+	"growth-mvp/backend/domain"
+)
+
+// Settings of an email integration. Only "to" is required when the server has
+// a global SMTP relay; setting "host" makes the shop use its own server, with
+// the integration secret as the SMTP password. "from" and the other server
+// settings need "host": the global relay always sends as the operator.
+const (
+	SettingTo       = "to"
+	SettingFrom     = "from"
+	SettingHost     = "host"
+	SettingPort     = "port"
+	SettingUsername = "username"
+	SettingTLS      = "tls"
+)
+
+// TLS modes: "tls" connects over TLS (usually port 465), "starttls" upgrades
+// a plain connection and fails if the server cannot, "none" sends in clear
+// text and is meant for local relays.
+const (
+	TLSNone     = "none"
+	TLSStartTLS = "starttls"
+	TLSImplicit = "tls"
+)
+
+const maxRecipients = 10
+
+// localHosts are the hosts smtp.PlainAuth sends credentials to without TLS.
+var localHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+var settingKeys = []string{SettingTo, SettingFrom, SettingHost, SettingPort, SettingUsername, SettingTLS}
+
+// Config is an SMTP server. The global one comes from SMTP_* variables and is
+// used by integrations without their own host.
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	TLS      string
+}
+
+// Notifier implements domain.RecipientNotifier: every address in "to" is a
+// separate recipient with its own send log row.
+type Notifier struct {
+	global   Config
+	outbound egress.Config
+	now      func() time.Time
+}
+
+// NewNotifier reaches the global relay directly and a shop's own server
+// through the egress guard, so a shop cannot probe the operator's network;
+// outbound.Timeout bounds a whole send.
+func NewNotifier(global Config, outbound egress.Config) *Notifier {
+	if outbound.Timeout <= 0 {
+		outbound.Timeout = 10 * time.Second
+	}
+
+	if global.TLS == "" {
+		global.TLS = TLSStartTLS
+	}
+
+	return &Notifier{global: global, outbound: outbound, now: time.Now}
+}
+
+func (n *Notifier) Normalize(settings map[string]string, secret string) (map[string]string, string, error) {
+	var fields []domain.FieldError
+	out := map[string]string{}
+
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		if !slices.Contains(settingKeys, key) {
+			fields = append(fields, domain.FieldError{Field: "settings." + key, Code: "unknown", Message: fmt.Sprintf("unknown email setting %q", key)})
+			continue
+		}
+
+		if value := strings.TrimSpace(settings[key]); value != "" {
+			out[key] = value
+		}
+	}
+
+	if to, err := normalizeAddressList(out[SettingTo]); err != nil {
+		fields = append(fields, domain.FieldError{Field: "settings.to", Code: "email", Message: err.Error()})
+	} else {
+		out[SettingTo] = to
+	}
+
+	if from, ok := out[SettingFrom]; ok {
+		if address, err := mail.ParseAddress(from); err != nil {
+			fields = append(fields, domain.FieldError{Field: "settings.from", Code: "email", Message: "from must be an email address"})
+		} else {
+			out[SettingFrom] = address.String()
+		}
+	}
+
+	if out[SettingHost] == "" {
+		for _, key := range []string{SettingFrom, SettingPort, SettingUsername, SettingTLS} {
+			if out[key] != "" {
+				fields = append(fields, domain.FieldError{Field: "settings." + key, Code: "required_with", Message: key + " requires settings.host"})
+			}
+		}
+
+		if n.global.Host == "" {
+			fields = append(fields, domain.FieldError{Field: "settings.host", Code: "required", Message: "host is required: the server has no global SMTP relay"})
+		}
+
+		if n.global.Host != "" && n.global.From == "" {
+			fields = append(fields, domain.FieldError{Field: "settings.host", Code: "required", Message: "host is required: the server has no global sender"})
+		}
+	} else {
+		if port := out[SettingPort]; port != "" {
+			if v, err := strconv.Atoi(port); err != nil || v <= 0 || v > 65535 {
+				fields = append(fields, domain.FieldError{Field: "settings.port", Code: "number", Message: "port must be between 1 and 65535"})
+			}
+		}
+
+		switch out[SettingTLS] {
+		case "", TLSNone, TLSStartTLS, TLSImplicit:
+		default:
+			fields = append(fields, domain.FieldError{Field: "settings.tls", Code: "oneof", Message: "tls must be none, starttls or tls"})
+		}
+
+		// net/smtp refuses to send credentials in clear text to anything but localhost
+		if out[SettingTLS] == TLSNone && out[SettingUsername] != "" && !slices.Contains(localHosts, out[SettingHost]) {
+			fields = append(fields, domain.FieldError{Field: "settings.tls", Code: "conflict", Message: "username requires tls or starttls"})
+		}
+
+		if out[SettingFrom] == "" {
+			fields = append(fields, domain.FieldError{Field: "settings.from", Code: "required", Message: "from is required with your own SMTP host"})
+		}
+	}
+
+	if len(fields) > 0 {
+		return nil, "", domain.ValidationFailed(fields)
+	}
+
+	return out, strings.TrimSpace(secret), nil
+}
+
+func (n *Notifier) Mask(settings map[string]string) map[string]string {
+	out := maps.Clone(settings)
+
+	if out == nil {
+		out = map[string]string{}
+	}
+
+	if to := out[SettingTo]; to != "" {
+		masked := strings.Split(to, ",")
+
+		for i, address := range masked {
+			masked[i] = domain.MaskEmail(address)
+		}
+
+		out[SettingTo] = strings.Join(masked, ",")
+	}
+
+	if username := out[SettingUsername]; username != "" {
+		out[SettingUsername] = domain.MaskEmail(username)
+	}
+
+	return out
+}
+
+func (n *Notifier) Recipients(integration domain.Integration) []string {
+	if integration.Settings[SettingTo] == "" {
+		return nil
+	}
+
+	return strings.Split(integration.Settings[SettingTo], ",")
+}
+
+func (n *Notifier) MaskRecipient(recipient string) string {
+	return domain.MaskEmail(recipient)
+}
+
+// Send delivers one message to notification.Recipient only, so a rejected
+// address does not fail or repeat the others.
+func (n *Notifier) Send(ctx context.Context, integration domain.Integration, notification domain.Notification) error {
+	server, own := n.server(integration)
+	message, err := buildMessage(server.From, notification, n.now())
+
+	if err != nil {
+		return err
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, n.outbound.Timeout)
+	defer cancel()
+
+	client, err := n.dial(sendCtx, server, own)
+
+	if err != nil {
+		return err
+	}
+
+	defer client.Close()
+
+	if err := deliver(client, server, notification.Recipient, message); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// server is the shop's own SMTP server, reported by own, when the integration
+// names a host, and the global one otherwise. "from" is only used with the
+// shop's server; rows saved before it required "host" may still carry it.
+func (n *Notifier) server(integration domain.Integration) (Config, bool) {
+	settings := integration.Settings
+	server := n.global
+	own := settings[SettingHost] != ""
+
+	if own {
+		server = Config{Host: settings[SettingHost], Port: 587, Username: settings[SettingUsername], Password: integration.Secret, From: settings[SettingFrom], TLS: TLSStartTLS}
+
+		if port, err := strconv.Atoi(settings[SettingPort]); err == nil {
+			server.Port = port
+		}
+
+		if settings[SettingTLS] != "" {
+			server.TLS = settings[SettingTLS]
+		}
+	}
+
+	if server.Port == 0 {
+		server.Port = 587
+	}
+
+	return server, own
+}
+
+func (n *Notifier) dial(ctx context.Context, server Config, own bool) (*smtp.Client, error) {
+	addr := net.JoinHostPort(server.Host, strconv.Itoa(server.Port))
+	dial := (&net.Dialer{}).DialContext
+
+	if own {
+		dial = n.outbound.DialContext
+	}
+
+	conn, err := dial(ctx, "tcp", addr)
+
+	if err != nil {
+		return nil, fmt.Errorf("connect to smtp server: %w", err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	tlsConfig := n.outbound.TLSConfig(server.Host)
+
+	if server.TLS == TLSImplicit {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, server.Host)
+
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("smtp handshake: %w", err)
+	}
+
+	if server.TLS == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, errors.New("smtp server does not support STARTTLS")
+		}
+
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+func deliver(client *smtp.Client, server Config, recipient string, message []byte) error {
+	if server.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", server.Username, server.Password, server.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	from, err := mail.ParseAddress(server.From)
+
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", server.From, err)
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("smtp MAIL FROM: %w", err)
+	}
+
+	if err := client.Rcpt(recipient); err != nil {
+		return fmt.Errorf("smtp RCPT TO: %w", err)
+	}
+
+	w, err := client.Data()
+
+	if err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("write message: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+
+	return nil
+}
+
+func normalizeAddressList(raw string) (string, error) {
+	if raw == "" {
+		return "", errors.New("to must list at least one address")
+	}
+
+	list, err := mail.ParseAddressList(raw)
+
+	if err != nil {
+		return "", errors.New("to must be a comma-separated list of email addresses")
+	}
+
+	if len(list) > maxRecipients {
+		return "", fmt.Errorf("to may list at most %d addresses", maxRecipients)
+	}
+
+	addresses := make([]string, 0, len(list))
+
+	for _, address := range list {
+		if !slices.Contains(addresses, address.Address) {
+			addresses = append(addresses, address.Address)
+		}
+	}
+
+	return strings.Join(addresses, ","), nil
+}
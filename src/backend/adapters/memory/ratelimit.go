@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"growth-mvp/backend/domain"
+)
+
+const sweepInterval = time.Minute
+
+type bucket struct {
+	windowStart time.Time
+	resetAt     time.Time
+	hits        int
+}
+
+// RateLimitStore keeps fixed-window counters in process memory. It is only
+// correct when a single API instance serves the traffic.
+type RateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewRateLimitStore() *RateLimitStore {
+	return &RateLimitStore{buckets: map[string]*bucket{}}
+}
+
+func (s *RateLimitStore) Hit(_ context.Context, key string, limit domain.RateLimit, now time.Time) (domain.RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for k, b := range s.buckets {
+			if !now.Before(b.resetAt) {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	windowStart := domain.RateLimitWindow(limit, now)
+	b, ok := s.buckets[key]
+	if !ok || !b.windowStart.Equal(windowStart) {
+		b = &bucket{windowStart: windowStart, resetAt: windowStart.Add(limit.Window)}
+		s.buckets[key] = b
+	}
+	b.hits++
+
+	return domain.NewRateLimitResult(limit, windowStart, b.hits), nil
+}
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus implements domain.Metrics and api.Metrics on its own registry,
+// so tests can create as many instances as they like.
+type Prometheus struct {
+	registry *prometheus.Registry
+
+	httpDuration     *prometheus.HistogramVec
+	telegramDuration *prometheus.HistogramVec
+	deliveries       *prometheus.CounterVec
+	retries          *prometheus.CounterVec
+	pending          prometheus.Gauge
+}
+
+func NewPrometheus() *Prometheus {
+	m := &Prometheus{
+		registry: prometheus.NewRegistry(),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by route template.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		telegramDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "telegram_send_duration_seconds",
+			Help:    "Telegram Bot API sendMessage attempts by error class (none on success).",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		}, []string{"error_class"}),
+		deliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "notification_deliveries_total",
+			Help: "Notifications finalized after all attempts, by channel and outcome.",
+		}, []string{"channel", "outcome"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "notification_retries_total",
+			Help: "Send attempts beyond the first one, by channel.",
+		}, []string{"channel"}),
+		pending: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "notification_queue_depth",
+			Help: "Notifications reserved but not yet finalized by this instance.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpDuration,
+		m.telegramDuration,
+		m.deliveries,
+		m.retries,
+		m.pending,
+	)
+
+	return m
+}
+
+// Register adds extra collectors, such as the pgxpool stats.
+func (m *Prometheus) Register(c prometheus.Collector) error {
+	return m.registry.Register(c)
+}
+
+func (m *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Prometheus) ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	m.httpDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+func (m *Prometheus) ObserveTelegramRequest(errorClass string, duration time.Duration) {
+	m.telegramDuration.WithLabelValues(errorClass).Observe(duration.Seconds())
+}
+
+func (m *Prometheus) ObserveDelivery(channel, outcome string, attempts int) {
+	m.deliveries.WithLabelValues(channel, outcome).Inc()
+
+	if attempts > 1 {
+		m.retries.WithLabelValues(channel).Add(float64(attempts - 1))
+	}
+}
+
+func (m *Prometheus) AddPendingNotifications(delta int) {
+	m.pending.Add(float64(delta))
+}
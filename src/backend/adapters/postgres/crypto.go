@@ -0,0 +1,222 @@
+package postgres
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const dataKeySize = 32
+
+// TokenCipher implements envelope encryption for secrets stored in the database:
+// every secret gets its own data key, and the data key is wrapped with one of
+// the key encryption keys from the keyring. Rotation only needs to re-wrap data keys.
+type TokenCipher struct {
+	keys     map[string]cipher.AEAD
+	activeID string
+}
+
+type EncryptedToken struct {
+	Ciphertext []byte
+	DataKey    []byte
+	KeyID      string
+}
+
+func NewTokenCipher(keys map[string][]byte, activeID string) (*TokenCipher, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("token encryption keyring is empty")
+	}
+
+	if _, ok := keys[activeID]; !ok {
+		return nil, fmt.Errorf("active token encryption key %q is not in the keyring", activeID)
+	}
+
+	c := &TokenCipher{keys: make(map[string]cipher.AEAD, len(keys)), activeID: activeID}
+
+	for id, key := range keys {
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("token encryption key %q: %w", id, err)
+		}
+		c.keys[id] = aead
+	}
+
+	return c, nil
+}
+
+func (c *TokenCipher) ActiveKeyID() string {
+	return c.activeID
+}
+
+func (c *TokenCipher) Encrypt(plaintext string) (EncryptedToken, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return EncryptedToken{}, err
+	}
+
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return EncryptedToken{}, err
+	}
+
+	ciphertext, err := sealGCM(dataAEAD, []byte(plaintext), nil)
+	if err != nil {
+		return EncryptedToken{}, err
+	}
+
+	wrapped, err := c.wrap(c.activeID, dataKey)
+	if err != nil {
+		return EncryptedToken{}, err
+	}
+
+	return EncryptedToken{Ciphertext: ciphertext, DataKey: wrapped, KeyID: c.activeID}, nil
+}
+
+func (c *TokenCipher) Decrypt(token EncryptedToken) (string, error) {
+	dataKey, err := c.unwrap(token.KeyID, token.DataKey)
+	if err != nil {
+		return "", err
+	}
+
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := openGCM(dataAEAD, token.Ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt token: %w", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// Rewrap re-encrypts the data key with the active key, leaving the ciphertext untouched.
+func (c *TokenCipher) Rewrap(token EncryptedToken) (EncryptedToken, error) {
+	if token.KeyID == c.activeID {
+		return token, nil
+	}
+
+	dataKey, err := c.unwrap(token.KeyID, token.DataKey)
+	if err != nil {
+		return EncryptedToken{}, err
+	}
+
+	wrapped, err := c.wrap(c.activeID, dataKey)
+	if err != nil {
+		return EncryptedToken{}, err
+	}
+
+	return EncryptedToken{Ciphertext: token.Ciphertext, DataKey: wrapped, KeyID: c.activeID}, nil
+}
+
+func (c *TokenCipher) wrap(keyID string, dataKey []byte) ([]byte, error) {
+	return sealGCM(c.keys[keyID], dataKey, []byte(keyID))
+}
+
+func (c *TokenCipher) unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := c.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("token encryption key %q is not in the keyring", keyID)
+	}
+
+	dataKey, err := openGCM(aead, wrapped, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key with %q: %w", keyID, err)
+	}
+
+	return dataKey, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func sealGCM(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func openGCM(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// LoadKeyring reads "id:base64key" entries separated by commas (spec) or by
+// newlines (file). The active key defaults to the last entry.
+func LoadKeyring(spec, file string) (map[string][]byte, string, error) {
+	entries := strings.Split(spec, ",")
+
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, "", fmt.Errorf("open keyring file: %w", err)
+		}
+		defer f.Close()
+
+		fileEntries, err := readKeyringLines(f)
+		if err != nil {
+			return nil, "", fmt.Errorf("read keyring file: %w", err)
+		}
+		entries = append(entries, fileEntries...)
+	}
+
+	keys := map[string][]byte{}
+	lastID := ""
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(entry, ":")
+		id = strings.TrimSpace(id)
+		if !ok || id == "" {
+			return nil, "", fmt.Errorf("keyring entry must look like id:base64key")
+		}
+
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, "", fmt.Errorf("token encryption key %q is not valid base64", id)
+		}
+		if len(key) != 32 {
+			return nil, "", fmt.Errorf("token encryption key %q must be 32 bytes, got %d", id, len(key))
+		}
+
+		keys[id] = key
+		lastID = id
+	}
+
+	return keys, lastID, nil
+}
+
+func readKeyringLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
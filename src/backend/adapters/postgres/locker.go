@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AdvisoryLocker implements domain.Locker with session-level Postgres
+// advisory locks. A held lock pins one pool connection until it is unlocked;
+// if the instance dies, Postgres releases the lock with the session.
+type AdvisoryLocker struct {
+	db *pgxpool.Pool
+}
+
+func NewAdvisoryLocker(db *pgxpool.Pool) *AdvisoryLocker {
+	return &AdvisoryLocker{db: db}
+}
+
+func (l *AdvisoryLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	conn, err := l.db.Acquire(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var acquired bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock(hashtextextended($1, 0))`, name).Scan(&acquired); err != nil {
+		conn.Release()
+		return nil, false, err
+	}
+	if !acquired {
+		conn.Release()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		// the job's context may be cancelled by now
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if _, err := conn.Exec(unlockCtx, `SELECT pg_advisory_unlock(hashtextextended($1, 0))`, name); err != nil {
+			// a session that may still hold the lock must not go back to the pool
+			_ = conn.Conn().Close(unlockCtx)
+		}
+		conn.Release()
+	}
+	return unlock, true, nil
+}
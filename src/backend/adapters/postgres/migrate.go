@@ -1,12 +1,15 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
@@ -40,3 +43,19 @@ func RunMigrations(databaseURL, migrationsPath string) error {
 	}
 	return nil
 }
+
+// MigrationVersion reads golang-migrate's bookkeeping table through the pool,
+// so readiness checks don't open a second connection per probe.
+func MigrationVersion(ctx context.Context, db *pgxpool.Pool) (uint, bool, error) {
+	var version int64
+	var dirty bool
+
+	err := db.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return uint(version), dirty, nil
+}
@@ -3,68 +3,851 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"growth-mvp/backend/domain"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const foreignKeyViolation = "23503"
+
+func mapShopForeignKey(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation && strings.HasSuffix(pgErr.ConstraintName, "_shop_id_fkey") {
+		return domain.ErrShopNotFound
+	}
+	return err
+}
+
+type ShopRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewShopRepository(db *pgxpool.Pool) *ShopRepository {
+	return &ShopRepository{db: db}
+}
+
+func (r *ShopRepository) Create(ctx context.Context, input domain.ShopInput) (domain.Shop, error) {
+	const q = `
+INSERT INTO shops (name, timezone, locale, currency, quiet_hours, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+RETURNING id, name, timezone, locale, currency, quiet_hours, created_at, updated_at`
+	var out domain.Shop
+	err := r.db.QueryRow(ctx, q, input.Name, input.Timezone, input.Locale, input.Currency, input.QuietHours).
+		Scan(&out.ID, &out.Name, &out.Timezone, &out.Locale, &out.Currency, &out.QuietHours, &out.CreatedAt, &out.UpdatedAt)
+	return out, err
+}
+
+func (r *ShopRepository) GetByID(ctx context.Context, shopID int64) (domain.Shop, error) {
+	const q = `SELECT id, name, timezone, locale, currency, quiet_hours, created_at, updated_at FROM shops WHERE id = $1`
+	var out domain.Shop
+	err := r.db.QueryRow(ctx, q, shopID).
+		Scan(&out.ID, &out.Name, &out.Timezone, &out.Locale, &out.Currency, &out.QuietHours, &out.CreatedAt, &out.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Shop{}, domain.ErrShopNotFound
+	}
+	return out, err
+}
+
+func (r *ShopRepository) List(ctx context.Context, limit, offset int) ([]domain.Shop, error) {
+	const q = `
+SELECT id, name, timezone, locale, currency, quiet_hours, created_at, updated_at
+FROM shops
+ORDER BY id
+LIMIT $1 OFFSET $2`
+	rows, err := r.db.Query(ctx, q, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.Shop, 0, limit)
+	for rows.Next() {
+		var shop domain.Shop
+		if err := rows.Scan(&shop.ID, &shop.Name, &shop.Timezone, &shop.Locale, &shop.Currency, &shop.QuietHours, &shop.CreatedAt, &shop.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, shop)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *ShopRepository) Update(ctx context.Context, shopID int64, input domain.ShopInput) (domain.Shop, error) {
+	const q = `
+UPDATE shops
+SET name = $2, timezone = $3, locale = $4, currency = $5, quiet_hours = $6, updated_at = NOW()
+WHERE id = $1
+RETURNING id, name, timezone, locale, currency, quiet_hours, created_at, updated_at`
+	var out domain.Shop
+	err := r.db.QueryRow(ctx, q, shopID, input.Name, input.Timezone, input.Locale, input.Currency, input.QuietHours).
+		Scan(&out.ID, &out.Name, &out.Timezone, &out.Locale, &out.Currency, &out.QuietHours, &out.CreatedAt, &out.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Shop{}, domain.ErrShopNotFound
+	}
+	return out, err
+}
+
+func (r *ShopRepository) Delete(ctx context.Context, shopID int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM shops WHERE id = $1`, shopID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrShopNotFound
+	}
+	return nil
+}
+
 type IntegrationRepository struct {
+	db     *pgxpool.Pool
+	cipher *TokenCipher
+}
+
+func NewIntegrationRepository(db *pgxpool.Pool, cipher *TokenCipher) *IntegrationRepository {
+	return &IntegrationRepository{db: db, cipher: cipher}
+}
+
+const integrationColumns = `id, shop_id, channel, settings, secret, secret_ciphertext, secret_dek, secret_key_id, enabled, batch_window_seconds, created_at, updated_at`
+
+func (r *IntegrationRepository) Upsert(ctx context.Context, shopID int64, channel domain.ChannelType, input domain.ConnectIntegrationInput) (domain.Integration, error) {
+	const q = `
+INSERT INTO integrations (shop_id, channel, settings, secret, secret_ciphertext, secret_dek, secret_key_id, enabled, batch_window_seconds, created_at, updated_at)
+VALUES ($1, $2, $3, NULL, $4, $5, $6, $7, $8, NOW(), NOW())
+ON CONFLICT (shop_id, channel)
+DO UPDATE SET
+  settings = EXCLUDED.settings,
+  secret = NULL,
+  secret_ciphertext = EXCLUDED.secret_ciphertext,
+  secret_dek = EXCLUDED.secret_dek,
+  secret_key_id = EXCLUDED.secret_key_id,
+  enabled = EXCLUDED.enabled,
+  batch_window_seconds = EXCLUDED.batch_window_seconds,
+  updated_at = NOW()
+RETURNING ` + integrationColumns
+
+	secret, err := r.cipher.Encrypt(input.Secret)
+	if err != nil {
+		return domain.Integration{}, err
+	}
+
+	settings := input.Settings
+	if settings == nil {
+		settings = map[string]string{}
+	}
+
+	out, err := r.scanIntegration(r.db.QueryRow(ctx, q, shopID, channel, settings, secret.Ciphertext, secret.DataKey, secret.KeyID, input.Enabled, input.BatchWindowSeconds))
+	return out, mapShopForeignKey(err)
+}
+
+func (r *IntegrationRepository) Get(ctx context.Context, shopID int64, channel domain.ChannelType) (domain.Integration, bool, error) {
+	q := `SELECT ` + integrationColumns + ` FROM integrations WHERE shop_id = $1 AND channel = $2`
+	out, err := r.scanIntegration(r.db.QueryRow(ctx, q, shopID, channel))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Integration{}, false, nil
+		}
+		return domain.Integration{}, false, err
+	}
+	return out, true, nil
+}
+
+func (r *IntegrationRepository) ListByShop(ctx context.Context, shopID int64) ([]domain.Integration, error) {
+	q := `SELECT ` + integrationColumns + ` FROM integrations WHERE shop_id = $1 ORDER BY channel`
+	rows, err := r.db.Query(ctx, q, shopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.Integration{}
+	for rows.Next() {
+		integration, err := r.scanIntegration(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, integration)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Update replaces settings as a whole; the service merges them beforehand.
+func (r *IntegrationRepository) Update(ctx context.Context, shopID int64, channel domain.ChannelType, input domain.UpdateIntegrationInput) (domain.Integration, error) {
+	const q = `
+UPDATE integrations
+SET
+  settings = COALESCE($3, settings),
+  secret = CASE WHEN $4::bytea IS NULL THEN secret ELSE NULL END,
+  secret_ciphertext = COALESCE($4, secret_ciphertext),
+  secret_dek = COALESCE($5, secret_dek),
+  secret_key_id = COALESCE($6, secret_key_id),
+  enabled = COALESCE($7, enabled),
+  batch_window_seconds = COALESCE($8, batch_window_seconds),
+  updated_at = NOW()
+WHERE shop_id = $1 AND channel = $2
+RETURNING ` + integrationColumns
+
+	var settings any
+	if input.Settings != nil {
+		settings = input.Settings
+	}
+
+	var secret EncryptedToken
+	var keyID *string
+	if input.Secret != nil {
+		var err error
+		if secret, err = r.cipher.Encrypt(*input.Secret); err != nil {
+			return domain.Integration{}, err
+		}
+		keyID = &secret.KeyID
+	}
+
+	out, err := r.scanIntegration(r.db.QueryRow(ctx, q, shopID, channel, settings, secret.Ciphertext, secret.DataKey, keyID, input.Enabled, input.BatchWindowSeconds))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Integration{}, domain.ErrShopNotIntegrated
+	}
+	return out, err
+}
+
+// Deleting an integration keeps its notification_log rows with integration_id
+// set to NULL, so the send history survives a disconnect.
+func (r *IntegrationRepository) Delete(ctx context.Context, shopID int64, channel domain.ChannelType) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM integrations WHERE shop_id = $1 AND channel = $2`, shopID, channel)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrShopNotIntegrated
+	}
+	return nil
+}
+
+// ReencryptTokens moves every secret that is still stored in plaintext or wrapped
+// with a non-active key onto the active key. It returns the number of updated rows.
+func (r *IntegrationRepository) ReencryptTokens(ctx context.Context) (int, error) {
+	return reencryptSecrets(ctx, r.db, r.cipher, "integrations")
+}
+
+// reencryptSecrets does ReencryptTokens for any table with the secret,
+// secret_ciphertext, secret_dek and secret_key_id columns.
+func reencryptSecrets(ctx context.Context, db *pgxpool.Pool, cipher *TokenCipher, table string) (int, error) {
+	selectQ := `
+SELECT id, secret, secret_ciphertext, secret_dek, secret_key_id
+FROM ` + table + `
+WHERE secret IS NOT NULL OR secret_key_id IS DISTINCT FROM $1
+ORDER BY id`
+	updateQ := `
+UPDATE ` + table + `
+SET secret = NULL, secret_ciphertext = $2, secret_dek = $3, secret_key_id = $4
+WHERE id = $1`
+
+	rows, err := db.Query(ctx, selectQ, cipher.ActiveKeyID())
+	if err != nil {
+		return 0, err
+	}
+
+	type pending struct {
+		id    int64
+		token EncryptedToken
+	}
+	var updates []pending
+
+	for rows.Next() {
+		var id int64
+		var plaintext *string
+		var stored EncryptedToken
+		var keyID *string
+		if err := rows.Scan(&id, &plaintext, &stored.Ciphertext, &stored.DataKey, &keyID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		var token EncryptedToken
+		if plaintext != nil {
+			token, err = cipher.Encrypt(*plaintext)
+		} else if keyID != nil {
+			stored.KeyID = *keyID
+			token, err = cipher.Rewrap(stored)
+		} else {
+			err = fmt.Errorf("%s %d has no secret", table, id)
+		}
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		updates = append(updates, pending{id: id, token: token})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, u := range updates {
+		if _, err := db.Exec(ctx, updateQ, u.id, u.token.Ciphertext, u.token.DataKey, u.token.KeyID); err != nil {
+			return 0, err
+		}
+	}
+	return len(updates), nil
+}
+
+func (r *IntegrationRepository) scanIntegration(row pgx.Row) (domain.Integration, error) {
+	var out domain.Integration
+	var plaintext *string
+	var stored EncryptedToken
+	var keyID *string
+	err := row.Scan(
+		&out.ID, &out.ShopID, &out.Channel, &out.Settings, &plaintext, &stored.Ciphertext, &stored.DataKey, &keyID, &out.Enabled, &out.BatchWindowSeconds, &out.CreatedAt, &out.UpdatedAt,
+	)
+	if err != nil {
+		return domain.Integration{}, err
+	}
+
+	switch {
+	case keyID != nil:
+		stored.KeyID = *keyID
+		out.Secret, err = r.cipher.Decrypt(stored)
+	case plaintext != nil:
+		out.Secret = *plaintext
+	}
+	return out, err
+}
+
+type WebhookRepository struct {
+	db     *pgxpool.Pool
+	cipher *TokenCipher
+}
+
+func NewWebhookRepository(db *pgxpool.Pool, cipher *TokenCipher) *WebhookRepository {
+	return &WebhookRepository{db: db, cipher: cipher}
+}
+
+const webhookColumns = `id, shop_id, url, secret, secret_ciphertext, secret_dek, secret_key_id, events, enabled, created_at, updated_at`
+
+func (r *WebhookRepository) Create(ctx context.Context, shopID int64, input domain.WebhookInput) (domain.WebhookSubscription, error) {
+	const q = `
+INSERT INTO webhook_subscriptions (shop_id, url, secret_ciphertext, secret_dek, secret_key_id, events, enabled, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+RETURNING ` + webhookColumns
+
+	secret, err := r.cipher.Encrypt(input.Secret)
+	if err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+
+	out, err := r.scanWebhook(r.db.QueryRow(ctx, q, shopID, input.URL, secret.Ciphertext, secret.DataKey, secret.KeyID, input.Events, input.Enabled))
+	return out, mapShopForeignKey(err)
+}
+
+func (r *WebhookRepository) Get(ctx context.Context, shopID, webhookID int64) (domain.WebhookSubscription, error) {
+	q := `SELECT ` + webhookColumns + ` FROM webhook_subscriptions WHERE shop_id = $1 AND id = $2`
+	out, err := r.scanWebhook(r.db.QueryRow(ctx, q, shopID, webhookID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.WebhookSubscription{}, domain.ErrWebhookNotFound
+	}
+	return out, err
+}
+
+func (r *WebhookRepository) ListByShop(ctx context.Context, shopID int64) ([]domain.WebhookSubscription, error) {
+	q := `SELECT ` + webhookColumns + ` FROM webhook_subscriptions WHERE shop_id = $1 ORDER BY id`
+	rows, err := r.db.Query(ctx, q, shopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.WebhookSubscription{}
+	for rows.Next() {
+		subscription, err := r.scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, subscription)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *WebhookRepository) Update(ctx context.Context, shopID, webhookID int64, input domain.UpdateWebhookInput) (domain.WebhookSubscription, error) {
+	const q = `
+UPDATE webhook_subscriptions
+SET
+  url = COALESCE($3, url),
+  secret = CASE WHEN $4::bytea IS NULL THEN secret ELSE NULL END,
+  secret_ciphertext = COALESCE($4, secret_ciphertext),
+  secret_dek = COALESCE($5, secret_dek),
+  secret_key_id = COALESCE($6, secret_key_id),
+  events = COALESCE($7, events),
+  enabled = COALESCE($8, enabled),
+  updated_at = NOW()
+WHERE shop_id = $1 AND id = $2
+RETURNING ` + webhookColumns
+
+	var secret EncryptedToken
+	var keyID *string
+	if input.Secret != nil {
+		var err error
+		if secret, err = r.cipher.Encrypt(*input.Secret); err != nil {
+			return domain.WebhookSubscription{}, err
+		}
+		keyID = &secret.KeyID
+	}
+
+	out, err := r.scanWebhook(r.db.QueryRow(ctx, q, shopID, webhookID, input.URL, secret.Ciphertext, secret.DataKey, keyID, input.Events, input.Enabled))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.WebhookSubscription{}, domain.ErrWebhookNotFound
+	}
+	return out, err
+}
+
+// Deleting a subscription keeps its deliveries with webhook_subscription_id
+// set to NULL, like a disconnected integration.
+func (r *WebhookRepository) Delete(ctx context.Context, shopID, webhookID int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE shop_id = $1 AND id = $2`, shopID, webhookID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) ReencryptTokens(ctx context.Context) (int, error) {
+	return reencryptSecrets(ctx, r.db, r.cipher, "webhook_subscriptions")
+}
+
+func (r *WebhookRepository) scanWebhook(row pgx.Row) (domain.WebhookSubscription, error) {
+	var out domain.WebhookSubscription
+	var plaintext *string
+	var stored EncryptedToken
+	var keyID *string
+	err := row.Scan(
+		&out.ID, &out.ShopID, &out.URL, &plaintext, &stored.Ciphertext, &stored.DataKey, &keyID, &out.Events, &out.Enabled, &out.CreatedAt, &out.UpdatedAt,
+	)
+	if err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+
+	switch {
+	case keyID != nil:
+		stored.KeyID = *keyID
+		out.Secret, err = r.cipher.Decrypt(stored)
+	case plaintext != nil:
+		out.Secret = *plaintext
+	}
+	return out, err
+}
+
+type TelegramDestinationRepository struct {
 	db *pgxpool.Pool
 }
 
-func NewIntegrationRepository(db *pgxpool.Pool) *IntegrationRepository {
-	return &IntegrationRepository{db: db}
+func NewTelegramDestinationRepository(db *pgxpool.Pool) *TelegramDestinationRepository {
+	return &TelegramDestinationRepository{db: db}
+}
+
+const destinationColumns = `id, shop_id, integration_id, name, chat_id, rule, enabled, created_at, updated_at`
+
+func (r *TelegramDestinationRepository) Create(ctx context.Context, shopID, integrationID int64, input domain.TelegramDestinationInput) (domain.TelegramDestination, error) {
+	const q = `
+INSERT INTO telegram_destinations (shop_id, integration_id, name, chat_id, rule, enabled, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+RETURNING ` + destinationColumns
+	out, err := scanDestination(r.db.QueryRow(ctx, q, shopID, integrationID, input.Name, input.ChatID, input.Rule, input.Enabled))
+	return out, mapDestinationError(mapShopForeignKey(err))
+}
+
+func (r *TelegramDestinationRepository) Get(ctx context.Context, shopID, destinationID int64) (domain.TelegramDestination, error) {
+	q := `SELECT ` + destinationColumns + ` FROM telegram_destinations WHERE shop_id = $1 AND id = $2`
+	out, err := scanDestination(r.db.QueryRow(ctx, q, shopID, destinationID))
+	return out, mapDestinationError(err)
+}
+
+func (r *TelegramDestinationRepository) ListByShop(ctx context.Context, shopID int64) ([]domain.TelegramDestination, error) {
+	q := `SELECT ` + destinationColumns + ` FROM telegram_destinations WHERE shop_id = $1 ORDER BY id`
+	rows, err := r.db.Query(ctx, q, shopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.TelegramDestination{}
+	for rows.Next() {
+		destination, err := scanDestination(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, destination)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *TelegramDestinationRepository) Update(ctx context.Context, shopID, destinationID int64, input domain.UpdateTelegramDestinationInput) (domain.TelegramDestination, error) {
+	const q = `
+UPDATE telegram_destinations
+SET
+  name = COALESCE($3, name),
+  chat_id = COALESCE($4, chat_id),
+  rule = COALESCE($5, rule),
+  enabled = COALESCE($6, enabled),
+  updated_at = NOW()
+WHERE shop_id = $1 AND id = $2
+RETURNING ` + destinationColumns
+	out, err := scanDestination(r.db.QueryRow(ctx, q, shopID, destinationID, input.Name, input.ChatID, input.Rule, input.Enabled))
+	return out, mapDestinationError(err)
+}
+
+func (r *TelegramDestinationRepository) Delete(ctx context.Context, shopID, destinationID int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM telegram_destinations WHERE shop_id = $1 AND id = $2`, shopID, destinationID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrDestinationNotFound
+	}
+	return nil
+}
+
+func scanDestination(row pgx.Row) (domain.TelegramDestination, error) {
+	var out domain.TelegramDestination
+	err := row.Scan(&out.ID, &out.ShopID, &out.IntegrationID, &out.Name, &out.ChatID, &out.Rule, &out.Enabled, &out.CreatedAt, &out.UpdatedAt)
+	return out, err
+}
+
+func mapDestinationError(err error) error {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return domain.ErrDestinationNotFound
+	case errors.As(err, &pgErr) && pgErr.Code == uniqueViolation:
+		return domain.ErrDestinationExists
+	}
+	return err
+}
+
+type NotificationRuleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewNotificationRuleRepository(db *pgxpool.Pool) *NotificationRuleRepository {
+	return &NotificationRuleRepository{db: db}
+}
+
+const ruleColumns = `id, shop_id, position, name, conditions, action, template, enabled, created_at, updated_at`
+
+func (r *NotificationRuleRepository) Create(ctx context.Context, shopID int64, input domain.NotificationRuleInput) (domain.NotificationRule, error) {
+	const q = `
+INSERT INTO notification_rules (shop_id, position, name, conditions, action, template, enabled, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+RETURNING ` + ruleColumns
+	out, err := scanRule(r.db.QueryRow(ctx, q, shopID, input.Position, input.Name, input.Conditions, input.Action, input.Template, input.Enabled))
+	return out, mapShopForeignKey(err)
+}
+
+func (r *NotificationRuleRepository) Get(ctx context.Context, shopID, ruleID int64) (domain.NotificationRule, error) {
+	q := `SELECT ` + ruleColumns + ` FROM notification_rules WHERE shop_id = $1 AND id = $2`
+	out, err := scanRule(r.db.QueryRow(ctx, q, shopID, ruleID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.NotificationRule{}, domain.ErrRuleNotFound
+	}
+	return out, err
+}
+
+func (r *NotificationRuleRepository) ListByShop(ctx context.Context, shopID int64) ([]domain.NotificationRule, error) {
+	q := `SELECT ` + ruleColumns + ` FROM notification_rules WHERE shop_id = $1 ORDER BY position, id`
+	rows, err := r.db.Query(ctx, q, shopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.NotificationRule{}
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *NotificationRuleRepository) Update(ctx context.Context, shopID, ruleID int64, input domain.UpdateNotificationRuleInput) (domain.NotificationRule, error) {
+	const q = `
+UPDATE notification_rules
+SET
+  position = COALESCE($3, position),
+  name = COALESCE($4, name),
+  conditions = COALESCE($5, conditions),
+  action = COALESCE($6, action),
+  template = COALESCE($7, template),
+  enabled = COALESCE($8, enabled),
+  updated_at = NOW()
+WHERE shop_id = $1 AND id = $2
+RETURNING ` + ruleColumns
+	out, err := scanRule(r.db.QueryRow(ctx, q, shopID, ruleID, input.Position, input.Name, input.Conditions, input.Action, input.Template, input.Enabled))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.NotificationRule{}, domain.ErrRuleNotFound
+	}
+	return out, err
+}
+
+func (r *NotificationRuleRepository) Delete(ctx context.Context, shopID, ruleID int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM notification_rules WHERE shop_id = $1 AND id = $2`, shopID, ruleID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrRuleNotFound
+	}
+	return nil
 }
 
-func (r *IntegrationRepository) Upsert(ctx context.Context, shopID int64, input domain.ConnectTelegramInput) (domain.TelegramIntegration, error) {
+func (r *NotificationRuleRepository) RecordEvaluation(ctx context.Context, evaluation domain.RuleEvaluation) error {
 	const q = `
-INSERT INTO telegram_integrations (shop_id, bot_token, chat_id, enabled, created_at, updated_at)
-VALUES ($1, $2, $3, $4, NOW(), NOW())
-ON CONFLICT (shop_id)
-DO UPDATE SET bot_token = EXCLUDED.bot_token, chat_id = EXCLUDED.chat_id, enabled = EXCLUDED.enabled, updated_at = NOW()
-RETURNING id, shop_id, bot_token, chat_id, enabled, created_at, updated_at`
+INSERT INTO notification_rule_evaluations (order_id, shop_id, rule_id, rule_name, action, template, reason, evaluated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (order_id) DO UPDATE
+SET rule_id = EXCLUDED.rule_id, rule_name = EXCLUDED.rule_name, action = EXCLUDED.action,
+    template = EXCLUDED.template, reason = EXCLUDED.reason, evaluated_at = EXCLUDED.evaluated_at`
+	_, err := r.db.Exec(ctx, q, evaluation.OrderID, evaluation.ShopID, evaluation.RuleID, evaluation.RuleName,
+		evaluation.Action, evaluation.Template, evaluation.Reason, evaluation.EvaluatedAt)
+	return err
+}
+
+func (r *NotificationRuleRepository) GetEvaluation(ctx context.Context, shopID, orderID int64) (domain.RuleEvaluation, error) {
+	const q = `
+SELECT order_id, shop_id, rule_id, rule_name, action, template, reason, evaluated_at
+FROM notification_rule_evaluations
+WHERE shop_id = $1 AND order_id = $2`
+	var out domain.RuleEvaluation
+	err := r.db.QueryRow(ctx, q, shopID, orderID).
+		Scan(&out.OrderID, &out.ShopID, &out.RuleID, &out.RuleName, &out.Action, &out.Template, &out.Reason, &out.EvaluatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.RuleEvaluation{}, domain.ErrEvaluationNotFound
+	}
+	return out, err
+}
+
+func scanRule(row pgx.Row) (domain.NotificationRule, error) {
+	var out domain.NotificationRule
+	err := row.Scan(&out.ID, &out.ShopID, &out.Position, &out.Name, &out.Conditions, &out.Action, &out.Template, &out.Enabled, &out.CreatedAt, &out.UpdatedAt)
+	return out, err
+}
+
+type DigestRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDigestRepository(db *pgxpool.Pool) *DigestRepository {
+	return &DigestRepository{db: db}
+}
+
+const digestColumns = `shop_id, enabled, frequency, time, weekday, sections, top_customers, last_period_end, last_sent_at, last_error, updated_at`
+
+func (r *DigestRepository) Get(ctx context.Context, shopID int64) (domain.DigestSchedule, bool, error) {
+	q := `SELECT ` + digestColumns + ` FROM digest_schedules WHERE shop_id = $1`
+	out, err := scanDigest(r.db.QueryRow(ctx, q, shopID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.DigestSchedule{}, false, nil
+	}
+	if err != nil {
+		return domain.DigestSchedule{}, false, err
+	}
+	return out, true, nil
+}
+
+func (r *DigestRepository) Upsert(ctx context.Context, shopID int64, input domain.DigestScheduleInput) (domain.DigestSchedule, error) {
+	const q = `
+INSERT INTO digest_schedules (shop_id, enabled, frequency, time, weekday, sections, top_customers, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+ON CONFLICT (shop_id) DO UPDATE
+SET
+  enabled = EXCLUDED.enabled,
+  frequency = EXCLUDED.frequency,
+  time = EXCLUDED.time,
+  weekday = EXCLUDED.weekday,
+  sections = EXCLUDED.sections,
+  top_customers = EXCLUDED.top_customers,
+  updated_at = NOW()
+RETURNING ` + digestColumns
+	out, err := scanDigest(r.db.QueryRow(ctx, q, shopID, input.Enabled, input.Frequency, input.Time, input.Weekday, input.Sections, input.TopCustomers))
+	return out, mapShopForeignKey(err)
+}
+
+func (r *DigestRepository) ListEnabled(ctx context.Context) ([]domain.DigestSchedule, error) {
+	q := `SELECT ` + digestColumns + ` FROM digest_schedules WHERE enabled ORDER BY shop_id`
+	rows, err := r.db.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.DigestSchedule{}
+	for rows.Next() {
+		schedule, err := scanDigest(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *DigestRepository) MarkSent(ctx context.Context, shopID int64, periodEnd, sentAt time.Time, errText *string) error {
+	const q = `
+UPDATE digest_schedules
+SET
+  last_period_end = $2,
+  last_sent_at = CASE WHEN $4::text IS NULL THEN $3 ELSE last_sent_at END,
+  last_error = $4
+WHERE shop_id = $1`
+	_, err := r.db.Exec(ctx, q, shopID, periodEnd, sentAt, errText)
+	return err
+}
+
+func (r *DigestRepository) Summary(ctx context.Context, shopID int64, from, to time.Time, top int) (domain.Digest, error) {
+	const totals = `
+SELECT
+  COUNT(*),
+  COALESCE(SUM(total), 0)::float8,
+  COALESCE(AVG(total), 0)::float8,
+  (SELECT COUNT(*) FROM notification_log
+   WHERE shop_id = $1 AND channel <> $4 AND status = 'FAILED' AND error IS DISTINCT FROM 'reserved'
+     AND sent_at >= $2 AND sent_at < $3)
+FROM orders
+WHERE shop_id = $1 AND created_at >= $2 AND created_at < $3`
+	out := domain.Digest{ShopID: shopID, From: from, To: to, TopCustomers: []domain.CustomerTotal{}}
+	err := r.db.QueryRow(ctx, totals, shopID, from, to, domain.ChannelWebhook).
+		Scan(&out.OrderCount, &out.Revenue, &out.AverageCheck, &out.FailedNotifications)
+	if err != nil {
+		return domain.Digest{}, err
+	}
+
+	const customers = `
+SELECT customer_name, COUNT(*), SUM(total)::float8
+FROM orders
+WHERE shop_id = $1 AND created_at >= $2 AND created_at < $3
+GROUP BY customer_name
+ORDER BY SUM(total) DESC, customer_name
+LIMIT $4`
+	rows, err := r.db.Query(ctx, customers, shopID, from, to, top)
+	if err != nil {
+		return domain.Digest{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var customer domain.CustomerTotal
+		if err := rows.Scan(&customer.Name, &customer.Orders, &customer.Total); err != nil {
+			return domain.Digest{}, err
+		}
+		out.TopCustomers = append(out.TopCustomers, customer)
+	}
+	if err := rows.Err(); err != nil {
+		return domain.Digest{}, err
+	}
+	return out, nil
+}
+
+func scanDigest(row pgx.Row) (domain.DigestSchedule, error) {
+	var out domain.DigestSchedule
+	err := row.Scan(&out.ShopID, &out.Enabled, &out.Frequency, &out.Time, &out.Weekday, &out.Sections, &out.TopCustomers,
+		&out.LastPeriodEnd, &out.LastSentAt, &out.LastError, &out.UpdatedAt)
+	return out, err
+}
+
+type OrderRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOrderRepository(db *pgxpool.Pool) *OrderRepository {
+	return &OrderRepository{db: db}
+}
+
+func (r *OrderRepository) Create(ctx context.Context, shopID int64, input domain.CreateOrderInput) (domain.Order, error) {
+	const q = `
+INSERT INTO orders (shop_id, number, total, customer_name, status, created_at)
+VALUES ($1, $2, $3, $4, $5, NOW())
+RETURNING id, shop_id, number, total, customer_name, status, created_at`
+	var out domain.Order
+	err := r.db.QueryRow(ctx, q, shopID, input.Number, input.Total, input.CustomerName, input.Status).
+		Scan(&out.ID, &out.ShopID, &out.Number, &out.Total, &out.CustomerName, &out.Status, &out.CreatedAt)
+	return out, mapShopForeignKey(err)
+}
+
+func (r *OrderRepository) CreateBatch(ctx context.Context, shopID int64, rows []domain.ImportOrderRow) ([]domain.Order, error) {
+	const q = `
+INSERT INTO orders (shop_id, number, total, customer_name, status, created_at)
+VALUES ($1, $2, $3, $4, $5, COALESCE($6, NOW()))
+RETURNING id, shop_id, number, total, customer_name, status, created_at`
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
 
-	var out domain.TelegramIntegration
-	err := r.db.QueryRow(ctx, q, shopID, input.BotToken, input.ChatID, input.Enabled).
-		Scan(&out.ID, &out.ShopID, &out.BotToken, &out.ChatID, &out.Enabled, &out.CreatedAt, &out.UpdatedAt)
-	return out, err
-}
+	batch := &pgx.Batch{}
+	for _, row := range rows {
+		batch.Queue(q, shopID, row.Order.Number, row.Order.Total, row.Order.CustomerName, row.Order.Status, row.CreatedAt)
+	}
 
-func (r *IntegrationRepository) GetByShopID(ctx context.Context, shopID int64) (domain.TelegramIntegration, bool, error) {
-	const q = `SELECT id, shop_id, bot_token, chat_id, enabled, created_at, updated_at FROM telegram_integrations WHERE shop_id = $1`
-	var out domain.TelegramIntegration
-	err := r.db.QueryRow(ctx, q, shopID).Scan(
-		&out.ID, &out.ShopID, &out.BotToken, &out.ChatID, &out.Enabled, &out.CreatedAt, &out.UpdatedAt,
-	)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return domain.TelegramIntegration{}, false, nil
+	results := tx.SendBatch(ctx, batch)
+	out := make([]domain.Order, 0, len(rows))
+	for range rows {
+		var order domain.Order
+		if err := results.QueryRow().Scan(&order.ID, &order.ShopID, &order.Number, &order.Total, &order.CustomerName, &order.Status, &order.CreatedAt); err != nil {
+			results.Close()
+			return nil, mapShopForeignKey(err)
 		}
-		return domain.TelegramIntegration{}, false, err
+		out = append(out, order)
+	}
+	if err := results.Close(); err != nil {
+		return nil, err
 	}
-	return out, true, nil
-}
-
-type OrderRepository struct {
-	db *pgxpool.Pool
-}
 
-func NewOrderRepository(db *pgxpool.Pool) *OrderRepository {
-	return &OrderRepository{db: db}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-func (r *OrderRepository) Create(ctx context.Context, shopID int64, input domain.CreateOrderInput) (domain.Order, error) {
+func (r *OrderRepository) GetByID(ctx context.Context, shopID, orderID int64) (domain.Order, error) {
 	const q = `
-INSERT INTO orders (shop_id, number, total, customer_name, created_at)
-VALUES ($1, $2, $3, $4, NOW())
-RETURNING id, shop_id, number, total, customer_name, created_at`
+SELECT id, shop_id, number, total, customer_name, status, created_at
+FROM orders
+WHERE shop_id = $1 AND id = $2`
 	var out domain.Order
-	err := r.db.QueryRow(ctx, q, shopID, input.Number, input.Total, input.CustomerName).
-		Scan(&out.ID, &out.ShopID, &out.Number, &out.Total, &out.CustomerName, &out.CreatedAt)
+	err := r.db.QueryRow(ctx, q, shopID, orderID).
+		Scan(&out.ID, &out.ShopID, &out.Number, &out.Total, &out.CustomerName, &out.Status, &out.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Order{}, domain.ErrOrderNotFound
+	}
 	return out, err
 }
 
@@ -76,11 +859,16 @@ SELECT
   o.number,
   o.total,
   o.customer_name,
+  o.status,
   o.created_at,
-  tsl.status::text AS send_status
+  nl.send_status
 FROM orders o
-LEFT JOIN telegram_send_log tsl
-  ON tsl.shop_id = o.shop_id AND tsl.order_id = o.id
+LEFT JOIN LATERAL (
+  SELECT CASE WHEN bool_or(status = 'FAILED') THEN 'FAILED' ELSE 'SENT' END AS send_status
+  FROM notification_log
+  WHERE shop_id = o.shop_id AND order_id = o.id AND channel <> 'webhook'
+  HAVING COUNT(*) > 0
+) nl ON TRUE
 WHERE o.shop_id = $1
 ORDER BY o.created_at DESC, o.id DESC
 LIMIT $2 OFFSET $3`
@@ -94,7 +882,7 @@ LIMIT $2 OFFSET $3`
 	for rows.Next() {
 		var item domain.OrderListItem
 		var sendStatus sql.NullString
-		if err := rows.Scan(&item.ID, &item.ShopID, &item.Number, &item.Total, &item.CustomerName, &item.CreatedAt, &sendStatus); err != nil {
+		if err := rows.Scan(&item.ID, &item.ShopID, &item.Number, &item.Total, &item.CustomerName, &item.Status, &item.CreatedAt, &sendStatus); err != nil {
 			return nil, err
 		}
 		if sendStatus.Valid {
@@ -108,46 +896,537 @@ LIMIT $2 OFFSET $3`
 	return out, nil
 }
 
-type SendLogRepository struct {
+type NotificationLogRepository struct {
 	db *pgxpool.Pool
 }
 
-func NewSendLogRepository(db *pgxpool.Pool) *SendLogRepository {
-	return &SendLogRepository{db: db}
+func NewNotificationLogRepository(db *pgxpool.Pool) *NotificationLogRepository {
+	return &NotificationLogRepository{db: db}
+}
+
+// An in-flight notification is stored as FAILED with the error "reserved"
+// until Finalize records the outcome; a deferred one also has deliver_after
+// until ClaimDue picks it up. Reserve does nothing when the row for the
+// integration and recipient, or for the webhook subscription and event, already
+// exists.
+func (r *NotificationLogRepository) Reserve(ctx context.Context, entry domain.NotificationLog, reservedAt time.Time) (int64, bool, error) {
+	const q = `
+INSERT INTO notification_log (shop_id, order_id, integration_id, webhook_subscription_id, channel, recipient, event, message, status, error, sent_at, trace_id, deliver_after)
+VALUES ($1, $2, NULLIF($3::bigint, 0), NULLIF($4::bigint, 0), $5, $6, NULLIF($7, ''), $8, 'FAILED', 'reserved', $9, $10, $11)
+ON CONFLICT DO NOTHING
+RETURNING id`
+	return r.reserve(ctx, q, entry, reservedAt)
 }
 
-func (r *SendLogRepository) Reserve(ctx context.Context, shopID, orderID int64, message string, reservedAt time.Time) (bool, error) {
+// Retry reserves a new attempt unless the notification was sent or is still in flight.
+func (r *NotificationLogRepository) Retry(ctx context.Context, entry domain.NotificationLog, reservedAt time.Time) (int64, bool, error) {
 	const q = `
-INSERT INTO telegram_send_log (shop_id, order_id, message, status, error, sent_at)
-VALUES ($1, $2, $3, 'FAILED', 'reserved', $4)
-ON CONFLICT (shop_id, order_id) DO NOTHING`
-	tag, err := r.db.Exec(ctx, q, shopID, orderID, message, reservedAt)
+INSERT INTO notification_log (shop_id, order_id, integration_id, webhook_subscription_id, channel, recipient, event, message, status, error, sent_at, trace_id, deliver_after)
+VALUES ($1, $2, NULLIF($3::bigint, 0), NULLIF($4::bigint, 0), $5, $6, NULLIF($7, ''), $8, 'FAILED', 'reserved', $9, $10, $11)
+ON CONFLICT (integration_id, order_id, recipient) DO UPDATE
+SET message = EXCLUDED.message, error = EXCLUDED.error, sent_at = EXCLUDED.sent_at, trace_id = EXCLUDED.trace_id,
+    deliver_after = EXCLUDED.deliver_after
+WHERE notification_log.status = 'FAILED' AND notification_log.error IS DISTINCT FROM 'reserved'
+RETURNING id`
+	return r.reserve(ctx, q, entry, reservedAt)
+}
+
+func (r *NotificationLogRepository) reserve(ctx context.Context, q string, entry domain.NotificationLog, reservedAt time.Time) (int64, bool, error) {
+	var id int64
+	err := r.db.QueryRow(ctx, q, entry.ShopID, entry.OrderID, entry.IntegrationID, entry.SubscriptionID, entry.Channel, entry.Recipient, entry.Event, entry.Message, reservedAt, traceID(ctx), entry.DeliverAfter).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
 	if err != nil {
-		return false, err
+		return 0, false, err
 	}
-	return tag.RowsAffected() == 1, nil
+	return id, true, nil
 }
 
-func (r *SendLogRepository) Finalize(ctx context.Context, shopID, orderID int64, status domain.TelegramSendStatus, errText *string, sentAt time.Time) error {
-	const q = `
-UPDATE telegram_send_log
-SET status = $3, error = $4, sent_at = $5
-WHERE shop_id = $1 AND order_id = $2`
-	_, err := r.db.Exec(ctx, q, shopID, orderID, status, errText, sentAt)
+func (r *NotificationLogRepository) Finalize(ctx context.Context, logID int64, status domain.NotificationStatus, errText *string, sentAt time.Time) error {
+	const q = `UPDATE notification_log SET status = $2, error = $3, sent_at = $4 WHERE id = $1`
+	_, err := r.db.Exec(ctx, q, logID, status, errText, sentAt)
+	return err
+}
+
+// LinkMessage records the message that carried the notifications in logIDs.
+func (r *NotificationLogRepository) LinkMessage(ctx context.Context, messageID string, logIDs []int64) error {
+	_, err := r.db.Exec(ctx, `UPDATE notification_log SET message_id = $1 WHERE id = ANY($2)`, messageID, logIDs)
 	return err
 }
 
-func (r *SendLogRepository) GetStatusStats(ctx context.Context, shopID int64, since time.Time) (*time.Time, int64, int64, error) {
+func (r *NotificationLogRepository) GetStatusStats(ctx context.Context, shopID int64, channel domain.ChannelType, since time.Time) (*time.Time, int64, int64, error) {
 	const q = `
 SELECT
   MAX(sent_at) FILTER (WHERE status = 'SENT') AS last_sent_at,
-  COUNT(*) FILTER (WHERE status = 'SENT' AND sent_at >= $2) AS sent_count,
-  COUNT(*) FILTER (WHERE status = 'FAILED' AND sent_at >= $2) AS failed_count
-FROM telegram_send_log
-WHERE shop_id = $1`
+  COUNT(*) FILTER (WHERE status = 'SENT' AND sent_at >= $3) AS sent_count,
+  COUNT(*) FILTER (WHERE status = 'FAILED' AND error IS DISTINCT FROM 'reserved' AND sent_at >= $3) AS failed_count
+FROM notification_log
+WHERE shop_id = $1 AND channel = $2`
 	var lastSentAt *time.Time
 	var sentCount int64
 	var failedCount int64
-	err := r.db.QueryRow(ctx, q, shopID, since).Scan(&lastSentAt, &sentCount, &failedCount)
+	err := r.db.QueryRow(ctx, q, shopID, channel, since).Scan(&lastSentAt, &sentCount, &failedCount)
 	return lastSentAt, sentCount, failedCount, err
 }
+
+func (r *NotificationLogRepository) GetRecipientStats(ctx context.Context, integrationID int64, since time.Time) (map[string]domain.RecipientStats, error) {
+	const q = `
+SELECT
+  recipient,
+  MAX(sent_at) FILTER (WHERE status = 'SENT') AS last_sent_at,
+  COUNT(*) FILTER (WHERE status = 'SENT' AND sent_at >= $2) AS sent_count,
+  COUNT(*) FILTER (WHERE status = 'FAILED' AND error IS DISTINCT FROM 'reserved' AND sent_at >= $2) AS failed_count
+FROM notification_log
+WHERE integration_id = $1
+GROUP BY recipient`
+	rows, err := r.db.Query(ctx, q, integrationID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]domain.RecipientStats{}
+	for rows.Next() {
+		var recipient string
+		var stats domain.RecipientStats
+		if err := rows.Scan(&recipient, &stats.LastSentAt, &stats.SentCount, &stats.FailedCount); err != nil {
+			return nil, err
+		}
+		out[recipient] = stats
+	}
+	return out, rows.Err()
+}
+
+func (r *NotificationLogRepository) ListWebhookDeliveries(ctx context.Context, webhookID int64, limit, offset int) ([]domain.WebhookDelivery, error) {
+	const q = `
+SELECT
+  id,
+  webhook_subscription_id,
+  order_id,
+  event,
+  CASE WHEN error = 'reserved' THEN 'PENDING' ELSE status END,
+  CASE WHEN error = 'reserved' THEN NULL ELSE error END,
+  message,
+  sent_at
+FROM notification_log
+WHERE webhook_subscription_id = $1
+ORDER BY sent_at DESC, id DESC
+LIMIT $2 OFFSET $3`
+	rows, err := r.db.Query(ctx, q, webhookID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.WebhookDelivery, 0, limit)
+	for rows.Next() {
+		var item domain.WebhookDelivery
+		var payload string
+		if err := rows.Scan(&item.ID, &item.WebhookID, &item.OrderID, &item.Event, &item.Status, &item.Error, &payload, &item.SentAt); err != nil {
+			return nil, err
+		}
+		item.Payload = json.RawMessage(payload)
+		out = append(out, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Redeliver reserves a finished delivery again, whatever its outcome was.
+func (r *NotificationLogRepository) Redeliver(ctx context.Context, webhookID, deliveryID int64, reservedAt time.Time) (domain.NotificationLog, bool, error) {
+	const q = `
+UPDATE notification_log
+SET status = 'FAILED', error = 'reserved', sent_at = $3, trace_id = $4
+WHERE id = $2 AND webhook_subscription_id = $1 AND error IS DISTINCT FROM 'reserved'
+RETURNING id, shop_id, order_id, webhook_subscription_id, channel, event, message, sent_at`
+	var out domain.NotificationLog
+	err := r.db.QueryRow(ctx, q, webhookID, deliveryID, reservedAt, traceID(ctx)).Scan(
+		&out.ID, &out.ShopID, &out.OrderID, &out.SubscriptionID, &out.Channel, &out.Event, &out.Message, &out.SentAt,
+	)
+	if err == nil {
+		return out, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return domain.NotificationLog{}, false, err
+	}
+
+	var exists bool
+	const existsQ = `SELECT EXISTS (SELECT 1 FROM notification_log WHERE id = $2 AND webhook_subscription_id = $1)`
+	if err := r.db.QueryRow(ctx, existsQ, webhookID, deliveryID).Scan(&exists); err != nil {
+		return domain.NotificationLog{}, false, err
+	}
+	if !exists {
+		return domain.NotificationLog{}, false, domain.ErrDeliveryNotFound
+	}
+	return domain.NotificationLog{}, false, nil
+}
+
+func (r *NotificationLogRepository) ClaimDue(ctx context.Context, now time.Time, limit int) ([]domain.NotificationLog, error) {
+	const q = `
+UPDATE notification_log
+SET deliver_after = NULL, sent_at = $1
+WHERE id IN (
+  SELECT id FROM notification_log
+  WHERE deliver_after <= $1 AND error = 'reserved'
+  ORDER BY deliver_after, id
+  LIMIT $2
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, shop_id, order_id, COALESCE(integration_id, 0), channel, recipient, message`
+	rows, err := r.db.Query(ctx, q, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.NotificationLog{}
+	for rows.Next() {
+		var entry domain.NotificationLog
+		if err := rows.Scan(&entry.ID, &entry.ShopID, &entry.OrderID, &entry.IntegrationID, &entry.Channel, &entry.Recipient, &entry.Message); err != nil {
+			return nil, err
+		}
+		out = append(out, entry)
+	}
+	return out, rows.Err()
+}
+
+func (r *NotificationLogRepository) OpenBatch(ctx context.Context, integrationID int64, recipient string, now time.Time) (*time.Time, error) {
+	const q = `
+SELECT MIN(deliver_after) FROM notification_log
+WHERE integration_id = $1 AND recipient = $2 AND error = 'reserved' AND deliver_after > $3`
+	var deliverAfter *time.Time
+	if err := r.db.QueryRow(ctx, q, integrationID, recipient, now).Scan(&deliverAfter); err != nil {
+		return nil, err
+	}
+	return deliverAfter, nil
+}
+
+// traceID links the notification_log row to the trace of the request that reserved it.
+func traceID(ctx context.Context) *string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return nil
+	}
+	id := sc.TraceID().String()
+	return &id
+}
+
+type APIKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(db *pgxpool.Pool) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, shop_id, name, prefix, scopes, created_at, last_used_at, revoked_at`
+
+func scanAPIKey(row pgx.Row) (domain.APIKey, error) {
+	var out domain.APIKey
+	var scopes []string
+	err := row.Scan(&out.ID, &out.ShopID, &out.Name, &out.Prefix, &scopes, &out.CreatedAt, &out.LastUsedAt, &out.RevokedAt)
+	for _, scope := range scopes {
+		out.Scopes = append(out.Scopes, domain.Scope(scope))
+	}
+	return out, err
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, key domain.NewAPIKey) (domain.APIKey, error) {
+	const q = `
+INSERT INTO api_keys (shop_id, name, prefix, key_hash, scopes, created_at)
+VALUES ($1, $2, $3, $4, $5, NOW())
+RETURNING ` + apiKeyColumns
+	scopes := make([]string, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		scopes = append(scopes, string(scope))
+	}
+	out, err := scanAPIKey(r.db.QueryRow(ctx, q, key.ShopID, key.Name, key.Prefix, key.Hash, scopes))
+	return out, mapShopForeignKey(err)
+}
+
+func (r *APIKeyRepository) List(ctx context.Context, shopID int64) ([]domain.APIKey, error) {
+	q := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE shop_id = $1 ORDER BY id`
+	rows, err := r.db.Query(ctx, q, shopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *APIKeyRepository) GetByHash(ctx context.Context, hash []byte) (domain.APIKey, bool, error) {
+	q := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1`
+	out, err := scanAPIKey(r.db.QueryRow(ctx, q, hash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.APIKey{}, false, nil
+		}
+		return domain.APIKey{}, false, err
+	}
+	return out, true, nil
+}
+
+func (r *APIKeyRepository) Revoke(ctx context.Context, shopID, keyID int64, revokedAt time.Time) error {
+	const q = `
+UPDATE api_keys
+SET revoked_at = COALESCE(revoked_at, $3)
+WHERE shop_id = $1 AND id = $2`
+	tag, err := r.db.Exec(ctx, q, shopID, keyID, revokedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, keyID int64, usedAt time.Time) error {
+	_, err := r.db.Exec(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, keyID, usedAt)
+	return err
+}
+
+const uniqueViolation = "23505"
+
+type UserRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewUserRepository(db *pgxpool.Pool) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+const userColumns = `id, email, password_hash, created_at, updated_at`
+
+func scanUser(row pgx.Row) (domain.User, error) {
+	var out domain.User
+	err := row.Scan(&out.ID, &out.Email, &out.PasswordHash, &out.CreatedAt, &out.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.User{}, domain.ErrUserNotFound
+	}
+	return out, err
+}
+
+func (r *UserRepository) Create(ctx context.Context, email, passwordHash string) (domain.User, error) {
+	const q = `
+INSERT INTO users (email, password_hash, created_at, updated_at)
+VALUES ($1, $2, NOW(), NOW())
+RETURNING ` + userColumns
+	out, err := scanUser(r.db.QueryRow(ctx, q, email, passwordHash))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return domain.User{}, domain.ErrUserExists
+	}
+	return out, err
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, userID int64) (domain.User, error) {
+	return scanUser(r.db.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, userID))
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (domain.User, error) {
+	return scanUser(r.db.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE LOWER(email) = LOWER($1)`, email))
+}
+
+type SessionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSessionRepository(db *pgxpool.Pool) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) Create(ctx context.Context, session domain.Session) error {
+	const q = `
+INSERT INTO sessions (token_hash, user_id, csrf_token, created_at, expires_at)
+VALUES ($1, $2, $3, $4, $5)`
+	_, err := r.db.Exec(ctx, q, session.TokenHash, session.UserID, session.CSRFToken, session.CreatedAt, session.ExpiresAt)
+	return err
+}
+
+func (r *SessionRepository) Get(ctx context.Context, tokenHash []byte) (domain.Session, bool, error) {
+	const q = `SELECT token_hash, user_id, csrf_token, created_at, expires_at FROM sessions WHERE token_hash = $1`
+	var out domain.Session
+	err := r.db.QueryRow(ctx, q, tokenHash).Scan(&out.TokenHash, &out.UserID, &out.CSRFToken, &out.CreatedAt, &out.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Session{}, false, nil
+		}
+		return domain.Session{}, false, err
+	}
+	return out, true, nil
+}
+
+func (r *SessionRepository) Delete(ctx context.Context, tokenHash []byte) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM sessions WHERE token_hash = $1 OR expires_at < NOW()`, tokenHash)
+	return err
+}
+
+type MemberRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMemberRepository(db *pgxpool.Pool) *MemberRepository {
+	return &MemberRepository{db: db}
+}
+
+func (r *MemberRepository) Upsert(ctx context.Context, shopID, userID int64, role domain.Role) (domain.ShopMember, error) {
+	const q = `
+WITH upserted AS (
+  INSERT INTO shop_members (shop_id, user_id, role, created_at, updated_at)
+  VALUES ($1, $2, $3, NOW(), NOW())
+  ON CONFLICT (shop_id, user_id)
+  DO UPDATE SET role = EXCLUDED.role, updated_at = NOW()
+  RETURNING shop_id, user_id, role, created_at, updated_at
+)
+SELECT m.shop_id, m.user_id, u.email, m.role, m.created_at, m.updated_at
+FROM upserted m
+JOIN users u ON u.id = m.user_id`
+	var out domain.ShopMember
+	err := r.db.QueryRow(ctx, q, shopID, userID, role).
+		Scan(&out.ShopID, &out.UserID, &out.Email, &out.Role, &out.CreatedAt, &out.UpdatedAt)
+	return out, mapShopForeignKey(err)
+}
+
+func (r *MemberRepository) ListByShop(ctx context.Context, shopID int64) ([]domain.ShopMember, error) {
+	const q = `
+SELECT m.shop_id, m.user_id, u.email, m.role, m.created_at, m.updated_at
+FROM shop_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.shop_id = $1
+ORDER BY m.created_at, m.user_id`
+	return r.list(ctx, q, shopID)
+}
+
+func (r *MemberRepository) ListByUser(ctx context.Context, userID int64) ([]domain.ShopMember, error) {
+	const q = `
+SELECT m.shop_id, m.user_id, u.email, m.role, m.created_at, m.updated_at
+FROM shop_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.user_id = $1
+ORDER BY m.shop_id`
+	return r.list(ctx, q, userID)
+}
+
+func (r *MemberRepository) Delete(ctx context.Context, shopID, userID int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM shop_members WHERE shop_id = $1 AND user_id = $2`, shopID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrMemberNotFound
+	}
+	return nil
+}
+
+func (r *MemberRepository) list(ctx context.Context, q string, arg int64) ([]domain.ShopMember, error) {
+	rows, err := r.db.Query(ctx, q, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.ShopMember{}
+	for rows.Next() {
+		var member domain.ShopMember
+		if err := rows.Scan(&member.ShopID, &member.UserID, &member.Email, &member.Role, &member.CreatedAt, &member.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type AuditRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditRepository(db *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) Append(ctx context.Context, event domain.AuditEvent) error {
+	const q = `
+INSERT INTO audit_events (shop_id, action, actor, entity_type, entity_id, before, after, request_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := r.db.Exec(ctx, q, event.ShopID, event.Action, event.Actor, event.EntityType, event.EntityID,
+		nullJSON(event.Before), nullJSON(event.After), event.RequestID)
+	return err
+}
+
+func (r *AuditRepository) List(ctx context.Context, shopID int64, limit, offset int) ([]domain.AuditEvent, error) {
+	const q = `
+SELECT id, shop_id, action, actor, entity_type, entity_id, before, after, request_id, created_at
+FROM audit_events
+WHERE shop_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2 OFFSET $3`
+	rows, err := r.db.Query(ctx, q, shopID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.AuditEvent, 0, limit)
+	for rows.Next() {
+		var event domain.AuditEvent
+		if err := rows.Scan(&event.ID, &event.ShopID, &event.Action, &event.Actor, &event.EntityType, &event.EntityID,
+			&event.Before, &event.After, &event.RequestID, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func nullJSON(raw []byte) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
+
+type RateLimitStore struct {
+	db *pgxpool.Pool
+}
+
+func NewRateLimitStore(db *pgxpool.Pool) *RateLimitStore {
+	return &RateLimitStore{db: db}
+}
+
+func (r *RateLimitStore) Hit(ctx context.Context, key string, limit domain.RateLimit, now time.Time) (domain.RateLimitResult, error) {
+	const q = `
+INSERT INTO rate_limits (key, window_start, hits)
+VALUES ($1, $2, 1)
+ON CONFLICT (key) DO UPDATE
+SET hits = CASE WHEN rate_limits.window_start = EXCLUDED.window_start THEN rate_limits.hits + 1 ELSE 1 END,
+    window_start = EXCLUDED.window_start
+RETURNING hits`
+	windowStart := domain.RateLimitWindow(limit, now)
+	var hits int
+	if err := r.db.QueryRow(ctx, q, key, windowStart).Scan(&hits); err != nil {
+		return domain.RateLimitResult{}, err
+	}
+	return domain.NewRateLimitResult(limit, windowStart, hits), nil
+}
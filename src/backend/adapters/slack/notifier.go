@@ -0,0 +1,211 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"growth-mvp/backend/adapters/egress"
+	"growth-mvp/backend/domain"
+)
+
+const webhookHost = "hooks.slack.com"
+
+// Slack rejects header blocks over 150 characters and section fields over 2000.
+const (
+	maxHeaderLength = 150
+	maxFieldLength  = 2000
+)
+
+// Notifier implements domain.Notifier for Slack incoming webhooks. The webhook
+// URL is the integration secret, since anyone who has it can post to the
+// channel, and there are no settings.
+type Notifier struct {
+	httpClient *http.Client
+	outbound   egress.Config
+}
+
+// NewNotifier accepts webhook URLs on hooks.slack.com and on
+// cfg.AllowedHosts, and dials through the egress guard.
+func NewNotifier(cfg egress.Config) *Notifier {
+	return &Notifier{httpClient: egress.NewHTTPClient(cfg), outbound: cfg}
+}
+
+func (n *Notifier) Normalize(settings map[string]string, secret string) (map[string]string, string, error) {
+	var fields []domain.FieldError
+
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		fields = append(fields, domain.FieldError{Field: "settings." + key, Code: "unknown", Message: fmt.Sprintf("unknown slack setting %q", key)})
+	}
+
+	secret = strings.TrimSpace(secret)
+
+	if err := n.validateWebhookURL(secret); err != nil {
+		fields = append(fields, domain.FieldError{Field: "secret", Code: "url", Message: err.Error()})
+	}
+
+	if len(fields) > 0 {
+		return nil, "", domain.ValidationFailed(fields)
+	}
+
+	return map[string]string{}, secret, nil
+}
+
+func (n *Notifier) Mask(map[string]string) map[string]string {
+	return map[string]string{}
+}
+
+// Send treats a 429 as a domain.RateLimitError carrying Retry-After, and any
+// other non-2xx answer as a failed attempt with Slack's error code.
+func (n *Notifier) Send(ctx context.Context, integration domain.Integration, notification domain.Notification) error {
+	body, err := json.Marshal(renderBlocks(notification))
+
+	if err != nil {
+		return fmt.Errorf("marshal slack payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, integration.Secret, bytes.NewReader(body))
+
+	if err != nil {
+		return fmt.Errorf("create slack request: %w", redactURLError(err))
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+
+	if err != nil {
+		return fmt.Errorf("slack webhook request failed: %w", redactURLError(err))
+	}
+
+	defer resp.Body.Close()
+	answer, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &domain.RateLimitError{
+			RetryAfter: retryAfter(resp.Header),
+			Err:        errors.New("slack webhook is rate limited (status=429)"),
+		}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("slack webhook failed (status=%d): %s", resp.StatusCode, strings.TrimSpace(string(answer)))
+	}
+
+	return nil
+}
+
+type textObject struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type block struct {
+	Type   string       `json:"type"`
+	Text   *textObject  `json:"text,omitempty"`
+	Fields []textObject `json:"fields,omitempty"`
+}
+
+type payload struct {
+	Text   string  `json:"text"`
+	Blocks []block `json:"blocks"`
+}
+
+// renderBlocks lays the notification out as a header and a section with one
+// field per value. Text stays as the fallback Slack shows in notifications.
+func renderBlocks(notification domain.Notification) payload {
+	blocks := []block{{
+		Type: "header",
+		Text: &textObject{Type: "plain_text", Text: truncate(notification.Subject, maxHeaderLength)},
+	}}
+
+	if len(notification.Fields) > 0 {
+		section := block{Type: "section"}
+
+		for _, field := range notification.Fields {
+			text := fmt.Sprintf("*%s*\n%s", escape(field.Name), escape(field.Value))
+			section.Fields = append(section.Fields, textObject{Type: "mrkdwn", Text: truncate(text, maxFieldLength)})
+		}
+
+		blocks = append(blocks, section)
+	}
+
+	return payload{Text: notification.Text, Blocks: blocks}
+}
+
+// escape keeps customer input from turning into links or mentions: mrkdwn
+// only treats &, < and > specially.
+func escape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+
+	if len(runes) <= limit {
+		return text
+	}
+
+	return string(runes[:limit-1]) + "…"
+}
+
+func retryAfter(header http.Header) time.Duration {
+	seconds, err := strconv.Atoi(header.Get("Retry-After"))
+
+	if err != nil || seconds < 0 {
+		return 0
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// validateWebhookURL accepts the URLs Slack issues for incoming webhooks,
+// https://hooks.slack.com/services/..., and the same path on a host the
+// operator allowed, e.g. a relay. The URL is a credential, so it must be https.
+func (n *Notifier) validateWebhookURL(rawURL string) error {
+	if rawURL == "" {
+		return errors.New("slack webhook URL must be non-empty")
+	}
+
+	parsed, err := url.Parse(rawURL)
+
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return errors.New("slack webhook URL must be an absolute https URL")
+	}
+
+	if host := parsed.Hostname(); host != webhookHost && !n.outbound.Allowed(host) {
+		return fmt.Errorf("slack webhook URL must point at %s", webhookHost)
+	}
+
+	if !strings.HasPrefix(parsed.Path, "/services/") {
+		return errors.New("slack webhook URL must look like https://hooks.slack.com/services/...")
+	}
+
+	return nil
+}
+
+// The whole URL is the credential, and net/http reports it in *url.Error, so
+// it is masked before the error is logged or stored.
+func redactURLError(err error) error {
+	var urlErr *url.Error
+
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+
+	return &url.Error{
+		Op:  urlErr.Op,
+		URL: domain.MaskURL(urlErr.URL),
+		Err: urlErr.Err,
+	}
+}
@@ -0,0 +1,169 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"growth-mvp/backend/domain"
+)
+
+// maxMessageLength is the Bot API limit on message text, counted in UTF-16
+// code units.
+const maxMessageLength = 4096
+
+// batchSeparator separates the orders merged into one message.
+const batchSeparator = "\n\n"
+
+// Sender is the part of Client the notifier needs.
+type Sender interface {
+	SendMessage(ctx context.Context, botToken, chatID, text string, silent bool) (int64, error)
+}
+
+// Notifier implements domain.Notifier for Telegram: the bot token is the
+// integration secret and the chat ID its only setting. Extra chats are
+// domain.TelegramDestination rows.
+type Notifier struct {
+	sender Sender
+}
+
+func NewNotifier(sender Sender) *Notifier {
+	return &Notifier{sender: sender}
+}
+
+func (n *Notifier) Normalize(settings map[string]string, secret string) (map[string]string, string, error) {
+	var fields []domain.FieldError
+
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		if key != domain.TelegramSettingChatID {
+			fields = append(fields, domain.FieldError{Field: "settings." + key, Code: "unknown", Message: fmt.Sprintf("unknown telegram setting %q", key)})
+		}
+	}
+
+	chatID := strings.TrimSpace(settings[domain.TelegramSettingChatID])
+	secret = strings.TrimSpace(secret)
+
+	if chatID == "" {
+		fields = append(fields, domain.FieldError{Field: "settings.chatId", Code: "required", Message: "chatId must be non-empty"})
+	}
+
+	if secret == "" {
+		fields = append(fields, domain.FieldError{Field: "secret", Code: "required", Message: "bot token must be non-empty"})
+	}
+
+	if len(fields) > 0 {
+		return nil, "", domain.ValidationFailed(fields)
+	}
+
+	return map[string]string{domain.TelegramSettingChatID: chatID}, secret, nil
+}
+
+func (n *Notifier) Mask(settings map[string]string) map[string]string {
+	return map[string]string{domain.TelegramSettingChatID: domain.MaskChatID(settings[domain.TelegramSettingChatID])}
+}
+
+// Send posts to the integration's chat, or to the destination chat in
+// notification.Recipient. A silent notification is sent with
+// disable_notification.
+func (n *Notifier) Send(ctx context.Context, integration domain.Integration, notification domain.Notification) error {
+	_, err := n.sender.SendMessage(ctx, integration.Secret, chatID(integration, notification), notification.Text, notification.Silent)
+	return err
+}
+
+// SendBatch merges notifications, which share one recipient, into messages
+// of up to maxMessageLength under a header with the number of orders. A
+// message is silent only if all of its notifications are.
+func (n *Notifier) SendBatch(ctx context.Context, integration domain.Integration, notifications []domain.Notification) ([]string, error) {
+	messageIDs := make([]string, len(notifications))
+
+	// the header is sized for the whole batch, so it fits every part
+	reserved := textLength(domain.RenderBatchHeader(len(notifications))) + textLength(batchSeparator)
+	texts := make([]string, len(notifications))
+
+	for i, notification := range notifications {
+		texts[i] = truncateText(notification.Text, maxMessageLength-reserved)
+	}
+
+	for start := 0; start < len(notifications); {
+		end, size := start+1, reserved+textLength(texts[start])
+
+		for end < len(notifications) && size+textLength(batchSeparator)+textLength(texts[end]) <= maxMessageLength {
+			size += textLength(batchSeparator) + textLength(texts[end])
+			end++
+		}
+
+		text, silent := batchText(notifications[start:end], texts[start:end])
+		messageID, err := n.sender.SendMessage(ctx, integration.Secret, chatID(integration, notifications[start]), text, silent)
+
+		if err != nil {
+			return messageIDs, err
+		}
+
+		for i := start; i < end; i++ {
+			messageIDs[i] = strconv.FormatInt(messageID, 10)
+		}
+
+		start = end
+	}
+
+	return messageIDs, nil
+}
+
+func chatID(integration domain.Integration, notification domain.Notification) string {
+	if notification.Recipient != "" {
+		return notification.Recipient
+	}
+
+	return integration.Settings[domain.TelegramSettingChatID]
+}
+
+// batchText builds one message of a batch; a single notification is sent
+// as is, without the header.
+func batchText(notifications []domain.Notification, texts []string) (string, bool) {
+	silent := true
+
+	for _, notification := range notifications {
+		silent = silent && notification.Silent
+	}
+
+	if len(notifications) == 1 {
+		return truncateText(notifications[0].Text, maxMessageLength), silent
+	}
+
+	return domain.RenderBatchHeader(len(notifications)) + batchSeparator + strings.Join(texts, batchSeparator), silent
+}
+
+func textLength(text string) int {
+	length := 0
+
+	for _, r := range text {
+		length += utf16.RuneLen(r)
+	}
+
+	return length
+}
+
+// truncateText cuts text to limit UTF-16 code units, ending it with "…".
+func truncateText(text string, limit int) string {
+	if textLength(text) <= limit {
+		return text
+	}
+
+	var b strings.Builder
+	length := 0
+
+	for _, r := range text {
+		if length+utf16.RuneLen(r) > limit-1 {
+			break
+		}
+
+		b.WriteRune(r)
+		length += utf16.RuneLen(r)
+	}
+
+	return b.String() + "…"
+}
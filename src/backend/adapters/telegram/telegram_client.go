@@ -4,55 +4,118 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
+
+	"growth-mvp/backend/domain"
+)
+
+const DefaultBaseURL = "https://api.telegram.org"
+
+const (
+	ErrorClassNone        = "none"
+	ErrorClassInvalid     = "invalid"
+	ErrorClassCanceled    = "canceled"
+	ErrorClassTimeout     = "timeout"
+	ErrorClassNetwork     = "network"
+	ErrorClassBadResponse = "bad_response"
+	ErrorClassRateLimited = "rate_limited"
+	ErrorClassClient      = "client_error"
+	ErrorClassServer      = "server_error"
 )
 
 type Client struct {
+	baseURL     string
 	sendTimeout time.Duration
 	httpClient  *http.Client
+	metrics     domain.Metrics
+}
+
+type ClientOption func(*Client)
+
+func WithMetrics(metrics domain.Metrics) ClientOption {
+	return func(c *Client) {
+		if metrics != nil {
+			c.metrics = metrics
+		}
+	}
 }
 
-func NewClient(sendTimeout time.Duration) *Client {
+// NewClient talks to the Bot API at baseURL, which can point at a self-hosted
+// Bot API server or a stub in tests.
+func NewClient(baseURL string, sendTimeout time.Duration, opts ...ClientOption) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
 	if sendTimeout <= 0 {
 		sendTimeout = 5 * time.Second
 	}
 
-	return &Client{
+	c := &Client{
+		baseURL:     strings.TrimRight(baseURL, "/"),
 		sendTimeout: sendTimeout,
 		httpClient: &http.Client{
 			Timeout: sendTimeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
 		},
+		metrics: domain.NopMetrics{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	return c
+}
+
+// SendMessage posts text to chatID and returns the sent message's ID; a
+// silent message arrives without a sound (disable_notification).
+func (c *Client) SendMessage(ctx context.Context, botToken, chatID, text string, silent bool) (int64, error) {
+	started := time.Now()
+	messageID, class, err := c.sendMessage(ctx, botToken, chatID, text, silent)
+	c.metrics.ObserveTelegramRequest(class, time.Since(started))
+	return messageID, err
 }
 
-func (c *Client) SendMessage(ctx context.Context, botToken, chatID, text string) error {
+func (c *Client) sendMessage(ctx context.Context, botToken, chatID, text string, silent bool) (int64, string, error) {
 	if botToken == "" || chatID == "" {
-		return fmt.Errorf("botToken and chatID must be non-empty")
+		return 0, ErrorClassInvalid, fmt.Errorf("botToken and chatID must be non-empty")
 	}
 
 	sendCtx, cancel := context.WithTimeout(ctx, c.sendTimeout)
 	defer cancel()
 
-	reqBody, err := json.Marshal(map[string]string{
+	payload := map[string]any{
 		"chat_id": chatID,
 		"text":    text,
-	})
+	}
+
+	if silent {
+		payload["disable_notification"] = true
+	}
+
+	reqBody, err := json.Marshal(payload)
 
 	if err != nil {
-		return fmt.Errorf("marshal telegram payload: %w", err)
+		return 0, ErrorClassInvalid, fmt.Errorf("marshal telegram payload: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(
 		sendCtx,
 		http.MethodPost,
-		fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", botToken),
+		fmt.Sprintf("%s/bot%s/sendMessage", c.baseURL, botToken),
 		bytes.NewReader(reqBody),
 	)
 
 	if err != nil {
-		return fmt.Errorf("create telegram request: %w", err)
+		return 0, ErrorClassInvalid, fmt.Errorf("create telegram request: %w", redactURLError(err, botToken))
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -60,7 +123,7 @@ func (c *Client) SendMessage(ctx context.Context, botToken, chatID, text string)
 	resp, err := c.httpClient.Do(req)
 
 	if err != nil {
-		return fmt.Errorf("telegram sendMessage request failed: %w", err)
+		return 0, transportErrorClass(err), fmt.Errorf("telegram sendMessage request failed: %w", redactURLError(err, botToken))
 	}
 
 	defer resp.Body.Close()
@@ -68,18 +131,83 @@ func (c *Client) SendMessage(ctx context.Context, botToken, chatID, text string)
 	var out struct {
 		OK          bool   `json:"ok"`
 		Description string `json:"description"`
+		Result      struct {
+			MessageID int64 `json:"message_id"`
+		} `json:"result"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return fmt.Errorf("decode telegram response: %w", err)
+		return 0, ErrorClassBadResponse, fmt.Errorf("decode telegram response: %w", err)
 	}
 
 	if resp.StatusCode >= 400 || !out.OK {
 		if out.Description == "" {
 			out.Description = "unknown telegram error"
 		}
-		return fmt.Errorf("telegram sendMessage failed (status=%d): %s", resp.StatusCode, out.Description)
+		return 0, statusErrorClass(resp.StatusCode), fmt.Errorf("telegram sendMessage failed (status=%d): %s", resp.StatusCode, out.Description)
+	}
+
+	return out.Result.MessageID, ErrorClassNone, nil
+}
+
+func transportErrorClass(err error) string {
+	var netErr interface{ Timeout() bool }
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	default:
+		return ErrorClassNetwork
+	}
+}
+
+func statusErrorClass(status int) string {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return ErrorClassRateLimited
+	case status >= 500:
+		return ErrorClassServer
+	default:
+		return ErrorClassClient
+	}
+}
+
+// Ping checks that the Bot API host answers at all; any HTTP response counts,
+// since there is no unauthenticated endpoint to call.
+func (c *Client) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.baseURL+"/", nil)
+
+	if err != nil {
+		return fmt.Errorf("create telegram request: %w", err)
 	}
 
+	resp, err := c.httpClient.Do(req)
+
+	if err != nil {
+		return fmt.Errorf("telegram unreachable: %w", err)
+	}
+
+	resp.Body.Close()
 	return nil
 }
+
+// The bot token is part of the request path, and net/http reports the full URL
+// in *url.Error, so it has to be masked before the error leaves the client.
+func redactURLError(err error, botToken string) error {
+	var urlErr *url.Error
+
+	if !errors.As(err, &urlErr) {
+		if botToken != "" && strings.Contains(err.Error(), botToken) {
+			return errors.New(domain.RedactSecrets(err.Error(), botToken))
+		}
+		return err
+	}
+
+	return &url.Error{
+		Op:  urlErr.Op,
+		URL: domain.RedactSecrets(urlErr.URL, botToken),
+		Err: urlErr.Err,
+	}
+}
@@ -0,0 +1,157 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"growth-mvp/backend/domain"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "growth-mvp/backend"
+
+func start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func orderAttrs(shopID, orderID int64) []attribute.KeyValue {
+	return []attribute.KeyValue{attribute.Int64("shop.id", shopID), attribute.Int64("order.id", orderID)}
+}
+
+// OrderRepository wraps order writes in spans; reads pass through.
+type OrderRepository struct {
+	domain.OrderRepository
+}
+
+func NewOrderRepository(next domain.OrderRepository) *OrderRepository {
+	return &OrderRepository{OrderRepository: next}
+}
+
+func (r *OrderRepository) Create(ctx context.Context, shopID int64, input domain.CreateOrderInput) (domain.Order, error) {
+	ctx, span := start(ctx, "OrderRepository.Create", attribute.Int64("shop.id", shopID))
+	order, err := r.OrderRepository.Create(ctx, shopID, input)
+	span.SetAttributes(attribute.Int64("order.id", order.ID))
+	end(span, err)
+	return order, err
+}
+
+func (r *OrderRepository) CreateBatch(ctx context.Context, shopID int64, rows []domain.ImportOrderRow) ([]domain.Order, error) {
+	ctx, span := start(ctx, "OrderRepository.CreateBatch", attribute.Int64("shop.id", shopID), attribute.Int("orders.count", len(rows)))
+	orders, err := r.OrderRepository.CreateBatch(ctx, shopID, rows)
+	end(span, err)
+	return orders, err
+}
+
+type NotificationLogRepository struct {
+	domain.NotificationLogRepository
+}
+
+func NewNotificationLogRepository(next domain.NotificationLogRepository) *NotificationLogRepository {
+	return &NotificationLogRepository{NotificationLogRepository: next}
+}
+
+func (r *NotificationLogRepository) Reserve(ctx context.Context, entry domain.NotificationLog, reservedAt time.Time) (int64, bool, error) {
+	ctx, span := start(ctx, "NotificationLogRepository.Reserve", logAttrs(entry)...)
+	logID, reserved, err := r.NotificationLogRepository.Reserve(ctx, entry, reservedAt)
+	span.SetAttributes(attribute.Bool("notification_log.reserved", reserved))
+	end(span, err)
+	return logID, reserved, err
+}
+
+func (r *NotificationLogRepository) Retry(ctx context.Context, entry domain.NotificationLog, reservedAt time.Time) (int64, bool, error) {
+	ctx, span := start(ctx, "NotificationLogRepository.Retry", logAttrs(entry)...)
+	logID, reserved, err := r.NotificationLogRepository.Retry(ctx, entry, reservedAt)
+	span.SetAttributes(attribute.Bool("notification_log.reserved", reserved))
+	end(span, err)
+	return logID, reserved, err
+}
+
+func (r *NotificationLogRepository) LinkMessage(ctx context.Context, messageID string, logIDs []int64) error {
+	ctx, span := start(ctx, "NotificationLogRepository.LinkMessage",
+		attribute.String("notification_log.message_id", messageID), attribute.Int("notification_log.count", len(logIDs)))
+	err := r.NotificationLogRepository.LinkMessage(ctx, messageID, logIDs)
+	end(span, err)
+	return err
+}
+
+func (r *NotificationLogRepository) Finalize(ctx context.Context, logID int64, status domain.NotificationStatus, errText *string, sentAt time.Time) error {
+	ctx, span := start(ctx, "NotificationLogRepository.Finalize",
+		attribute.Int64("notification_log.id", logID), attribute.String("notification_log.status", string(status)))
+	err := r.NotificationLogRepository.Finalize(ctx, logID, status, errText, sentAt)
+	end(span, err)
+	return err
+}
+
+func logAttrs(entry domain.NotificationLog) []attribute.KeyValue {
+	return append(orderAttrs(entry.ShopID, entry.OrderID), attribute.String("notification.channel", string(entry.Channel)))
+}
+
+type Notifier struct {
+	domain.Notifier
+	channel domain.ChannelType
+}
+
+// NewNotifier keeps a domain.RecipientNotifier recognisable as one, so the
+// service still sends to each recipient separately, and likewise a
+// domain.BatchNotifier, so batching stays available.
+func NewNotifier(channel domain.ChannelType, next domain.Notifier) domain.Notifier {
+	n := &Notifier{Notifier: next, channel: channel}
+
+	if recipients, ok := next.(domain.RecipientNotifier); ok {
+		return &RecipientNotifier{Notifier: n, recipients: recipients}
+	}
+
+	if batches, ok := next.(domain.BatchNotifier); ok {
+		return &BatchNotifier{Notifier: n, batches: batches}
+	}
+
+	return n
+}
+
+// Send records one span per attempt. Settings are left out since they may
+// identify the recipient; the error text is already redacted by the notifier.
+func (n *Notifier) Send(ctx context.Context, integration domain.Integration, notification domain.Notification) error {
+	ctx, span := start(ctx, "Notifier.Send", append(orderAttrs(notification.ShopID, notification.OrderID),
+		attribute.String("notification.channel", string(n.channel)))...)
+	err := n.Notifier.Send(ctx, integration, notification)
+	end(span, err)
+	return err
+}
+
+type RecipientNotifier struct {
+	*Notifier
+	recipients domain.RecipientNotifier
+}
+
+func (n *RecipientNotifier) Recipients(integration domain.Integration) []string {
+	return n.recipients.Recipients(integration)
+}
+
+func (n *RecipientNotifier) MaskRecipient(recipient string) string {
+	return n.recipients.MaskRecipient(recipient)
+}
+
+type BatchNotifier struct {
+	*Notifier
+	batches domain.BatchNotifier
+}
+
+func (n *BatchNotifier) SendBatch(ctx context.Context, integration domain.Integration, notifications []domain.Notification) ([]string, error) {
+	ctx, span := start(ctx, "Notifier.SendBatch", attribute.Int64("shop.id", integration.ShopID),
+		attribute.String("notification.channel", string(n.channel)), attribute.Int("notification.count", len(notifications)))
+	messageIDs, err := n.batches.SendBatch(ctx, integration, notifications)
+	end(span, err)
+	return messageIDs, err
+}
@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"growth-mvp/backend/adapters/egress"
+	"growth-mvp/backend/domain"
+)
+
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Notifier implements domain.Notifier for webhook subscriptions: the signing
+// secret is the integration secret and the target URL its only setting. The
+// notification text is the JSON payload and is sent as is.
+type Notifier struct {
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewNotifier dials through the egress guard, so a subscription cannot point
+// the server at its own network.
+func NewNotifier(cfg egress.Config) *Notifier {
+	return &Notifier{
+		httpClient: egress.NewHTTPClient(cfg),
+		now:        time.Now,
+	}
+}
+
+// Sign returns the X-Webhook-Signature value for body sent at timestamp (Unix
+// seconds): "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>".
+// Receivers recompute it with the shared secret and reject stale timestamps.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (n *Notifier) Normalize(settings map[string]string, secret string) (map[string]string, string, error) {
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		if key != domain.WebhookSettingURL {
+			return nil, "", domain.InvalidField("settings."+key, "unknown", fmt.Sprintf("unknown webhook setting %q", key))
+		}
+	}
+
+	target, err := domain.NormalizeWebhookURL(settings[domain.WebhookSettingURL])
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	return map[string]string{domain.WebhookSettingURL: target}, secret, nil
+}
+
+func (n *Notifier) Mask(settings map[string]string) map[string]string {
+	return map[string]string{domain.WebhookSettingURL: domain.MaskURL(settings[domain.WebhookSettingURL])}
+}
+
+// Send treats any non-2xx answer, including a redirect, as a failed attempt.
+func (n *Notifier) Send(ctx context.Context, integration domain.Integration, notification domain.Notification) error {
+	target := integration.Settings[domain.WebhookSettingURL]
+	body := []byte(notification.Text)
+	timestamp := n.now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+
+	if err != nil {
+		return fmt.Errorf("create webhook request: %w", redactURLError(err))
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "growth-mvp-webhooks/1")
+	req.Header.Set(HeaderEvent, notification.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(notification.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(integration.Secret, timestamp, body))
+
+	resp, err := n.httpClient.Do(req)
+
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", redactURLError(err))
+	}
+
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook receiver answered with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// The URL path or query may carry a token, and net/http reports the full URL
+// in *url.Error, so it is masked before the error is logged or stored.
+func redactURLError(err error) error {
+	var urlErr *url.Error
+
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+
+	return &url.Error{
+		Op:  urlErr.Op,
+		URL: domain.MaskURL(urlErr.URL),
+		Err: urlErr.Err,
+	}
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"growth-mvp/backend/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	sessionCookieName = "session"
+	csrfHeaderName    = "X-CSRF-Token"
+)
+
+// authenticate accepts either an API key (Authorization: Bearer or X-API-Key)
+// or a session cookie. Cookie-authenticated requests that change state must
+// also echo the session's CSRF token in the X-CSRF-Token header.
+func (h *Handler) authenticate(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var principal domain.Principal
+	var err error
+
+	if raw := apiKeyFromRequest(c.Request); raw != "" {
+		principal, err = h.apiKeys.Authenticate(ctx, raw)
+	} else if token, cookieErr := c.Cookie(sessionCookieName); cookieErr == nil && token != "" {
+		principal, err = h.auth.AuthenticateSession(ctx, token)
+
+		if err == nil && !isSafeMethod(c.Request.Method) && !validCSRFToken(c.GetHeader(csrfHeaderName), principal.CSRFToken) {
+			respondError(c, errInvalidCSRF)
+			return
+		}
+	} else {
+		err = domain.ErrUnauthorized
+	}
+
+	if err != nil {
+		if errors.Is(err, domain.ErrUnauthorized) {
+			c.Header("WWW-Authenticate", `Bearer realm="api"`)
+		}
+		respondError(c, err)
+		return
+	}
+
+	c.Request = c.Request.WithContext(domain.WithPrincipal(ctx, principal))
+	c.Next()
+}
+
+// authorize checks the caller against the :shopId path parameter. Without
+// scopes any member of the shop (or key issued for it) is accepted.
+func (h *Handler) authorize(scopes ...domain.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shopID, ok := parseShopID(c)
+
+		if !ok {
+			c.Abort()
+			return
+		}
+
+		principal, _ := domain.PrincipalFromContext(c.Request.Context())
+		allowed := principal.Member(shopID)
+
+		for _, scope := range scopes {
+			allowed = allowed && principal.Can(shopID, scope)
+		}
+
+		if !allowed {
+			respondError(c, domain.ErrForbidden)
+			return
+		}
+
+		if !h.rateLimitShop(c, shopID) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func (h *Handler) requireAdmin(c *gin.Context) {
+	principal, _ := domain.PrincipalFromContext(c.Request.Context())
+
+	if !principal.Admin {
+		respondError(c, domain.ErrForbidden)
+		return
+	}
+
+	c.Next()
+}
+
+func (h *Handler) requireUser(c *gin.Context) {
+	principal, _ := domain.PrincipalFromContext(c.Request.Context())
+
+	if principal.UserID == 0 {
+		respondError(c, errSessionOnly)
+		return
+	}
+
+	c.Next()
+}
+
+func (h *Handler) login(c *gin.Context) {
+	var input domain.LoginInput
+
+	if !h.bindJSON(c, &input) {
+		return
+	}
+
+	out, token, err := h.auth.Login(c.Request.Context(), input)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  out.ExpiresAt,
+		HttpOnly: true,
+		Secure:   isSecureRequest(c.Request),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) logout(c *gin.Context) {
+	token, _ := c.Cookie(sessionCookieName)
+
+	if err := h.auth.Logout(c.Request.Context(), token); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isSecureRequest(c.Request),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) me(c *gin.Context) {
+	principal, _ := domain.PrincipalFromContext(c.Request.Context())
+
+	out, err := h.auth.Me(c.Request.Context(), principal)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) createUser(c *gin.Context) {
+	var input domain.CreateUserInput
+
+	if !h.bindJSON(c, &input) {
+		return
+	}
+
+	out, err := h.auth.CreateUser(c.Request.Context(), input)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, out)
+}
+
+func (h *Handler) listMembers(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	out, err := h.auth.ListMembers(c.Request.Context(), shopID)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) setMember(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	var input domain.SetMemberInput
+
+	if !h.bindJSON(c, &input) {
+		return
+	}
+
+	out, err := h.auth.SetMember(c.Request.Context(), shopID, input)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) removeMember(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+
+	if err != nil || userID <= 0 {
+		respondError(c, domain.InvalidField("userId", "number", "invalid userId"))
+		return
+	}
+
+	if err := h.auth.RemoveMember(c.Request.Context(), shopID, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func apiKeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+
+	if found && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+
+	return ""
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func validCSRFToken(got, want string) bool {
+	return got != "" && want != "" && subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+func isSecureRequest(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"growth-mvp/backend/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) listTelegramDestinations(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	out, err := h.service.ListTelegramDestinations(c.Request.Context(), shopID)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) createTelegramDestination(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	var input domain.TelegramDestinationInput
+
+	if !h.bindJSON(c, &input) {
+		return
+	}
+
+	out, err := h.service.CreateTelegramDestination(c.Request.Context(), shopID, input)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, out)
+}
+
+func (h *Handler) updateTelegramDestination(c *gin.Context) {
+	shopID, destinationID, ok := parseDestinationID(c)
+
+	if !ok {
+		return
+	}
+
+	var input domain.UpdateTelegramDestinationInput
+
+	if !h.bindJSON(c, &input) {
+		return
+	}
+
+	out, err := h.service.UpdateTelegramDestination(c.Request.Context(), shopID, destinationID, input)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) deleteTelegramDestination(c *gin.Context) {
+	shopID, destinationID, ok := parseDestinationID(c)
+
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteTelegramDestination(c.Request.Context(), shopID, destinationID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func parseDestinationID(c *gin.Context) (int64, int64, bool) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return 0, 0, false
+	}
+
+	destinationID, err := strconv.ParseInt(c.Param("destinationId"), 10, 64)
+
+	if err != nil || destinationID <= 0 {
+		respondError(c, domain.InvalidField("destinationId", "number", "invalid destinationId"))
+		return 0, 0, false
+	}
+
+	return shopID, destinationID, true
+}
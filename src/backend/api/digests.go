@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+
+	"growth-mvp/backend/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) getDigestSchedule(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	out, err := h.service.GetDigestSchedule(c.Request.Context(), shopID)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) updateDigestSchedule(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	var input domain.DigestScheduleInput
+
+	if !h.bindJSON(c, &input) {
+		return
+	}
+
+	out, err := h.service.UpdateDigestSchedule(c.Request.Context(), shopID, input)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) previewDigest(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	out, err := h.service.PreviewDigest(c.Request.Context(), shopID)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
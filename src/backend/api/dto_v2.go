@@ -0,0 +1,80 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"growth-mvp/backend/domain"
+)
+
+// Money is the v2 representation of an amount: a decimal string, so clients
+// never round through float64, and the shop's currency.
+type Money struct {
+	Amount   string `json:"amount" binding:"required"`
+	Currency string `json:"currency,omitempty"`
+}
+
+type CreateOrderInputV2 struct {
+	Number       string `json:"number" binding:"required"`
+	Total        Money  `json:"total" binding:"required"`
+	CustomerName string `json:"customerName" binding:"required"`
+	Status       string `json:"status,omitempty"`
+}
+
+type OrderV2 struct {
+	ID           int64     `json:"id"`
+	ShopID       int64     `json:"shopId"`
+	Number       string    `json:"number"`
+	Total        Money     `json:"total"`
+	CustomerName string    `json:"customerName"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"createdAt"`
+	SendStatus   string    `json:"sendStatus,omitempty"`
+}
+
+type OrderSendResultV2 struct {
+	Order      OrderV2 `json:"order"`
+	SendStatus string  `json:"sendStatus"`
+	SendError  *string `json:"sendError,omitempty"`
+}
+
+type ListOrdersResultV2 struct {
+	Items   []OrderV2 `json:"items"`
+	Limit   int       `json:"limit"`
+	Offset  int       `json:"offset"`
+	HasMore bool      `json:"hasMore"`
+}
+
+func newMoney(amount float64, currency string) Money {
+	return Money{Amount: strconv.FormatFloat(amount, 'f', 2, 64), Currency: currency}
+}
+
+func orderV2(order domain.Order, currency string) OrderV2 {
+	return OrderV2{
+		ID:           order.ID,
+		ShopID:       order.ShopID,
+		Number:       order.Number,
+		Total:        newMoney(order.Total, currency),
+		CustomerName: order.CustomerName,
+		Status:       order.Status,
+		CreatedAt:    order.CreatedAt,
+	}
+}
+
+func orderSendResultV2(result domain.OrderSendResult, currency string) OrderSendResultV2 {
+	return OrderSendResultV2{
+		Order:      orderV2(result.Order, currency),
+		SendStatus: result.SendStatus,
+		SendError:  result.SendError,
+	}
+}
+
+// sendStatusV2 lowercases the send log statuses so that v2 uses the same
+// vocabulary everywhere ("sent", "failed", "none").
+func sendStatusV2(status string) string {
+	if status == "" {
+		return "none"
+	}
+	return strings.ToLower(status)
+}
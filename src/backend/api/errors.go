@@ -0,0 +1,162 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"growth-mvp/backend/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const problemContentType = "application/problem+json"
+
+var (
+	errRateLimited  = domain.NewError(domain.KindRateLimited, "rate_limited", "rate limit exceeded")
+	errBodyTooLarge = domain.NewError(domain.KindTooLarge, "body_too_large", "request body is too large")
+	errInvalidCSRF  = domain.NewError(domain.KindForbidden, "invalid_csrf_token", "invalid csrf token")
+	errSessionOnly  = domain.NewError(domain.KindForbidden, "session_required", "only available for user sessions")
+)
+
+// Problem is an RFC 7807 problem details body extended with a stable code,
+// per-field validation errors and the request ID.
+type Problem struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	Instance  string              `json:"instance,omitempty"`
+	Code      string              `json:"code"`
+	RequestID string              `json:"requestId,omitempty"`
+	Errors    []domain.FieldError `json:"errors,omitempty"`
+}
+
+var kindStatus = map[domain.ErrorKind]int{
+	domain.KindInvalid:      http.StatusBadRequest,
+	domain.KindUnauthorized: http.StatusUnauthorized,
+	domain.KindForbidden:    http.StatusForbidden,
+	domain.KindNotFound:     http.StatusNotFound,
+	domain.KindConflict:     http.StatusConflict,
+	domain.KindTooLarge:     http.StatusRequestEntityTooLarge,
+	domain.KindRateLimited:  http.StatusTooManyRequests,
+}
+
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonFieldName)
+	}
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+	if name == "-" {
+		return ""
+	}
+
+	if name == "" {
+		return field.Name
+	}
+
+	return name
+}
+
+// handleErrors renders the last error added with c.Error as problem+json,
+// unless the handler has already written a response.
+func handleErrors(c *gin.Context) {
+	c.Next()
+
+	if c.Writer.Written() || len(c.Errors) == 0 {
+		return
+	}
+
+	writeProblem(c, c.Errors.Last().Err)
+}
+
+func writeProblem(c *gin.Context, err error) {
+	problem := Problem{
+		Type:      "about:blank",
+		Status:    http.StatusInternalServerError,
+		Code:      "internal",
+		Detail:    "internal server error",
+		Instance:  c.Request.URL.Path,
+		RequestID: domain.RequestIDFromContext(c.Request.Context()),
+	}
+
+	var domainErr *domain.Error
+
+	if errors.As(err, &domainErr) {
+		if status, ok := kindStatus[domainErr.Kind]; ok {
+			problem.Status = status
+			problem.Code = domainErr.Code
+			problem.Detail = err.Error()
+			problem.Errors = domainErr.Fields
+		}
+	}
+
+	problem.Title = http.StatusText(problem.Status)
+
+	body, _ := json.Marshal(problem)
+	c.Data(problem.Status, problemContentType, body)
+}
+
+func respondError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// bindError turns JSON decoding and validation failures into domain errors
+// that name the offending fields.
+func bindError(err error) error {
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	var tooLarge *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := make([]domain.FieldError, len(validationErrs))
+
+		for i, fe := range validationErrs {
+			fields[i] = domain.FieldError{Field: fe.Field(), Code: fe.Tag(), Message: fieldMessage(fe)}
+		}
+
+		return domain.ValidationFailed(fields)
+	case errors.As(err, &typeErr):
+		return domain.InvalidField(typeErr.Field, "type", fmt.Sprintf("%s must be a %s", typeErr.Field, typeErr.Type))
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return fmt.Errorf("%w: malformed JSON body", domain.ErrInvalidInput)
+	case errors.As(err, &tooLarge):
+		return fmt.Errorf("%w: must not exceed %d bytes", errBodyTooLarge, tooLarge.Limit)
+	default:
+		return fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+	}
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return fe.Field() + " is required"
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", fe.Field(), fe.Param())
+	case "gte", "min":
+		return fmt.Sprintf("%s must be at least %s", fe.Field(), fe.Param())
+	case "lt":
+		return fmt.Sprintf("%s must be less than %s", fe.Field(), fe.Param())
+	case "lte", "max":
+		return fmt.Sprintf("%s must be at most %s", fe.Field(), fe.Param())
+	case "len":
+		return fmt.Sprintf("%s must be %s characters long", fe.Field(), fe.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", fe.Field(), fe.Param())
+	default:
+		return fmt.Sprintf("%s failed %q validation", fe.Field(), fe.Tag())
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"errors"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -11,53 +12,387 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+const defaultMaxBodyBytes = 1 << 20
+
 type Handler struct {
 	service *domain.Service
+	shops   *domain.ShopService
+	apiKeys *domain.APIKeyService
+	auth    *domain.AuthService
+	limiter *RateLimiter
+	health  *Health
+	metrics Metrics
+
+	maxBodyBytes int64
+}
+
+type HandlerOption func(*Handler)
+
+func WithRateLimiter(limiter *RateLimiter) HandlerOption {
+	return func(h *Handler) {
+		h.limiter = limiter
+	}
+}
+
+func WithHealth(health *Health) HandlerOption {
+	return func(h *Handler) {
+		h.health = health
+	}
+}
+
+// WithMetrics records HTTP latency for every route and serves /metrics.
+func WithMetrics(metrics Metrics) HandlerOption {
+	return func(h *Handler) {
+		h.metrics = metrics
+	}
+}
+
+// WithMaxBodyBytes caps JSON request bodies; CSV imports keep their own limit.
+func WithMaxBodyBytes(n int64) HandlerOption {
+	return func(h *Handler) {
+		if n > 0 {
+			h.maxBodyBytes = n
+		}
+	}
+}
+
+func NewHandler(service *domain.Service, shops *domain.ShopService, apiKeys *domain.APIKeyService, auth *domain.AuthService, opts ...HandlerOption) *Handler {
+	h := &Handler{service: service, shops: shops, apiKeys: apiKeys, auth: auth, maxBodyBytes: defaultMaxBodyBytes, health: NewHealth(0)}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
 }
 
-func NewHandler(service *domain.Service) *Handler {
-	return &Handler{service: service}
+// versionHandlers holds the handlers whose request or response shape differs
+// between API versions; every other route is shared.
+type versionHandlers struct {
+	createOrder gin.HandlerFunc
+	listOrders  gin.HandlerFunc
+	resendOrder gin.HandlerFunc
 }
 
+// RegisterRoutes mounts /v1 and /v2. Unversioned paths are kept as aliases of
+// v1 for existing clients; both carry deprecation headers pointing at v2.
 func (h *Handler) RegisterRoutes(router *gin.Engine) {
-	router.POST("/shops/:shopId/telegram/connect", h.connectTelegram)
-	router.POST("/shops/:shopId/orders", h.createOrder)
-	router.GET("/shops/:shopId/orders", h.listOrders)
-	router.GET("/shops/:shopId/telegram/status", h.telegramStatus)
+	if h.metrics != nil {
+		router.Use(observeHTTP(h.metrics))
+		router.GET("/metrics", gin.WrapH(h.metrics.Handler()))
+	}
+
+	router.Use(handleErrors)
+
+	router.GET("/openapi.json", serveOpenAPI)
+	router.GET("/docs", serveDocs)
+	router.GET("/swagger-ui/*filepath", serveSwaggerUI)
+	router.GET("/livez", h.health.livez)
+	router.GET("/readyz", h.health.readyz)
+
+	v1 := versionHandlers{createOrder: h.createOrder, listOrders: h.listOrders, resendOrder: h.resendOrder}
+	v2 := versionHandlers{createOrder: h.createOrderV2, listOrders: h.listOrdersV2, resendOrder: h.resendOrderV2}
+
+	h.registerVersion(router.Group("", deprecated("")), v1)
+	h.registerVersion(router.Group("/v1", deprecated("/v1")), v1)
+	h.registerVersion(router.Group("/v2"), v2)
 }
 
-func (h *Handler) connectTelegram(c *gin.Context) {
+func (h *Handler) registerVersion(r *gin.RouterGroup, v versionHandlers) {
+	r.POST("/auth/login", h.rateLimitLogin, h.login)
+
+	api := r.Group("", h.authenticate, h.rateLimit)
+
+	api.POST("/auth/logout", h.requireUser, h.logout)
+	api.GET("/auth/me", h.requireUser, h.me)
+	api.POST("/users", h.requireAdmin, h.createUser)
+
+	api.POST("/shops", h.requireAdmin, h.createShop)
+	api.GET("/shops", h.requireAdmin, h.listShops)
+	api.GET("/shops/:shopId", h.authorize(), h.getShop)
+	api.PUT("/shops/:shopId", h.authorize(domain.ScopeIntegrationAdmin), h.updateShop)
+	api.DELETE("/shops/:shopId", h.authorize(domain.ScopeShopAdmin), h.deleteShop)
+
+	api.GET("/shops/:shopId/members", h.authorize(domain.ScopeShopAdmin), h.listMembers)
+	api.POST("/shops/:shopId/members", h.authorize(domain.ScopeShopAdmin), h.setMember)
+	api.DELETE("/shops/:shopId/members/:userId", h.authorize(domain.ScopeShopAdmin), h.removeMember)
+
+	api.POST("/shops/:shopId/api-keys", h.authorize(domain.ScopeIntegrationAdmin), h.createAPIKey)
+	api.GET("/shops/:shopId/api-keys", h.authorize(domain.ScopeIntegrationAdmin), h.listAPIKeys)
+	api.DELETE("/shops/:shopId/api-keys/:keyId", h.authorize(domain.ScopeIntegrationAdmin), h.revokeAPIKey)
+
+	api.GET("/channels", h.listChannels)
+	api.GET("/shops/:shopId/integrations", h.authorize(domain.ScopeIntegrationAdmin), h.listIntegrations)
+	api.POST("/shops/:shopId/integrations/:channel/connect", h.authorize(domain.ScopeIntegrationAdmin), h.connectIntegration)
+	api.PATCH("/shops/:shopId/integrations/:channel", h.authorize(domain.ScopeIntegrationAdmin), h.updateIntegration)
+	api.DELETE("/shops/:shopId/integrations/:channel", h.authorize(domain.ScopeIntegrationAdmin), h.disconnectIntegration)
+	api.GET("/shops/:shopId/integrations/:channel/status", h.authorize(domain.ScopeIntegrationAdmin), h.integrationStatus)
+
+	api.POST("/shops/:shopId/telegram/connect", h.authorize(domain.ScopeIntegrationAdmin), h.connectTelegram)
+	api.PATCH("/shops/:shopId/telegram", h.authorize(domain.ScopeIntegrationAdmin), h.updateTelegram)
+	api.DELETE("/shops/:shopId/telegram", h.authorize(domain.ScopeIntegrationAdmin), h.disconnectTelegram)
+	api.GET("/shops/:shopId/telegram/status", h.authorize(domain.ScopeIntegrationAdmin), h.telegramStatus)
+	api.GET("/shops/:shopId/telegram/destinations", h.authorize(domain.ScopeIntegrationAdmin), h.listTelegramDestinations)
+	api.POST("/shops/:shopId/telegram/destinations", h.authorize(domain.ScopeIntegrationAdmin), h.createTelegramDestination)
+	api.PATCH("/shops/:shopId/telegram/destinations/:destinationId", h.authorize(domain.ScopeIntegrationAdmin), h.updateTelegramDestination)
+	api.DELETE("/shops/:shopId/telegram/destinations/:destinationId", h.authorize(domain.ScopeIntegrationAdmin), h.deleteTelegramDestination)
+
+	api.GET("/shops/:shopId/webhooks", h.authorize(domain.ScopeIntegrationAdmin), h.listWebhooks)
+	api.POST("/shops/:shopId/webhooks", h.authorize(domain.ScopeIntegrationAdmin), h.createWebhook)
+	api.GET("/shops/:shopId/webhooks/:webhookId", h.authorize(domain.ScopeIntegrationAdmin), h.getWebhook)
+	api.PATCH("/shops/:shopId/webhooks/:webhookId", h.authorize(domain.ScopeIntegrationAdmin), h.updateWebhook)
+	api.DELETE("/shops/:shopId/webhooks/:webhookId", h.authorize(domain.ScopeIntegrationAdmin), h.deleteWebhook)
+	api.GET("/shops/:shopId/webhooks/:webhookId/deliveries", h.authorize(domain.ScopeIntegrationAdmin), h.listWebhookDeliveries)
+	api.POST("/shops/:shopId/webhooks/:webhookId/deliveries/:deliveryId/redeliver", h.authorize(domain.ScopeIntegrationAdmin), h.redeliverWebhook)
+
+	api.GET("/shops/:shopId/notification-rules", h.authorize(domain.ScopeIntegrationAdmin), h.listNotificationRules)
+	api.POST("/shops/:shopId/notification-rules", h.authorize(domain.ScopeIntegrationAdmin), h.createNotificationRule)
+	api.PATCH("/shops/:shopId/notification-rules/:ruleId", h.authorize(domain.ScopeIntegrationAdmin), h.updateNotificationRule)
+	api.DELETE("/shops/:shopId/notification-rules/:ruleId", h.authorize(domain.ScopeIntegrationAdmin), h.deleteNotificationRule)
+
+	api.GET("/shops/:shopId/digest", h.authorize(domain.ScopeIntegrationAdmin), h.getDigestSchedule)
+	api.PUT("/shops/:shopId/digest", h.authorize(domain.ScopeIntegrationAdmin), h.updateDigestSchedule)
+	api.GET("/shops/:shopId/digest/preview", h.authorize(domain.ScopeOrdersRead), h.previewDigest)
+
+	api.POST("/shops/:shopId/orders", h.authorize(domain.ScopeOrdersWrite), v.createOrder)
+	api.POST("/shops/:shopId/orders/import", h.authorize(domain.ScopeOrdersWrite), h.importOrders)
+	api.GET("/shops/:shopId/orders", h.authorize(domain.ScopeOrdersRead), v.listOrders)
+	api.POST("/shops/:shopId/orders/:orderId/resend", h.authorize(domain.ScopeOrdersWrite), v.resendOrder)
+	api.GET("/shops/:shopId/orders/:orderId/rule-evaluation", h.authorize(domain.ScopeOrdersRead), h.getRuleEvaluation)
+
+	api.GET("/shops/:shopId/audit", h.authorize(domain.ScopeIntegrationAdmin), h.listAuditEvents)
+}
+
+func (h *Handler) createShop(c *gin.Context) {
+	var input domain.ShopInput
+
+	if !h.bindJSON(c, &input) {
+		return
+	}
+
+	out, err := h.shops.CreateShop(c.Request.Context(), input)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, out)
+}
+
+func (h *Handler) listShops(c *gin.Context) {
+	limit, offset, ok := parsePagination(c)
+
+	if !ok {
+		return
+	}
+
+	out, err := h.shops.ListShops(c.Request.Context(), limit, offset)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) getShop(c *gin.Context) {
 	shopID, ok := parseShopID(c)
 
 	if !ok {
 		return
 	}
 
-	var input domain.ConnectTelegramInput
+	out, err := h.shops.GetShop(c.Request.Context(), shopID)
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	if strings.TrimSpace(input.BotToken) == "" || strings.TrimSpace(input.ChatID) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "botToken and chatId must be non-empty"})
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) updateShop(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	var input domain.ShopInput
+
+	if !h.bindJSON(c, &input) {
+		return
+	}
+
+	out, err := h.shops.UpdateShop(c.Request.Context(), shopID, input)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) deleteShop(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	if err := h.shops.DeleteShop(c.Request.Context(), shopID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) createAPIKey(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	var input domain.CreateAPIKeyInput
+
+	if !h.bindJSON(c, &input) {
+		return
+	}
+
+	out, err := h.apiKeys.CreateKey(c.Request.Context(), shopID, input)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, out)
+}
+
+func (h *Handler) listAPIKeys(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	out, err := h.apiKeys.ListKeys(c.Request.Context(), shopID)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) revokeAPIKey(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	keyID, err := strconv.ParseInt(c.Param("keyId"), 10, 64)
+
+	if err != nil || keyID <= 0 {
+		respondError(c, domain.InvalidField("keyId", "number", "invalid keyId"))
+		return
+	}
+
+	if err := h.apiKeys.RevokeKey(c.Request.Context(), shopID, keyID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) connectTelegram(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	var input domain.ConnectTelegramInput
+
+	if !h.bindJSON(c, &input) {
 		return
 	}
 
 	input.BotToken = strings.TrimSpace(input.BotToken)
 	input.ChatID = strings.TrimSpace(input.ChatID)
 
+	var fields []domain.FieldError
+
+	if input.BotToken == "" {
+		fields = append(fields, domain.FieldError{Field: "botToken", Code: "required", Message: "botToken must be non-empty"})
+	}
+
+	if input.ChatID == "" {
+		fields = append(fields, domain.FieldError{Field: "chatId", Code: "required", Message: "chatId must be non-empty"})
+	}
+
+	if len(fields) > 0 {
+		respondError(c, domain.ValidationFailed(fields))
+		return
+	}
+
 	out, err := h.service.ConnectTelegram(c.Request.Context(), shopID, input)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, out)
 }
 
+func (h *Handler) updateTelegram(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	var input domain.UpdateTelegramInput
+
+	if !h.bindJSON(c, &input) {
+		return
+	}
+
+	out, err := h.service.UpdateTelegram(c.Request.Context(), shopID, input)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) disconnectTelegram(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	if err := h.service.DisconnectTelegram(c.Request.Context(), shopID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 func (h *Handler) createOrder(c *gin.Context) {
 	shopID, ok := parseShopID(c)
 
@@ -66,8 +401,7 @@ func (h *Handler) createOrder(c *gin.Context) {
 	}
 
 	var input domain.CreateOrderInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !h.bindJSON(c, &input) {
 		return
 	}
 
@@ -76,46 +410,136 @@ func (h *Handler) createOrder(c *gin.Context) {
 
 	out, err := h.service.CreateOrder(c.Request.Context(), shopID, input)
 	if err != nil {
-		if errors.Is(err, domain.ErrShopNotIntegrated) {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, out)
 }
 
-func (h *Handler) listOrders(c *gin.Context) {
+func (h *Handler) importOrders(c *gin.Context) {
 	shopID, ok := parseShopID(c)
+
 	if !ok {
 		return
 	}
 
-	limit := 20
-	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
-		v, err := strconv.Atoi(raw)
+	notify := true
+
+	if raw := strings.TrimSpace(c.Query("notify")); raw != "" {
+		v, err := strconv.ParseBool(raw)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			respondError(c, domain.InvalidField("notify", "boolean", "invalid notify"))
 			return
 		}
-		limit = v
+		notify = v
 	}
 
-	offset := 0
-	if raw := strings.TrimSpace(c.Query("offset")); raw != "" {
-		v, err := strconv.Atoi(raw)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBodyBytes)
+
+	body := io.Reader(c.Request.Body)
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				respondError(c, bindError(err))
+				return
+			}
+			respondError(c, domain.InvalidField("file", "required", "multipart body must contain a \"file\" field"))
 			return
 		}
-		offset = v
+
+		f, err := file.Open()
+		if err != nil {
+			respondError(c, bindError(err))
+			return
+		}
+		defer f.Close()
+
+		body = f
+	}
+
+	rows, err := parseOrdersCSV(body)
+
+	if err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	out, err := h.service.ImportOrders(c.Request.Context(), shopID, rows, domain.ImportOrdersOptions{Notify: notify})
+
+	// once orders are saved the report is what the client needs; its rows carry the error
+	if err != nil && out.Imported == 0 {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) listOrders(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+	if !ok {
+		return
+	}
+
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
 	}
 
 	out, err := h.service.ListOrders(c.Request.Context(), shopID, limit, offset)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) resendOrder(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	orderID, err := strconv.ParseInt(c.Param("orderId"), 10, 64)
+
+	if err != nil || orderID <= 0 {
+		respondError(c, domain.InvalidField("orderId", "number", "invalid orderId"))
+		return
+	}
+
+	out, err := h.service.ResendOrder(c.Request.Context(), shopID, orderID)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, out)
+}
+
+func (h *Handler) listAuditEvents(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	limit, offset, ok := parsePagination(c)
+
+	if !ok {
+		return
+	}
+
+	out, err := h.service.ListAuditEvents(c.Request.Context(), shopID, limit, offset)
+
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -132,19 +556,55 @@ func (h *Handler) telegramStatus(c *gin.Context) {
 	out, err := h.service.GetTelegramStatus(c.Request.Context(), shopID)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, out)
 }
 
+// bindJSON decodes a body of at most maxBodyBytes and writes the error response itself.
+func (h *Handler) bindJSON(c *gin.Context, out any) bool {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxBodyBytes)
+
+	if err := c.ShouldBindJSON(out); err != nil {
+		respondError(c, bindError(err))
+		return false
+	}
+
+	return true
+}
+
+func parsePagination(c *gin.Context) (int, int, bool) {
+	limit := 20
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			respondError(c, domain.InvalidField("limit", "number", "invalid limit"))
+			return 0, 0, false
+		}
+		limit = v
+	}
+
+	offset := 0
+	if raw := strings.TrimSpace(c.Query("offset")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			respondError(c, domain.InvalidField("offset", "number", "invalid offset"))
+			return 0, 0, false
+		}
+		offset = v
+	}
+
+	return limit, offset, true
+}
+
 func parseShopID(c *gin.Context) (int64, bool) {
 	raw := c.Param("shopId")
 	shopID, err := strconv.ParseInt(raw, 10, 64)
 
 	if err != nil || shopID <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid shopId"})
+		respondError(c, domain.InvalidField("shopId", "number", "invalid shopId"))
 		return 0, false
 	}
 
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"growth-mvp/backend/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) createOrderV2(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	var input CreateOrderInputV2
+
+	if !h.bindJSON(c, &input) {
+		return
+	}
+
+	shop, err := h.shops.GetShop(c.Request.Context(), shopID)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	total, err := parseMoney(input.Total, shop.Currency)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	out, err := h.service.CreateOrder(c.Request.Context(), shopID, domain.CreateOrderInput{
+		Number:       strings.TrimSpace(input.Number),
+		Total:        total,
+		CustomerName: strings.TrimSpace(input.CustomerName),
+		Status:       input.Status,
+	})
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, orderSendResultV2(out, shop.Currency))
+}
+
+func (h *Handler) listOrdersV2(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	limit, offset, ok := parsePagination(c)
+
+	if !ok {
+		return
+	}
+
+	shop, err := h.shops.GetShop(c.Request.Context(), shopID)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	out, err := h.service.ListOrders(c.Request.Context(), shopID, limit, offset)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	items := make([]OrderV2, len(out.Items))
+
+	for i, item := range out.Items {
+		items[i] = orderV2(domain.Order{
+			ID:           item.ID,
+			ShopID:       item.ShopID,
+			Number:       item.Number,
+			Total:        item.Total,
+			CustomerName: item.CustomerName,
+			Status:       item.Status,
+			CreatedAt:    item.CreatedAt,
+		}, shop.Currency)
+		items[i].SendStatus = sendStatusV2(item.SendStatus)
+	}
+
+	c.JSON(http.StatusOK, ListOrdersResultV2{Items: items, Limit: out.Limit, Offset: out.Offset, HasMore: out.HasMore})
+}
+
+func (h *Handler) resendOrderV2(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	orderID, err := strconv.ParseInt(c.Param("orderId"), 10, 64)
+
+	if err != nil || orderID <= 0 {
+		respondError(c, domain.InvalidField("orderId", "number", "invalid orderId"))
+		return
+	}
+
+	shop, err := h.shops.GetShop(c.Request.Context(), shopID)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	out, err := h.service.ResendOrder(c.Request.Context(), shopID, orderID)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, orderSendResultV2(out, shop.Currency))
+}
+
+var moneyAmountPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]{1,2})?$`)
+
+// maxAmountDigits is what orders.total, NUMERIC(12,2), holds before the point.
+const maxAmountDigits = 10
+
+func parseMoney(money Money, currency string) (float64, error) {
+	if money.Currency != "" && !strings.EqualFold(money.Currency, currency) {
+		return 0, domain.InvalidField("total.currency", "currency", fmt.Sprintf("total.currency must be %s", currency))
+	}
+
+	raw := strings.TrimSpace(money.Amount)
+
+	if !moneyAmountPattern.MatchString(raw) {
+		return 0, domain.InvalidField("total.amount", "decimal", "total.amount must be a decimal with at most 2 fraction digits")
+	}
+
+	if digits, _, _ := strings.Cut(raw, "."); len(digits) > maxAmountDigits {
+		return 0, domain.InvalidField("total.amount", "max", fmt.Sprintf("total.amount must have at most %d integer digits", maxAmountDigits))
+	}
+
+	amount, err := strconv.ParseFloat(raw, 64)
+
+	if err != nil {
+		return 0, domain.InvalidField("total.amount", "decimal", "total.amount must be a decimal with at most 2 fraction digits")
+	}
+
+	if amount <= 0 {
+		return 0, domain.InvalidField("total.amount", "gt", "total.amount must be greater than 0")
+	}
+
+	return amount, nil
+}
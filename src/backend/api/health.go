@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"growth-mvp/backend/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultHealthTimeout = 2 * time.Second
+
+// HealthCheck probes one dependency. /readyz only reports whether it passed;
+// the detail and error of a failed check are logged. Non-critical checks only
+// degrade readiness.
+type HealthCheck struct {
+	Name     string
+	Critical bool
+	Check    func(ctx context.Context) (detail string, err error)
+}
+
+type Health struct {
+	checks   []HealthCheck
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+type healthStatus struct {
+	Status string                       `json:"status"`
+	Checks map[string]healthCheckResult `json:"checks,omitempty"`
+}
+
+// healthCheckResult is public, so it must not say more than the status:
+// errors can name hosts and addresses.
+type healthCheckResult struct {
+	Status string `json:"status"`
+}
+
+func NewHealth(timeout time.Duration, checks ...HealthCheck) *Health {
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+
+	return &Health{checks: checks, timeout: timeout}
+}
+
+// SetDraining makes /readyz fail so load balancers stop routing new requests
+// while in-flight ones finish.
+func (h *Health) SetDraining() {
+	h.draining.Store(true)
+}
+
+func (h *Health) livez(c *gin.Context) {
+	c.JSON(http.StatusOK, healthStatus{Status: "ok"})
+}
+
+func (h *Health) readyz(c *gin.Context) {
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, healthStatus{Status: "draining"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	logger := domain.LoggerFromContext(ctx)
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	results := make([]healthCheckResult, len(h.checks))
+
+	var wg sync.WaitGroup
+
+	for i, check := range h.checks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			results[i] = runHealthCheck(ctx, logger, check)
+		}()
+	}
+
+	wg.Wait()
+
+	out := healthStatus{Status: "ok", Checks: make(map[string]healthCheckResult, len(results))}
+	status := http.StatusOK
+
+	for i, result := range results {
+		out.Checks[h.checks[i].Name] = result
+
+		if result.Status == "ok" {
+			continue
+		}
+
+		if h.checks[i].Critical {
+			out.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+		} else if out.Status == "ok" {
+			out.Status = "degraded"
+		}
+	}
+
+	c.JSON(status, out)
+}
+
+func runHealthCheck(ctx context.Context, logger *slog.Logger, check HealthCheck) healthCheckResult {
+	started := time.Now()
+	detail, err := check.Check(ctx)
+
+	if err == nil {
+		return healthCheckResult{Status: "ok"}
+	}
+
+	logger.Warn("health check failed", "check", check.Name, "critical", check.Critical, "detail", detail,
+		"latencyMs", time.Since(started).Milliseconds(), "error", err)
+
+	return healthCheckResult{Status: "fail"}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"net/http"
+
+	"growth-mvp/backend/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) listChannels(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.ListChannels())
+}
+
+func (h *Handler) listIntegrations(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	out, err := h.service.ListIntegrations(c.Request.Context(), shopID)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) connectIntegration(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	var input domain.ConnectIntegrationInput
+
+	if !h.bindJSON(c, &input) {
+		return
+	}
+
+	out, err := h.service.ConnectIntegration(c.Request.Context(), shopID, channelParam(c), input)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) updateIntegration(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	var input domain.UpdateIntegrationInput
+
+	if !h.bindJSON(c, &input) {
+		return
+	}
+
+	out, err := h.service.UpdateIntegration(c.Request.Context(), shopID, channelParam(c), input)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) disconnectIntegration(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	if err := h.service.DisconnectIntegration(c.Request.Context(), shopID, channelParam(c)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) integrationStatus(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	out, err := h.service.GetIntegrationStatus(c.Request.Context(), shopID, channelParam(c))
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func channelParam(c *gin.Context) domain.ChannelType {
+	return domain.ChannelType(c.Param("channel"))
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"growth-mvp/backend/domain"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestLogger logs one line per request and puts a logger tagged with the
+// request and trace IDs into the context. It must run after RequestID.
+// The query string is left out since it may carry customer data.
+func RequestLogger(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		ctx := c.Request.Context()
+
+		requestLogger := logger.With("requestId", domain.RequestIDFromContext(ctx))
+
+		if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+			requestLogger = requestLogger.With("traceId", sc.TraceID().String())
+		}
+
+		c.Request = c.Request.WithContext(domain.ContextWithLogger(ctx, requestLogger))
+		c.Next()
+
+		status := c.Writer.Status()
+		route := c.FullPath()
+
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		attrs := []any{
+			"method", c.Request.Method,
+			"route", route,
+			"path", c.Request.URL.Path,
+			"status", status,
+			"latencyMs", time.Since(started).Milliseconds(),
+			"bytes", c.Writer.Size(),
+			"clientIp", c.ClientIP(),
+		}
+
+		level := slog.LevelInfo
+
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+
+			if len(c.Errors) > 0 {
+				attrs = append(attrs, "error", c.Errors.Last().Err.Error())
+			}
+		}
+
+		requestLogger.Log(ctx, level, "http request", attrs...)
+	}
+}
+
+// Recovery logs panics through slog instead of gin's plain-text writer and
+// answers with the standard internal problem.
+func Recovery(logger *slog.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		logger.ErrorContext(c.Request.Context(), "panic recovered",
+			"requestId", domain.RequestIDFromContext(c.Request.Context()),
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"panic", fmt.Sprint(recovered),
+			"stack", string(debug.Stack()),
+		)
+		writeProblem(c, errors.New("panic"))
+		c.Abort()
+	})
+}
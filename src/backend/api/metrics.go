@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const unmatchedRoute = "unmatched"
+
+type Metrics interface {
+	ObserveHTTPRequest(method, route string, status int, duration time.Duration)
+	Handler() http.Handler
+}
+
+// observeHTTP labels requests by route template, never the raw path, to keep
+// label cardinality bounded.
+func observeHTTP(metrics Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		metrics.ObserveHTTPRequest(c.Request.Method, route, c.Writer.Status(), time.Since(started))
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+//go:embed docs.html
+var docsPage []byte
+
+// swaggerUI is the vendored Swagger UI the docs page loads, so /docs works
+// without a CDN; the version is in swagger-ui/NOTICE.
+//
+//go:embed swagger-ui/swagger-ui-bundle.js swagger-ui/swagger-ui.css swagger-ui/NOTICE
+var swaggerUI embed.FS
+
+// OpenAPISpec returns the embedded OpenAPI document.
+func OpenAPISpec() []byte {
+	return openAPISpec
+}
+
+func serveOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", openAPISpec)
+}
+
+func serveDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+}
+
+var swaggerUIFiles = func() http.FileSystem {
+	files, err := fs.Sub(swaggerUI, "swagger-ui")
+	if err != nil {
+		panic(err)
+	}
+	return http.FS(files)
+}()
+
+func serveSwaggerUI(c *gin.Context) {
+	c.FileFromFS(c.Param("filepath"), swaggerUIFiles)
+}
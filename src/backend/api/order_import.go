@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"growth-mvp/backend/domain"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+const maxImportBodyBytes = 10 << 20
+
+var importColumns = map[string]string{
+	"number":        "number",
+	"total":         "total",
+	"customername":  "customerName",
+	"customer_name": "customerName",
+	"createdat":     "createdAt",
+	"created_at":    "createdAt",
+	"status":        "status",
+}
+
+func parseOrdersCSV(r io.Reader) ([]domain.ImportOrderRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("csv is empty")
+		}
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+
+	columns := map[string]int{}
+
+	for i, name := range header {
+		key := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if column, ok := importColumns[key]; ok {
+			columns[column] = i
+		}
+	}
+
+	for _, required := range []string{"number", "total", "customerName"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("csv header must contain %q column", required)
+		}
+	}
+
+	var rows []domain.ImportOrderRow
+
+	for {
+		record, err := reader.Read()
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rows = append(rows, domain.ImportOrderRow{Line: parseErr.Line, Err: err})
+				continue
+			}
+			return nil, fmt.Errorf("read csv: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, parseOrderRecord(line, record, columns))
+	}
+
+	return rows, nil
+}
+
+func parseOrderRecord(line int, record []string, columns map[string]int) domain.ImportOrderRow {
+	field := func(name string) string {
+		idx, ok := columns[name]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[idx])
+	}
+
+	row := domain.ImportOrderRow{
+		Line: line,
+		Order: domain.CreateOrderInput{
+			Number:       field("number"),
+			CustomerName: field("customerName"),
+			Status:       field("status"),
+		},
+	}
+
+	if raw := field("total"); raw != "" {
+		total, err := strconv.ParseFloat(strings.ReplaceAll(raw, ",", "."), 64)
+		if err != nil {
+			row.Err = fmt.Errorf("invalid total %q", raw)
+			return row
+		}
+		row.Order.Total = total
+	}
+
+	if raw := field("createdAt"); raw != "" {
+		createdAt, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			row.Err = fmt.Errorf("invalid createdAt %q, expected RFC 3339", raw)
+			return row
+		}
+		row.CreatedAt = &createdAt
+	}
+
+	if err := binding.Validator.ValidateStruct(&row.Order); err != nil {
+		row.Err = bindError(err)
+	}
+
+	return row
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"growth-mvp/backend/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) listNotificationRules(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	out, err := h.service.ListNotificationRules(c.Request.Context(), shopID)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) createNotificationRule(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	var input domain.NotificationRuleInput
+
+	if !h.bindJSON(c, &input) {
+		return
+	}
+
+	out, err := h.service.CreateNotificationRule(c.Request.Context(), shopID, input)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, out)
+}
+
+func (h *Handler) updateNotificationRule(c *gin.Context) {
+	shopID, ruleID, ok := parseRuleID(c)
+
+	if !ok {
+		return
+	}
+
+	var input domain.UpdateNotificationRuleInput
+
+	if !h.bindJSON(c, &input) {
+		return
+	}
+
+	out, err := h.service.UpdateNotificationRule(c.Request.Context(), shopID, ruleID, input)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) deleteNotificationRule(c *gin.Context) {
+	shopID, ruleID, ok := parseRuleID(c)
+
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteNotificationRule(c.Request.Context(), shopID, ruleID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) getRuleEvaluation(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	orderID, err := strconv.ParseInt(c.Param("orderId"), 10, 64)
+
+	if err != nil || orderID <= 0 {
+		respondError(c, domain.InvalidField("orderId", "number", "invalid orderId"))
+		return
+	}
+
+	out, err := h.service.GetRuleEvaluation(c.Request.Context(), shopID, orderID)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func parseRuleID(c *gin.Context) (int64, int64, bool) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return 0, 0, false
+	}
+
+	ruleID, err := strconv.ParseInt(c.Param("ruleId"), 10, 64)
+
+	if err != nil || ruleID <= 0 {
+		respondError(c, domain.InvalidField("ruleId", "number", "invalid ruleId"))
+		return 0, 0, false
+	}
+
+	return shopID, ruleID, true
+}
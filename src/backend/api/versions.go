@@ -0,0 +1,28 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const latestVersionPrefix = "/v2"
+
+// v1DeprecatedAt is when v2 became available; sent as an RFC 9745 Deprecation header.
+var v1DeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// deprecated marks responses of an old version and links the same resource in
+// the latest one. prefix is the version prefix of the group, "" for aliases.
+func deprecated(prefix string) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", v1DeprecatedAt.Unix())
+
+	return func(c *gin.Context) {
+		successor := latestVersionPrefix + strings.TrimPrefix(c.Request.URL.Path, prefix)
+
+		c.Header("Deprecation", deprecation)
+		c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		c.Next()
+	}
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"growth-mvp/backend/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) listWebhooks(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	out, err := h.service.ListWebhooks(c.Request.Context(), shopID)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) createWebhook(c *gin.Context) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return
+	}
+
+	var input domain.WebhookInput
+
+	if !h.bindJSON(c, &input) {
+		return
+	}
+
+	out, err := h.service.CreateWebhook(c.Request.Context(), shopID, input)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, out)
+}
+
+func (h *Handler) getWebhook(c *gin.Context) {
+	shopID, webhookID, ok := parseWebhookID(c)
+
+	if !ok {
+		return
+	}
+
+	out, err := h.service.GetWebhook(c.Request.Context(), shopID, webhookID)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) updateWebhook(c *gin.Context) {
+	shopID, webhookID, ok := parseWebhookID(c)
+
+	if !ok {
+		return
+	}
+
+	var input domain.UpdateWebhookInput
+
+	if !h.bindJSON(c, &input) {
+		return
+	}
+
+	out, err := h.service.UpdateWebhook(c.Request.Context(), shopID, webhookID, input)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) deleteWebhook(c *gin.Context) {
+	shopID, webhookID, ok := parseWebhookID(c)
+
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteWebhook(c.Request.Context(), shopID, webhookID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) listWebhookDeliveries(c *gin.Context) {
+	shopID, webhookID, ok := parseWebhookID(c)
+
+	if !ok {
+		return
+	}
+
+	limit, offset, ok := parsePagination(c)
+
+	if !ok {
+		return
+	}
+
+	out, err := h.service.ListWebhookDeliveries(c.Request.Context(), shopID, webhookID, limit, offset)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) redeliverWebhook(c *gin.Context) {
+	shopID, webhookID, ok := parseWebhookID(c)
+
+	if !ok {
+		return
+	}
+
+	deliveryID, err := strconv.ParseInt(c.Param("deliveryId"), 10, 64)
+
+	if err != nil || deliveryID <= 0 {
+		respondError(c, domain.InvalidField("deliveryId", "number", "invalid deliveryId"))
+		return
+	}
+
+	out, err := h.service.RedeliverWebhook(c.Request.Context(), shopID, webhookID, deliveryID)
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, out)
+}
+
+func parseWebhookID(c *gin.Context) (int64, int64, bool) {
+	shopID, ok := parseShopID(c)
+
+	if !ok {
+		return 0, 0, false
+	}
+
+	webhookID, err := strconv.ParseInt(c.Param("webhookId"), 10, 64)
+
+	if err != nil || webhookID <= 0 {
+		respondError(c, domain.InvalidField("webhookId", "number", "invalid webhookId"))
+		return 0, 0, false
+	}
+
+	return shopID, webhookID, true
+}
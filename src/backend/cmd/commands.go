@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"growth-mvp/backend/adapters/postgres"
+	"growth-mvp/backend/domain"
+)
+
+type commandDeps struct {
+	integrations *postgres.IntegrationRepository
+	webhooks     *postgres.WebhookRepository
+	tokenCipher  *postgres.TokenCipher
+	auth         *domain.AuthService
+}
+
+func runCommand(ctx context.Context, logger *slog.Logger, args []string, deps commandDeps) error {
+	switch args[0] {
+	case "reencrypt-tokens":
+		n, err := deps.integrations.ReencryptTokens(ctx)
+
+		if err != nil {
+			return fmt.Errorf("re-encrypt integration secrets: %w", err)
+		}
+
+		logger.Info("integration secrets re-encrypted", "count", n, "keyId", deps.tokenCipher.ActiveKeyID())
+
+		n, err = deps.webhooks.ReencryptTokens(ctx)
+
+		if err != nil {
+			return fmt.Errorf("re-encrypt webhook secrets: %w", err)
+		}
+
+		logger.Info("webhook secrets re-encrypted", "count", n, "keyId", deps.tokenCipher.ActiveKeyID())
+		return nil
+
+	case "create-user":
+		fs := flag.NewFlagSet("create-user", flag.ContinueOnError)
+		email := fs.String("email", "", "user e-mail")
+		password := fs.String("password", "", "user password")
+		shopID := fs.Int64("shop", 0, "shop to add the user to")
+		role := fs.String("role", string(domain.RoleOwner), "role in the shop: owner, manager or viewer")
+
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		user, err := deps.auth.CreateUser(ctx, domain.CreateUserInput{Email: *email, Password: *password})
+
+		if err != nil {
+			return fmt.Errorf("create user: %w", err)
+		}
+
+		logger.Info("user created", "userId", user.ID, "email", user.Email)
+
+		if *shopID == 0 {
+			return nil
+		}
+
+		if _, err := deps.auth.SetMember(ctx, *shopID, domain.SetMemberInput{Email: user.Email, Role: domain.Role(*role)}); err != nil {
+			return fmt.Errorf("add user to shop: %w", err)
+		}
+
+		logger.Info("user added to shop", "userId", user.ID, "shopId", *shopID, "role", *role)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
@@ -4,7 +4,13 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"growth-mvp/backend/adapters/email"
+	"growth-mvp/backend/adapters/telegram"
+	"growth-mvp/backend/adapters/tracing"
+	"growth-mvp/backend/domain"
 )
 
 type Config struct {
@@ -14,20 +20,101 @@ type Config struct {
 	FrontendURL         string
 	TelegramSendTimeout time.Duration
 	TelegramMaxAttempts int
+	TelegramAPIBaseURL  string
+	TelegramHealthCheck bool
+	WebhookTimeout      time.Duration
+	// OutboundAllowedHosts may be reached on internal addresses by shop
+	// webhooks and chat webhooks.
+	OutboundAllowedHosts []string
+	DeferredPollEvery    time.Duration
+	DigestPollEvery      time.Duration
+	AdminAPIKey          string
+	SessionTTL           time.Duration
+	MaxBodyBytes         int64
+	ShutdownDrainDelay   time.Duration
+	MetricsEnabled       bool
+	TraceExporter        string
+	ServiceName          string
+
+	SMTP        email.Config
+	SMTPTimeout time.Duration
+
+	RateLimitStore   string
+	RateLimitPerKey  domain.RateLimit
+	RateLimitPerShop domain.RateLimit
+	RateLimitLogin   domain.RateLimit
+	// TrustedProxies may set X-Forwarded-For; without them the peer address is
+	// the client, so the login limit cannot be dodged with a forged header.
+	TrustedProxies []string
+
+	TokenEncryptionKeys      string
+	TokenEncryptionKeysFile  string
+	TokenEncryptionActiveKey string
 }
 
 func LoadConfig() (Config, error) {
 	cfg := Config{
-		Port:                env("PORT", "8080"),
-		DatabaseURL:         os.Getenv("DATABASE_URL"),
-		MigrationsPath:      env("MIGRATIONS_PATH", "migrations"),
-		FrontendURL:         env("FRONTEND_URL", "http://localhost:5173"),
-		TelegramSendTimeout: envDuration("TELEGRAM_SEND_TIMEOUT", 5*time.Second),
-		TelegramMaxAttempts: envInt("TELEGRAM_MAX_ATTEMPTS", 3),
+		Port:                 env("PORT", "8080"),
+		DatabaseURL:          os.Getenv("DATABASE_URL"),
+		MigrationsPath:       env("MIGRATIONS_PATH", "migrations"),
+		FrontendURL:          env("FRONTEND_URL", "http://localhost:5173"),
+		TelegramSendTimeout:  envDuration("TELEGRAM_SEND_TIMEOUT", 5*time.Second),
+		TelegramMaxAttempts:  envInt("TELEGRAM_MAX_ATTEMPTS", 3),
+		TelegramAPIBaseURL:   env("TELEGRAM_API_BASE_URL", telegram.DefaultBaseURL),
+		TelegramHealthCheck:  os.Getenv("TELEGRAM_HEALTHCHECK") == "true",
+		WebhookTimeout:       envDuration("WEBHOOK_TIMEOUT", 5*time.Second),
+		OutboundAllowedHosts: envList("OUTBOUND_ALLOWED_HOSTS"),
+		DeferredPollEvery:    envDuration("DEFERRED_POLL_INTERVAL", 30*time.Second),
+		DigestPollEvery:      envDuration("DIGEST_POLL_INTERVAL", time.Minute),
+		AdminAPIKey:          os.Getenv("ADMIN_API_KEY"),
+		SessionTTL:           envDuration("SESSION_TTL", 12*time.Hour),
+		MaxBodyBytes:         int64(envInt("MAX_BODY_BYTES", 1<<20)),
+		ShutdownDrainDelay:   envDuration("SHUTDOWN_DRAIN_DELAY", 5*time.Second),
+		MetricsEnabled:       os.Getenv("METRICS_ENABLED") != "false",
+		TraceExporter:        env("OTEL_TRACES_EXPORTER", tracing.ExporterNone),
+		ServiceName:          env("OTEL_SERVICE_NAME", "growth-mvp-api"),
+		RateLimitStore:       env("RATE_LIMIT_STORE", "memory"),
+		TrustedProxies:       envList("TRUSTED_PROXIES"),
+
+		SMTP: email.Config{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     envInt("SMTP_PORT", 587),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+			TLS:      env("SMTP_TLS", email.TLSStartTLS),
+		},
+		SMTPTimeout: envDuration("SMTP_TIMEOUT", 10*time.Second),
+
+		TokenEncryptionKeys:      os.Getenv("TOKEN_ENCRYPTION_KEYS"),
+		TokenEncryptionKeysFile:  os.Getenv("TOKEN_ENCRYPTION_KEYS_FILE"),
+		TokenEncryptionActiveKey: os.Getenv("TOKEN_ENCRYPTION_ACTIVE_KEY"),
 	}
 	if cfg.DatabaseURL == "" {
 		return Config{}, fmt.Errorf("DATABASE_URL is required")
 	}
+	if cfg.TokenEncryptionKeys == "" && cfg.TokenEncryptionKeysFile == "" {
+		return Config{}, fmt.Errorf("TOKEN_ENCRYPTION_KEYS or TOKEN_ENCRYPTION_KEYS_FILE is required")
+	}
+	if cfg.TraceExporter != tracing.ExporterNone && cfg.TraceExporter != tracing.ExporterOTLP && cfg.TraceExporter != tracing.ExporterStdout {
+		return Config{}, fmt.Errorf("OTEL_TRACES_EXPORTER must be none, otlp or stdout")
+	}
+	if tls := cfg.SMTP.TLS; tls != email.TLSNone && tls != email.TLSStartTLS && tls != email.TLSImplicit {
+		return Config{}, fmt.Errorf("SMTP_TLS must be none, starttls or tls")
+	}
+	if cfg.RateLimitStore != "memory" && cfg.RateLimitStore != "postgres" {
+		return Config{}, fmt.Errorf("RATE_LIMIT_STORE must be memory or postgres")
+	}
+	var err error
+	if cfg.RateLimitPerKey, err = domain.ParseRateLimit(env("RATE_LIMIT_PER_KEY", "300/1m")); err != nil {
+		return Config{}, fmt.Errorf("RATE_LIMIT_PER_KEY: %w", err)
+	}
+	if cfg.RateLimitPerShop, err = domain.ParseRateLimit(env("RATE_LIMIT_PER_SHOP", "600/1m")); err != nil {
+		return Config{}, fmt.Errorf("RATE_LIMIT_PER_SHOP: %w", err)
+	}
+	if cfg.RateLimitLogin, err = domain.ParseRateLimit(env("RATE_LIMIT_LOGIN", "10/1m")); err != nil {
+		return Config{}, fmt.Errorf("RATE_LIMIT_LOGIN: %w", err)
+	}
 	return cfg, nil
 }
 
@@ -39,6 +126,19 @@ func env(key, fallback string) string {
 	return v
 }
 
+// envList splits a comma-separated variable, dropping empty items.
+func envList(key string) []string {
+	var out []string
+
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+
+	return out
+}
+
 func envDuration(key string, fallback time.Duration) time.Duration {
 	v := os.Getenv(key)
 
@@ -3,20 +3,32 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
+	"growth-mvp/backend/adapters/discord"
+	"growth-mvp/backend/adapters/egress"
+	"growth-mvp/backend/adapters/email"
+	"growth-mvp/backend/adapters/memory"
+	"growth-mvp/backend/adapters/metrics"
 	"growth-mvp/backend/adapters/postgres"
+	"growth-mvp/backend/adapters/slack"
 	"growth-mvp/backend/adapters/telegram"
+	"growth-mvp/backend/adapters/tracing"
+	"growth-mvp/backend/adapters/webhook"
 	"growth-mvp/backend/api"
 	"growth-mvp/backend/domain"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func main() {
@@ -33,6 +45,22 @@ func main() {
 
 	defer stop()
 
+	shutdownTracing, err := tracing.Setup(ctx, cfg.TraceExporter, cfg.ServiceName)
+
+	if err != nil {
+		logger.Error("failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := shutdownTracing(flushCtx); err != nil {
+			logger.Error("failed to flush traces", "error", err)
+		}
+	}()
+
 	if err := postgres.RunMigrations(cfg.DatabaseURL, cfg.MigrationsPath); err != nil {
 		logger.Error("failed to run migrations", "error", err)
 		os.Exit(1)
@@ -47,16 +75,118 @@ func main() {
 
 	defer db.Close()
 
-	integrationRepo := postgres.NewIntegrationRepository(db)
-	orderRepo := postgres.NewOrderRepository(db)
-	sendLogRepo := postgres.NewSendLogRepository(db)
-	telegramClient := telegram.NewClient(cfg.TelegramSendTimeout)
+	tokenCipher, err := newTokenCipher(cfg)
+
+	if err != nil {
+		logger.Error("failed to load token encryption keys", "error", err)
+		os.Exit(1)
+	}
+
+	shopRepo := postgres.NewShopRepository(db)
+	integrationRepo := postgres.NewIntegrationRepository(db, tokenCipher)
+	webhookRepo := postgres.NewWebhookRepository(db, tokenCipher)
+	destinationRepo := postgres.NewTelegramDestinationRepository(db)
+	ruleRepo := postgres.NewNotificationRuleRepository(db)
+	orderRepo := tracing.NewOrderRepository(postgres.NewOrderRepository(db))
+	notificationLogRepo := tracing.NewNotificationLogRepository(postgres.NewNotificationLogRepository(db))
+	digestRepo := postgres.NewDigestRepository(db)
+	apiKeyRepo := postgres.NewAPIKeyRepository(db)
+	userRepo := postgres.NewUserRepository(db)
+	sessionRepo := postgres.NewSessionRepository(db)
+	memberRepo := postgres.NewMemberRepository(db)
+	auditRepo := postgres.NewAuditRepository(db)
+
+	prom := metrics.NewPrometheus()
+
+	if err := prom.Register(metrics.NewPoolCollector(db)); err != nil {
+		logger.Error("failed to register pool metrics", "error", err)
+		os.Exit(1)
+	}
+
+	var deliveryMetrics domain.Metrics = domain.NopMetrics{}
+	if cfg.MetricsEnabled {
+		deliveryMetrics = prom
+	}
+
+	telegramClient := telegram.NewClient(cfg.TelegramAPIBaseURL, cfg.TelegramSendTimeout, telegram.WithMetrics(deliveryMetrics))
+
+	outbound := egress.Config{Timeout: cfg.WebhookTimeout, AllowedHosts: cfg.OutboundAllowedHosts}
+	notifiers := domain.NewNotifierRegistry().
+		Register(domain.ChannelTelegram, tracing.NewNotifier(domain.ChannelTelegram, telegram.NewNotifier(telegramClient))).
+		Register(domain.ChannelEmail, tracing.NewNotifier(domain.ChannelEmail, email.NewNotifier(cfg.SMTP, egress.Config{Timeout: cfg.SMTPTimeout, AllowedHosts: cfg.OutboundAllowedHosts}))).
+		Register(domain.ChannelSlack, tracing.NewNotifier(domain.ChannelSlack, slack.NewNotifier(outbound))).
+		Register(domain.ChannelDiscord, tracing.NewNotifier(domain.ChannelDiscord, discord.NewNotifier(outbound)))
+
+	webhookNotifier := tracing.NewNotifier(domain.ChannelWebhook, webhook.NewNotifier(outbound))
+
+	service := domain.NewService(integrationRepo, orderRepo, notificationLogRepo, notifiers, cfg.TelegramMaxAttempts,
+		domain.WithAuditLog(auditRepo), domain.WithMetrics(deliveryMetrics), domain.WithLogger(logger),
+		domain.WithWebhooks(webhookRepo, webhookNotifier), domain.WithTelegramDestinations(destinationRepo),
+		domain.WithNotificationRules(ruleRepo), domain.WithShops(shopRepo), domain.WithDigests(digestRepo),
+		domain.WithBatchTimers(ctx))
+	shopService := domain.NewShopService(shopRepo)
+
+	if cfg.AdminAPIKey == "" {
+		logger.Warn("ADMIN_API_KEY is not set, shops and the first API keys cannot be created")
+	}
+
+	apiKeyService := domain.NewAPIKeyService(apiKeyRepo, cfg.AdminAPIKey)
+	authService := domain.NewAuthService(userRepo, sessionRepo, memberRepo, cfg.SessionTTL)
+
+	if len(os.Args) > 1 {
+		deps := commandDeps{integrations: integrationRepo, webhooks: webhookRepo, tokenCipher: tokenCipher, auth: authService}
+
+		if err := runCommand(ctx, logger, os.Args[1:], deps); err != nil {
+			logger.Error("command failed", "command", os.Args[1], "error", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	var rateLimitStore domain.RateLimitStore = memory.NewRateLimitStore()
+	if cfg.RateLimitStore == "postgres" {
+		rateLimitStore = postgres.NewRateLimitStore(db)
+	}
+
+	scheduler := domain.NewScheduler(postgres.NewAdvisoryLocker(db), logger).
+		Add(domain.Job{Name: "deferred-notifications", Every: cfg.DeferredPollEvery, Run: func(ctx context.Context, _ time.Time) error {
+			return service.DeliverAllDue(ctx)
+		}}).
+		Add(domain.Job{Name: "digests", Every: cfg.DigestPollEvery, Run: func(ctx context.Context, now time.Time) error {
+			_, err := service.SendDueDigests(ctx, now)
+			return err
+		}})
 
-	service := domain.NewService(integrationRepo, orderRepo, sendLogRepo, telegramClient, cfg.TelegramMaxAttempts)
-	handler := api.NewHandler(service)
+	go scheduler.Start(ctx)
+
+	logger.Info("rate limits", "store", cfg.RateLimitStore, "perKey", cfg.RateLimitPerKey.String(), "perShop", cfg.RateLimitPerShop.String(), "login", cfg.RateLimitLogin.String())
+
+	health := api.NewHealth(2*time.Second, healthChecks(cfg, db, telegramClient)...)
+
+	handlerOpts := []api.HandlerOption{
+		api.WithRateLimiter(api.NewRateLimiter(rateLimitStore, cfg.RateLimitPerKey, cfg.RateLimitPerShop, cfg.RateLimitLogin)),
+		api.WithMaxBodyBytes(cfg.MaxBodyBytes),
+		api.WithHealth(health),
+	}
+	if cfg.MetricsEnabled {
+		handlerOpts = append(handlerOpts, api.WithMetrics(prom))
+	}
+
+	handler := api.NewHandler(service, shopService, apiKeyService, authService, handlerOpts...)
+
+	if os.Getenv(gin.EnvGinMode) == "" {
+		gin.SetMode(gin.ReleaseMode)
+	}
 
 	router := gin.New()
-	router.Use(gin.Recovery(), gin.Logger())
+
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logger.Error("invalid TRUSTED_PROXIES", "error", err)
+		os.Exit(1)
+	}
+
+	router.Use(api.Recovery(logger), otelgin.Middleware(cfg.ServiceName), api.RequestID(), api.RequestLogger(logger))
 	router.Use(cors.New(cors.Config{
 		AllowOrigins: []string{cfg.FrontendURL},
 		AllowMethods: []string{
@@ -72,7 +202,11 @@ func main() {
 			"Content-Type",
 			"Accept",
 			"Authorization",
+			"X-API-Key",
+			"X-CSRF-Token",
+			api.RequestIDHeader,
 		},
+		ExposeHeaders:    []string{api.RequestIDHeader, "Retry-After", "Deprecation", "Link"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -83,16 +217,22 @@ func main() {
 		Handler: router,
 	}
 
+	stopped := make(chan struct{})
+
 	go func() {
+		defer close(stopped)
 		<-ctx.Done()
 
+		health.SetDraining()
+		logger.Info("draining before shutdown", "delay", cfg.ShutdownDrainDelay.String())
+		time.Sleep(cfg.ShutdownDrainDelay)
+
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
 		if err := server.Shutdown(shutdownCtx); err != nil {
 			logger.Error("http shutdown failed", "error", err)
 		}
-
 	}()
 
 	logger.Info("starting API server", "port", cfg.Port)
@@ -101,4 +241,62 @@ func main() {
 		logger.Error("server stopped with error", "error", err)
 		os.Exit(1)
 	}
+
+	<-stopped
+}
+
+func healthChecks(cfg Config, db *pgxpool.Pool, telegramClient *telegram.Client) []api.HealthCheck {
+	checks := []api.HealthCheck{
+		{
+			Name:     "database",
+			Critical: true,
+			Check: func(ctx context.Context) (string, error) {
+				return "", db.Ping(ctx)
+			},
+		},
+		{
+			Name:     "migrations",
+			Critical: true,
+			Check: func(ctx context.Context) (string, error) {
+				version, dirty, err := postgres.MigrationVersion(ctx, db)
+
+				if err != nil {
+					return "", err
+				}
+
+				if dirty {
+					return "", fmt.Errorf("migration %d is dirty", version)
+				}
+
+				return fmt.Sprintf("version %d", version), nil
+			},
+		},
+	}
+
+	if cfg.TelegramHealthCheck {
+		checks = append(checks, api.HealthCheck{
+			Name: "telegram",
+			Check: func(ctx context.Context) (string, error) {
+				return cfg.TelegramAPIBaseURL, telegramClient.Ping(ctx)
+			},
+		})
+	}
+
+	return checks
+}
+
+func newTokenCipher(cfg Config) (*postgres.TokenCipher, error) {
+	keys, lastID, err := postgres.LoadKeyring(cfg.TokenEncryptionKeys, cfg.TokenEncryptionKeysFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	activeID := cfg.TokenEncryptionActiveKey
+
+	if activeID == "" {
+		activeID = lastID
+	}
+
+	return postgres.NewTokenCipher(keys, activeID)
 }
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+var (
+	ErrUnauthorized = NewError(KindUnauthorized, "unauthorized", "missing or invalid credentials")
+	ErrForbidden    = NewError(KindForbidden, "forbidden", "not allowed")
+)
+
+type Scope string
+
+const (
+	ScopeOrdersWrite      Scope = "orders:write"
+	ScopeOrdersRead       Scope = "orders:read"
+	ScopeIntegrationAdmin Scope = "integration:admin"
+	ScopeShopAdmin        Scope = "shop:admin"
+)
+
+// APIKeyScopes are the scopes that can be granted to an API key. Shop
+// administration is reserved for owners and the global admin key.
+var APIKeyScopes = []Scope{ScopeOrdersWrite, ScopeOrdersRead, ScopeIntegrationAdmin}
+
+type Role string
+
+const (
+	RoleOwner   Role = "owner"
+	RoleManager Role = "manager"
+	RoleViewer  Role = "viewer"
+)
+
+var roleScopes = map[Role][]Scope{
+	RoleOwner:   {ScopeOrdersRead, ScopeOrdersWrite, ScopeIntegrationAdmin, ScopeShopAdmin},
+	RoleManager: {ScopeOrdersRead, ScopeOrdersWrite, ScopeIntegrationAdmin},
+	RoleViewer:  {ScopeOrdersRead},
+}
+
+func (r Role) Valid() bool {
+	_, ok := roleScopes[r]
+	return ok
+}
+
+// Principal is the authenticated caller of a request: the global admin key, a
+// shop API key, or a user session with per-shop roles.
+type Principal struct {
+	Admin bool
+
+	KeyID  int64
+	ShopID int64
+	Scopes []Scope
+
+	UserID    int64
+	Roles     map[int64]Role
+	CSRFToken string
+}
+
+func (p Principal) Member(shopID int64) bool {
+	if p.Admin {
+		return true
+	}
+
+	if p.UserID != 0 {
+		_, ok := p.Roles[shopID]
+		return ok
+	}
+
+	return shopID != 0 && p.ShopID == shopID
+}
+
+func (p Principal) Can(shopID int64, scope Scope) bool {
+	if p.Admin {
+		return true
+	}
+
+	if p.UserID != 0 {
+		return slices.Contains(roleScopes[p.Roles[shopID]], scope)
+	}
+
+	return shopID != 0 && p.ShopID == shopID && slices.Contains(p.Scopes, scope)
+}
+
+// Actor identifies the principal in audit records.
+func (p Principal) Actor() string {
+	switch {
+	case p.Admin:
+		return "admin"
+	case p.UserID != 0:
+		return fmt.Sprintf("user:%d", p.UserID)
+	case p.KeyID != 0:
+		return fmt.Sprintf("api_key:%d", p.KeyID)
+	default:
+		return "system"
+	}
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
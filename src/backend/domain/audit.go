@@ -0,0 +1,267 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Integration events are named "<channel>.connected", "<channel>.updated" and
+// "<channel>.disconnected", e.g. "telegram.connected".
+const (
+	AuditOrderCreated   = "order.created"
+	AuditOrdersImported = "orders.imported"
+	AuditOrderResent    = "order.resent"
+
+	AuditWebhookCreated     = "webhook.created"
+	AuditWebhookUpdated     = "webhook.updated"
+	AuditWebhookDeleted     = "webhook.deleted"
+	AuditWebhookRedelivered = "webhook.redelivered"
+
+	AuditDestinationCreated = "destination.created"
+	AuditDestinationUpdated = "destination.updated"
+	AuditDestinationDeleted = "destination.deleted"
+
+	AuditRuleCreated = "rule.created"
+	AuditRuleUpdated = "rule.updated"
+	AuditRuleDeleted = "rule.deleted"
+
+	AuditDigestUpdated = "digest.updated"
+)
+
+const (
+	AuditEntityIntegration = "integration"
+	AuditEntityOrder       = "order"
+	AuditEntityWebhook     = "webhook"
+	AuditEntityDestination = "destination"
+	AuditEntityRule        = "notification_rule"
+	AuditEntityDigest      = "digest_schedule"
+)
+
+type AuditEvent struct {
+	ID         int64           `json:"id"`
+	ShopID     int64           `json:"shopId"`
+	Action     string          `json:"action"`
+	Actor      string          `json:"actor"`
+	EntityType string          `json:"entityType"`
+	EntityID   *int64          `json:"entityId,omitempty"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	RequestID  string          `json:"requestId,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+type requestIDKey struct{}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+type ServiceOption func(*Service)
+
+func WithAuditLog(audit AuditRepository) ServiceOption {
+	return func(s *Service) {
+		s.audit = audit
+	}
+}
+
+func (s *Service) ListAuditEvents(ctx context.Context, shopID int64, limit, offset int) (ListAuditEventsResult, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	if limit > 100 {
+		limit = 100
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	if s.audit == nil {
+		return ListAuditEventsResult{Items: []AuditEvent{}, Limit: limit, Offset: offset}, nil
+	}
+
+	rows, err := s.audit.List(ctx, shopID, limit+1, offset)
+
+	if err != nil {
+		return ListAuditEventsResult{}, err
+	}
+
+	hasMore := len(rows) > limit
+
+	if hasMore {
+		rows = rows[:limit]
+	}
+
+	return ListAuditEventsResult{
+		Items:   rows,
+		Limit:   limit,
+		Offset:  offset,
+		HasMore: hasMore,
+	}, nil
+}
+
+// recordAudit appends an event for a change that has already been applied.
+// before and after are stored as JSON and must not contain secrets. The change
+// is committed by then, so a failure is logged rather than returned: failing
+// the request would make the client retry, and repeat, a change that was made.
+func (s *Service) recordAudit(ctx context.Context, shopID int64, action, entityType string, entityID *int64, before, after any) {
+	if s.audit == nil {
+		return
+	}
+
+	if err := s.appendAudit(ctx, shopID, action, entityType, entityID, before, after); err != nil {
+		s.log(ctx).Error("failed to record audit event", "shopId", shopID, "action", action, "error", err)
+	}
+}
+
+func (s *Service) appendAudit(ctx context.Context, shopID int64, action, entityType string, entityID *int64, before, after any) error {
+	principal, _ := PrincipalFromContext(ctx)
+	event := AuditEvent{
+		ShopID:     shopID,
+		Action:     action,
+		Actor:      principal.Actor(),
+		EntityType: entityType,
+		EntityID:   entityID,
+		RequestID:  RequestIDFromContext(ctx),
+	}
+
+	var err error
+
+	if before != nil {
+		if event.Before, err = json.Marshal(before); err != nil {
+			return fmt.Errorf("encode audit event: %w", err)
+		}
+	}
+
+	if after != nil {
+		if event.After, err = json.Marshal(after); err != nil {
+			return fmt.Errorf("encode audit event: %w", err)
+		}
+	}
+
+	if err := s.audit.Append(ctx, event); err != nil {
+		return fmt.Errorf("append audit event: %w", err)
+	}
+
+	return nil
+}
+
+type auditIntegration struct {
+	Channel            ChannelType       `json:"channel"`
+	Settings           map[string]string `json:"settings"`
+	Enabled            bool              `json:"enabled"`
+	BatchWindowSeconds int               `json:"batchWindowSeconds,omitempty"`
+	SecretFingerprint  string            `json:"secretFingerprint"`
+}
+
+type auditWebhook struct {
+	URL               string   `json:"url"`
+	Events            []string `json:"events"`
+	Enabled           bool     `json:"enabled"`
+	SecretFingerprint string   `json:"secretFingerprint"`
+}
+
+type auditDestination struct {
+	Name    string      `json:"name"`
+	ChatID  string      `json:"chatId"`
+	Rule    RoutingRule `json:"rule"`
+	Enabled bool        `json:"enabled"`
+}
+
+type auditRule struct {
+	Position   int            `json:"position"`
+	Name       string         `json:"name"`
+	Conditions RuleConditions `json:"conditions"`
+	Action     RuleAction     `json:"action"`
+	Template   string         `json:"template,omitempty"`
+	Enabled    bool           `json:"enabled"`
+}
+
+type auditDigest struct {
+	Enabled      bool            `json:"enabled"`
+	Frequency    DigestFrequency `json:"frequency"`
+	Time         string          `json:"time"`
+	Weekday      int             `json:"weekday"`
+	Sections     []DigestSection `json:"sections"`
+	TopCustomers int             `json:"topCustomers"`
+}
+
+type auditOrder struct {
+	Number       string  `json:"number"`
+	Total        float64 `json:"total"`
+	CustomerName string  `json:"customerName"`
+	Status       string  `json:"status"`
+}
+
+func (s *Service) integrationSnapshot(integration Integration, found bool) any {
+	if !found {
+		return nil
+	}
+
+	masked := s.maskIntegration(integration)
+
+	return auditIntegration{
+		Channel:            masked.Channel,
+		Settings:           masked.Settings,
+		Enabled:            masked.Enabled,
+		BatchWindowSeconds: masked.BatchWindowSeconds,
+		SecretFingerprint:  masked.SecretFingerprint,
+	}
+}
+
+func (s *Service) webhookSnapshot(subscription WebhookSubscription) auditWebhook {
+	return auditWebhook{
+		URL:               MaskURL(subscription.URL),
+		Events:            subscription.Events,
+		Enabled:           subscription.Enabled,
+		SecretFingerprint: TokenFingerprint(subscription.Secret),
+	}
+}
+
+func destinationSnapshot(destination TelegramDestination) auditDestination {
+	return auditDestination{
+		Name:    destination.Name,
+		ChatID:  MaskChatID(destination.ChatID),
+		Rule:    destination.Rule,
+		Enabled: destination.Enabled,
+	}
+}
+
+func ruleSnapshot(rule NotificationRule) auditRule {
+	return auditRule{
+		Position:   rule.Position,
+		Name:       rule.Name,
+		Conditions: rule.Conditions,
+		Action:     rule.Action,
+		Template:   rule.Template,
+		Enabled:    rule.Enabled,
+	}
+}
+
+func digestSnapshot(schedule DigestSchedule) auditDigest {
+	return auditDigest{
+		Enabled:      schedule.Enabled,
+		Frequency:    schedule.Frequency,
+		Time:         schedule.Time,
+		Weekday:      schedule.Weekday,
+		Sections:     schedule.Sections,
+		TopCustomers: schedule.TopCustomers,
+	}
+}
+
+func orderSnapshot(order Order) auditOrder {
+	return auditOrder{
+		Number:       order.Number,
+		Total:        order.Total,
+		CustomerName: MaskCustomerName(order.CustomerName),
+		Status:       order.Status,
+	}
+}
@@ -0,0 +1,192 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const maxBatchWindowSeconds = 300
+
+type batchKey struct {
+	integrationID int64
+	recipient     string
+}
+
+// orderBatch is the claimed notifications of one integration and recipient,
+// sent together.
+type orderBatch struct {
+	notifier      BatchNotifier
+	integration   Integration
+	notifications []Notification
+}
+
+// WithBatchTimers makes the instance that opens a batch send it as soon as its
+// window ends rather than at the next DeliverDue poll. The timers stop with
+// ctx; a batch whose timer did not fire, e.g. after a restart, waits for the
+// poll.
+func WithBatchTimers(ctx context.Context) ServiceOption {
+	return func(s *Service) {
+		s.batchTimers = ctx
+	}
+}
+
+// batchDeadline returns when a notification of an integration that merges
+// orders is due: with the batch already waiting for the recipient, or at the
+// end of the window it opens. It returns nil when the integration sends each
+// order on its own. The rows wait in notification_log like deferred ones, so a
+// restart does not lose them.
+func (s *Service) batchDeadline(ctx context.Context, notifier Notifier, integration Integration, recipient string, now time.Time) (*time.Time, error) {
+	if _, ok := notifier.(BatchNotifier); !ok || integration.BatchWindowSeconds <= 0 {
+		return nil, nil
+	}
+
+	open, err := s.notificationLogs.OpenBatch(ctx, integration.ID, recipient, now)
+
+	if err != nil || open != nil {
+		return open, err
+	}
+
+	end := now.Add(time.Duration(integration.BatchWindowSeconds) * time.Second)
+	s.deliverDueAt(ctx, end)
+
+	return &end, nil
+}
+
+// deliverDueAt runs DeliverAllDue at the end of a batch window opened by this
+// instance. The rows that joined the batch on other instances are due by then
+// too, and claiming keeps a concurrent poll from sending them twice.
+func (s *Service) deliverDueAt(ctx context.Context, at time.Time) {
+	stop := s.batchTimers
+
+	if stop == nil {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+
+	time.AfterFunc(time.Until(at), func() {
+		if stop.Err() != nil {
+			return
+		}
+
+		if err := s.DeliverAllDue(ctx); err != nil {
+			s.log(ctx).Error("failed to deliver the batch at the end of its window", "error", err)
+		}
+	})
+}
+
+// deliverBatches sends the batches at once and waits for them.
+func (s *Service) deliverBatches(ctx context.Context, batches []*orderBatch) {
+	var wg sync.WaitGroup
+
+	for _, batch := range batches {
+		wg.Go(func() { s.deliverBatch(ctx, batch) })
+	}
+
+	wg.Wait()
+}
+
+// deliverBatch sends a batch like deliver sends one notification: failed
+// parts are retried, and every row ends up SENT with the ID of the message
+// that carried it, or FAILED. Cancelling ctx stops the retries; the rows left
+// are marked FAILED, so they can be resent.
+func (s *Service) deliverBatch(ctx context.Context, batch *orderBatch) {
+	s.metrics.AddPendingNotifications(len(batch.notifications))
+	defer s.metrics.AddPendingNotifications(-len(batch.notifications))
+
+	// the outcome is recorded even when ctx is cancelled
+	store := context.WithoutCancel(ctx)
+	integration := batch.integration
+	channel := string(integration.Channel)
+	logger := s.log(ctx).With("shopId", integration.ShopID, "channel", channel)
+	unsent := batch.notifications
+	attempts := 0
+	var sendErr error
+
+	for attempts < s.retryMaxAttempts {
+		attempts++
+
+		var messageIDs []string
+		messageIDs, sendErr = batch.notifier.SendBatch(ctx, integration, unsent)
+		unsent = s.finalizeBatch(store, logger, channel, unsent, messageIDs, attempts)
+
+		if sendErr == nil || len(unsent) == 0 {
+			return
+		}
+
+		logger.Warn("batch send attempt failed", "attempt", attempts, "maxAttempts", s.retryMaxAttempts, "unsent", len(unsent),
+			"error", RedactSecrets(sendErr.Error(), integration.Secret))
+
+		if attempts == s.retryMaxAttempts {
+			break
+		}
+
+		if err := wait(ctx, s.retryDelay(sendErr, attempts)); err != nil {
+			sendErr = fmt.Errorf("%w; retries stopped: %w", sendErr, err)
+			break
+		}
+	}
+
+	errText := RedactSecrets(sendErr.Error(), integration.Secret)
+	logger.Error("batch delivery failed", "attempts", attempts, "orders", len(unsent), "error", errText)
+
+	for _, notification := range unsent {
+		s.metrics.ObserveDelivery(channel, DeliveryOutcomeFailed, attempts)
+		s.finalize(store, logger.With("orderId", notification.OrderID), notification.ID, NotificationStatusFailed, &errText)
+	}
+}
+
+// finalizeBatch marks the notifications that got a message ID as sent, links
+// their rows to the message and returns the ones left to send.
+func (s *Service) finalizeBatch(ctx context.Context, logger *slog.Logger, channel string, notifications []Notification, messageIDs []string, attempt int) []Notification {
+	var unsent []Notification
+	var messages []string
+	byMessage := map[string][]int64{}
+
+	for i, notification := range notifications {
+		if i >= len(messageIDs) || messageIDs[i] == "" {
+			unsent = append(unsent, notification)
+			continue
+		}
+
+		if _, ok := byMessage[messageIDs[i]]; !ok {
+			messages = append(messages, messageIDs[i])
+		}
+
+		byMessage[messageIDs[i]] = append(byMessage[messageIDs[i]], notification.ID)
+	}
+
+	for _, messageID := range messages {
+		logIDs := byMessage[messageID]
+
+		for _, logID := range logIDs {
+			s.metrics.ObserveDelivery(channel, DeliveryOutcomeSent, attempt)
+			s.finalize(ctx, logger, logID, NotificationStatusSent, nil)
+		}
+
+		if err := s.notificationLogs.LinkMessage(ctx, messageID, logIDs); err != nil {
+			logger.Error("failed to link notifications to the message", "messageId", messageID, "error", err)
+		}
+
+		logger.Info("batch sent", "messageId", messageID, "orders", len(logIDs), "attempt", attempt)
+	}
+
+	return unsent
+}
+
+// normalizeBatchWindow checks the batching window of an integration whose
+// channel is served by notifier.
+func normalizeBatchWindow(notifier Notifier, channel ChannelType, seconds int) error {
+	if seconds < 0 || seconds > maxBatchWindowSeconds {
+		return InvalidField("batchWindowSeconds", "max", fmt.Sprintf("batchWindowSeconds must be from 0 to %d", maxBatchWindowSeconds))
+	}
+
+	if _, ok := notifier.(BatchNotifier); seconds > 0 && !ok {
+		return InvalidField("batchWindowSeconds", "unsupported", fmt.Sprintf("%s does not support batching", channel))
+	}
+
+	return nil
+}
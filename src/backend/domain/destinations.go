@@ -0,0 +1,364 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrDestinationNotFound  = NewError(KindNotFound, "destination_not_found", "telegram destination not found")
+	ErrDestinationExists    = NewError(KindConflict, "destination_exists", "the integration already sends to this chat")
+	ErrDestinationsDisabled = NewError(KindNotFound, "destinations_disabled", "telegram destinations are not configured")
+)
+
+const maxDestinationNameLength = 100
+
+// TelegramDestination is an extra chat of the shop's Telegram integration,
+// e.g. the owner's DM next to the managers' group. The integration's own chat
+// gets every order; a destination only the orders its rule matches. Messages
+// go out through the integration's bot.
+type TelegramDestination struct {
+	ID            int64       `json:"id"`
+	ShopID        int64       `json:"shopId"`
+	IntegrationID int64       `json:"integrationId"`
+	Name          string      `json:"name"`
+	ChatID        string      `json:"chatId"`
+	Rule          RoutingRule `json:"rule"`
+	Enabled       bool        `json:"enabled"`
+	CreatedAt     time.Time   `json:"createdAt"`
+	UpdatedAt     time.Time   `json:"updatedAt"`
+}
+
+// RoutingRule selects the orders a destination receives; an empty rule matches
+// every order. Statuses matches an order whose status is any of them.
+// ActiveFrom and ActiveTo are "HH:MM" in the shop's time zone and compared
+// with the order's creation time; a window may wrap midnight, e.g.
+// 22:00-08:00.
+type RoutingRule struct {
+	MinTotal   *float64 `json:"minTotal,omitempty"`
+	Statuses   []string `json:"statuses,omitempty"`
+	ActiveFrom string   `json:"activeFrom,omitempty"`
+	ActiveTo   string   `json:"activeTo,omitempty"`
+}
+
+// Matches reports whether order should be sent to the destination; loc is the
+// shop's time zone. The rule must have been normalized.
+func (r RoutingRule) Matches(order Order, loc *time.Location) bool {
+	if r.MinTotal != nil && order.Total < *r.MinTotal {
+		return false
+	}
+
+	if len(r.Statuses) > 0 && !slices.Contains(r.Statuses, order.Status) {
+		return false
+	}
+
+	if r.ActiveFrom == "" {
+		return true
+	}
+
+	return inClockWindow(order.CreatedAt.In(loc).Format("15:04"), r.ActiveFrom, r.ActiveTo)
+}
+
+// normalize validates the rule, lowercases the statuses and writes the window
+// as zero-padded HH:MM.
+func (r RoutingRule) normalize() (RoutingRule, []FieldError) {
+	var fields []FieldError
+
+	if r.MinTotal != nil && *r.MinTotal < 0 {
+		fields = append(fields, FieldError{Field: "rule.minTotal", Code: "min", Message: "minTotal must not be negative"})
+	}
+
+	statuses, statusFields := normalizeStatuses("rule.statuses", r.Statuses)
+	r.Statuses = statuses
+	fields = append(fields, statusFields...)
+
+	for _, bound := range []struct {
+		name  string
+		value *string
+	}{{"activeFrom", &r.ActiveFrom}, {"activeTo", &r.ActiveTo}} {
+		*bound.value = strings.TrimSpace(*bound.value)
+
+		if *bound.value == "" {
+			continue
+		}
+
+		clock, err := parseClock(*bound.value)
+
+		if err != nil {
+			fields = append(fields, FieldError{Field: "rule." + bound.name, Code: "time", Message: bound.name + " must be HH:MM"})
+			continue
+		}
+
+		*bound.value = clock
+	}
+
+	switch {
+	case (r.ActiveFrom == "") != (r.ActiveTo == ""):
+		fields = append(fields, FieldError{Field: "rule.activeTo", Code: "required_with", Message: "activeFrom and activeTo must be set together"})
+	case r.ActiveFrom != "" && r.ActiveFrom == r.ActiveTo:
+		fields = append(fields, FieldError{Field: "rule.activeTo", Code: "ne", Message: "activeTo must differ from activeFrom"})
+	}
+
+	return r, fields
+}
+
+// WithTelegramDestinations lets a shop's Telegram integration send to more than
+// one chat.
+func WithTelegramDestinations(destinations TelegramDestinationRepository) ServiceOption {
+	return func(s *Service) {
+		s.destinations = destinations
+	}
+}
+
+func (s *Service) ListTelegramDestinations(ctx context.Context, shopID int64) (ListTelegramDestinationsResult, error) {
+	if s.destinations == nil {
+		return ListTelegramDestinationsResult{}, ErrDestinationsDisabled
+	}
+
+	destinations, err := s.destinations.ListByShop(ctx, shopID)
+
+	if err != nil {
+		return ListTelegramDestinationsResult{}, err
+	}
+
+	items := make([]TelegramDestination, 0, len(destinations))
+
+	for _, destination := range destinations {
+		items = append(items, maskDestination(destination))
+	}
+
+	return ListTelegramDestinationsResult{Items: items}, nil
+}
+
+func (s *Service) CreateTelegramDestination(ctx context.Context, shopID int64, input TelegramDestinationInput) (TelegramDestination, error) {
+	if s.destinations == nil {
+		return TelegramDestination{}, ErrDestinationsDisabled
+	}
+
+	integration, found, err := s.integrations.Get(ctx, shopID, ChannelTelegram)
+
+	if err != nil {
+		return TelegramDestination{}, err
+	}
+
+	if !found {
+		return TelegramDestination{}, ErrShopNotIntegrated
+	}
+
+	name, chatID, rule, err := normalizeDestination(input.Name, input.ChatID, input.Rule)
+
+	if err != nil {
+		return TelegramDestination{}, err
+	}
+
+	if chatID == integration.Settings[TelegramSettingChatID] {
+		return TelegramDestination{}, ErrDestinationExists
+	}
+
+	enabled := input.Enabled == nil || *input.Enabled
+	input.Name, input.ChatID, input.Rule, input.Enabled = name, chatID, rule, &enabled
+	destination, err := s.destinations.Create(ctx, shopID, integration.ID, input)
+
+	if err != nil {
+		return TelegramDestination{}, err
+	}
+
+	s.recordAudit(ctx, shopID, AuditDestinationCreated, AuditEntityDestination, &destination.ID, nil, destinationSnapshot(destination))
+
+	return maskDestination(destination), nil
+}
+
+func (s *Service) UpdateTelegramDestination(ctx context.Context, shopID, destinationID int64, input UpdateTelegramDestinationInput) (TelegramDestination, error) {
+	if s.destinations == nil {
+		return TelegramDestination{}, ErrDestinationsDisabled
+	}
+
+	if input.Name == nil && input.ChatID == nil && input.Rule == nil && input.Enabled == nil {
+		return TelegramDestination{}, fmt.Errorf("%w: nothing to update", ErrInvalidInput)
+	}
+
+	before, err := s.destinations.Get(ctx, shopID, destinationID)
+
+	if err != nil {
+		return TelegramDestination{}, err
+	}
+
+	name, chatID, rule := before.Name, before.ChatID, before.Rule
+
+	if input.Name != nil {
+		name = *input.Name
+	}
+
+	if input.ChatID != nil {
+		chatID = *input.ChatID
+	}
+
+	if input.Rule != nil {
+		rule = *input.Rule
+	}
+
+	name, chatID, rule, err = normalizeDestination(name, chatID, rule)
+
+	if err != nil {
+		return TelegramDestination{}, err
+	}
+
+	if input.ChatID != nil {
+		integration, found, err := s.integrations.Get(ctx, shopID, ChannelTelegram)
+
+		if err != nil {
+			return TelegramDestination{}, err
+		}
+
+		if found && integration.ID == before.IntegrationID && chatID == integration.Settings[TelegramSettingChatID] {
+			return TelegramDestination{}, ErrDestinationExists
+		}
+	}
+
+	input.Name, input.ChatID, input.Rule = &name, &chatID, &rule
+	destination, err := s.destinations.Update(ctx, shopID, destinationID, input)
+
+	if err != nil {
+		return TelegramDestination{}, err
+	}
+
+	s.recordAudit(ctx, shopID, AuditDestinationUpdated, AuditEntityDestination, &destination.ID,
+		destinationSnapshot(before), destinationSnapshot(destination))
+
+	return maskDestination(destination), nil
+}
+
+func (s *Service) DeleteTelegramDestination(ctx context.Context, shopID, destinationID int64) error {
+	if s.destinations == nil {
+		return ErrDestinationsDisabled
+	}
+
+	before, err := s.destinations.Get(ctx, shopID, destinationID)
+
+	if err != nil {
+		return err
+	}
+
+	if err := s.destinations.Delete(ctx, shopID, destinationID); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, shopID, AuditDestinationDeleted, AuditEntityDestination, &before.ID, destinationSnapshot(before), nil)
+
+	return nil
+}
+
+// recipients returns the recipients to send order to separately: the
+// notifier's own for a RecipientNotifier, the integration's chat and the
+// matching destinations for Telegram, and a single empty one otherwise, which
+// means the integration as a whole. loc is the shop's time zone.
+func (s *Service) recipients(ctx context.Context, notifier Notifier, integration Integration, order Order, loc *time.Location) ([]string, error) {
+	if r, ok := notifier.(RecipientNotifier); ok {
+		return r.Recipients(integration), nil
+	}
+
+	out := []string{""}
+
+	if integration.Channel != ChannelTelegram || s.destinations == nil {
+		return out, nil
+	}
+
+	destinations, err := s.destinations.ListByShop(ctx, integration.ShopID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, destination := range destinations {
+		if destination.IntegrationID != integration.ID || !destination.Enabled || !destination.Rule.Matches(order, loc) {
+			continue
+		}
+
+		// the integration's own chat already gets the order
+		if destination.ChatID == integration.Settings[TelegramSettingChatID] {
+			continue
+		}
+
+		out = append(out, destination.ChatID)
+	}
+
+	return out, nil
+}
+
+// destinationStatuses reports the 7-day stats of the integration's own chat
+// and of every destination. Rows are matched by chat ID, so a destination
+// whose chat was changed starts from zero. Destinations of an integration are
+// unique per chat, and Create and Update reject the integration's own chat, so
+// each row is counted once.
+func (s *Service) destinationStatuses(ctx context.Context, integration Integration, since time.Time) ([]DestinationStatus, error) {
+	destinations, err := s.destinations.ListByShop(ctx, integration.ShopID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.notificationLogs.GetRecipientStats(ctx, integration.ID, since)
+
+	if err != nil {
+		return nil, err
+	}
+
+	out := []DestinationStatus{newDestinationStatus(nil, "", integration.Settings[TelegramSettingChatID], integration.Enabled, stats[""])}
+
+	for _, destination := range destinations {
+		if destination.IntegrationID != integration.ID {
+			continue
+		}
+
+		out = append(out, newDestinationStatus(&destination.ID, destination.Name, destination.ChatID, destination.Enabled, stats[destination.ChatID]))
+	}
+
+	return out, nil
+}
+
+func newDestinationStatus(id *int64, name, chatID string, enabled bool, stats RecipientStats) DestinationStatus {
+	return DestinationStatus{
+		ID:          id,
+		Name:        name,
+		ChatID:      MaskChatID(chatID),
+		Enabled:     enabled,
+		LastSentAt:  stats.LastSentAt,
+		SentCount:   stats.SentCount,
+		FailedCount: stats.FailedCount,
+	}
+}
+
+func normalizeDestination(name, chatID string, rule RoutingRule) (string, string, RoutingRule, error) {
+	var fields []FieldError
+
+	name = strings.TrimSpace(name)
+	chatID = strings.TrimSpace(chatID)
+
+	if name == "" {
+		fields = append(fields, FieldError{Field: "name", Code: "required", Message: "name must be non-empty"})
+	} else if utf8.RuneCountInString(name) > maxDestinationNameLength {
+		fields = append(fields, FieldError{Field: "name", Code: "max", Message: fmt.Sprintf("name must be at most %d characters", maxDestinationNameLength)})
+	}
+
+	if chatID == "" {
+		fields = append(fields, FieldError{Field: "chatId", Code: "required", Message: "chatId must be non-empty"})
+	}
+
+	rule, ruleFields := rule.normalize()
+	fields = append(fields, ruleFields...)
+
+	if len(fields) > 0 {
+		return "", "", RoutingRule{}, ValidationFailed(fields)
+	}
+
+	return name, chatID, rule, nil
+}
+
+func maskDestination(destination TelegramDestination) TelegramDestination {
+	destination.ChatID = MaskChatID(destination.ChatID)
+	return destination
+}
@@ -0,0 +1,465 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrDigestsDisabled = NewError(KindNotFound, "digests_disabled", "digests are not configured")
+	errNoDigestChat    = errors.New("telegram integration is not connected or disabled")
+)
+
+const (
+	defaultDigestTime         = "09:00"
+	defaultDigestTopCustomers = 3
+	maxDigestTopCustomers     = 10
+)
+
+type DigestFrequency string
+
+const (
+	DigestDaily  DigestFrequency = "daily"
+	DigestWeekly DigestFrequency = "weekly"
+)
+
+var digestFrequencies = []DigestFrequency{DigestDaily, DigestWeekly}
+
+type DigestSection string
+
+const (
+	DigestSectionOrders              DigestSection = "orders"
+	DigestSectionRevenue             DigestSection = "revenue"
+	DigestSectionAverageCheck        DigestSection = "averageCheck"
+	DigestSectionTopCustomers        DigestSection = "topCustomers"
+	DigestSectionFailedNotifications DigestSection = "failedNotifications"
+)
+
+// digestSections is also the order in which a digest lists its sections.
+var digestSections = []DigestSection{
+	DigestSectionOrders,
+	DigestSectionRevenue,
+	DigestSectionAverageCheck,
+	DigestSectionTopCustomers,
+	DigestSectionFailedNotifications,
+}
+
+// DigestSchedule is a shop's summary sent to its Telegram chat every day, or
+// every week on Weekday, at Time in the shop's time zone. The digest covers
+// the day or week before that moment.
+type DigestSchedule struct {
+	ShopID    int64           `json:"shopId"`
+	Enabled   bool            `json:"enabled"`
+	Frequency DigestFrequency `json:"frequency"`
+	// Time is "HH:MM" in the shop's time zone.
+	Time string `json:"time"`
+	// Weekday of a weekly digest, from 1 (Monday) to 7 (Sunday).
+	Weekday      int             `json:"weekday"`
+	Sections     []DigestSection `json:"sections"`
+	TopCustomers int             `json:"topCustomers"`
+	// LastPeriodEnd is the end of the last digest sent or attempted; LastError
+	// tells why that attempt failed.
+	LastPeriodEnd *time.Time `json:"lastPeriodEnd"`
+	LastSentAt    *time.Time `json:"lastSentAt"`
+	LastError     *string    `json:"lastError"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// Digest sums up a shop's orders in [From, To). FailedNotifications counts
+// channel notifications that finally failed in the period; webhooks are not
+// counted.
+type Digest struct {
+	ShopID              int64           `json:"shopId"`
+	From                time.Time       `json:"from"`
+	To                  time.Time       `json:"to"`
+	OrderCount          int64           `json:"orderCount"`
+	Revenue             float64         `json:"revenue"`
+	AverageCheck        float64         `json:"averageCheck"`
+	TopCustomers        []CustomerTotal `json:"topCustomers"`
+	FailedNotifications int64           `json:"failedNotifications"`
+}
+
+type CustomerTotal struct {
+	Name   string  `json:"name"`
+	Orders int64   `json:"orders"`
+	Total  float64 `json:"total"`
+}
+
+// LatestRun returns the latest scheduled time at or before now.
+func (d DigestSchedule) LatestRun(now time.Time, loc *time.Location) time.Time {
+	local := now.In(loc)
+	// normalized, so it parses
+	clock, _ := time.Parse("15:04", d.Time)
+	run := time.Date(local.Year(), local.Month(), local.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+	step := 1
+
+	if d.Frequency == DigestWeekly {
+		step = 7
+		run = run.AddDate(0, 0, -((isoWeekday(local) - d.Weekday + 7) % 7))
+	}
+
+	if run.After(local) {
+		run = run.AddDate(0, 0, -step)
+	}
+
+	return run
+}
+
+// PeriodStart returns the start of the period of a digest sent at end.
+func (d DigestSchedule) PeriodStart(end time.Time) time.Time {
+	if d.Frequency == DigestWeekly {
+		return end.AddDate(0, 0, -7)
+	}
+
+	return end.AddDate(0, 0, -1)
+}
+
+// due returns the period end of the digest to send at now. A schedule only
+// sends runs after it was last saved and after its last digest, and after
+// downtime only the latest missed run is sent.
+func (d DigestSchedule) due(now time.Time, loc *time.Location) (time.Time, bool) {
+	if !d.Enabled {
+		return time.Time{}, false
+	}
+
+	run := d.LatestRun(now, loc)
+	since := d.UpdatedAt
+
+	if d.LastPeriodEnd != nil && d.LastPeriodEnd.After(since) {
+		since = *d.LastPeriodEnd
+	}
+
+	return run, run.After(since)
+}
+
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+
+	return int(t.Weekday())
+}
+
+func defaultDigestSchedule(shopID int64) DigestSchedule {
+	return DigestSchedule{
+		ShopID:       shopID,
+		Frequency:    DigestDaily,
+		Time:         defaultDigestTime,
+		Weekday:      1,
+		Sections:     slices.Clone(digestSections),
+		TopCustomers: defaultDigestTopCustomers,
+	}
+}
+
+// WithDigests lets shops schedule daily or weekly summaries to their Telegram
+// chat; SendDueDigests sends them.
+func WithDigests(digests DigestRepository) ServiceOption {
+	return func(s *Service) {
+		s.digests = digests
+	}
+}
+
+// GetDigestSchedule returns the shop's schedule, or the default disabled one
+// if the shop never saved it.
+func (s *Service) GetDigestSchedule(ctx context.Context, shopID int64) (DigestSchedule, error) {
+	if s.digests == nil {
+		return DigestSchedule{}, ErrDigestsDisabled
+	}
+
+	schedule, found, err := s.digests.Get(ctx, shopID)
+
+	if err != nil {
+		return DigestSchedule{}, err
+	}
+
+	if !found {
+		return defaultDigestSchedule(shopID), nil
+	}
+
+	return schedule, nil
+}
+
+func (s *Service) UpdateDigestSchedule(ctx context.Context, shopID int64, input DigestScheduleInput) (DigestSchedule, error) {
+	if s.digests == nil {
+		return DigestSchedule{}, ErrDigestsDisabled
+	}
+
+	input, err := normalizeDigestSchedule(input)
+
+	if err != nil {
+		return DigestSchedule{}, err
+	}
+
+	before, found, err := s.digests.Get(ctx, shopID)
+
+	if err != nil {
+		return DigestSchedule{}, err
+	}
+
+	saved, err := s.digests.Upsert(ctx, shopID, input)
+
+	if err != nil {
+		return DigestSchedule{}, err
+	}
+
+	var beforeSnapshot any
+
+	if found {
+		beforeSnapshot = digestSnapshot(before)
+	}
+
+	s.recordAudit(ctx, shopID, AuditDigestUpdated, AuditEntityDigest, nil, beforeSnapshot, digestSnapshot(saved))
+
+	return saved, nil
+}
+
+// PreviewDigest builds the digest the shop's schedule would send if it ran
+// now, with its rendered text.
+func (s *Service) PreviewDigest(ctx context.Context, shopID int64) (DigestPreview, error) {
+	if s.digests == nil {
+		return DigestPreview{}, ErrDigestsDisabled
+	}
+
+	schedule, err := s.GetDigestSchedule(ctx, shopID)
+
+	if err != nil {
+		return DigestPreview{}, err
+	}
+
+	now := time.Now()
+	digest, err := s.digests.Summary(ctx, shopID, schedule.PeriodStart(now), now, schedule.TopCustomers)
+
+	if err != nil {
+		return DigestPreview{}, err
+	}
+
+	loc, err := s.shopLocation(ctx, shopID)
+
+	if err != nil {
+		return DigestPreview{}, err
+	}
+
+	return DigestPreview{Digest: digest, Text: renderDigest(schedule, digest, loc)}, nil
+}
+
+// SendDueDigests sends the digests whose run is due at now and returns how
+// many were sent. It must not run on two instances at once, so it runs as a
+// Scheduler job. A failed digest is recorded and not retried until the next
+// run; the other shops still get theirs. Cancelling ctx stops the run after
+// the current digest is recorded.
+func (s *Service) SendDueDigests(ctx context.Context, now time.Time) (int, error) {
+	if s.digests == nil {
+		return 0, nil
+	}
+
+	schedules, err := s.digests.ListEnabled(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+
+	for _, schedule := range schedules {
+		logger := s.log(ctx).With("shopId", schedule.ShopID)
+		loc, err := s.shopLocation(ctx, schedule.ShopID)
+
+		if err != nil {
+			logger.Error("failed to load shop for digest", "error", err)
+			continue
+		}
+
+		end, ok := schedule.due(now, loc)
+
+		if !ok {
+			continue
+		}
+
+		sendErr := s.sendDigest(ctx, schedule, schedule.PeriodStart(end), end, loc)
+		var errText *string
+
+		if sendErr != nil {
+			text := sendErr.Error()
+			errText = &text
+			logger.Error("digest delivery failed", "periodEnd", end, "error", text)
+		} else {
+			sent++
+			logger.Info("digest sent", "periodEnd", end)
+		}
+
+		// a lost mark leaves the period due, so the next run sends it again: a
+		// repeat if this digest went out, another attempt if it failed
+		if err := s.digests.MarkSent(context.WithoutCancel(ctx), schedule.ShopID, end, time.Now(), errText); err != nil {
+			logger.Error("failed to record digest delivery", "periodEnd", end, "error", err)
+		}
+
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+	}
+
+	return sent, nil
+}
+
+func (s *Service) sendDigest(ctx context.Context, schedule DigestSchedule, from, to time.Time, loc *time.Location) error {
+	integration, found, err := s.integrations.Get(ctx, schedule.ShopID, ChannelTelegram)
+
+	if err != nil {
+		return err
+	}
+
+	if !found || !integration.Enabled {
+		return errNoDigestChat
+	}
+
+	notifier, err := s.notifiers.Get(ChannelTelegram)
+
+	if err != nil {
+		return err
+	}
+
+	digest, err := s.digests.Summary(ctx, schedule.ShopID, from, to, schedule.TopCustomers)
+
+	if err != nil {
+		return err
+	}
+
+	notification := Notification{ShopID: schedule.ShopID, Text: renderDigest(schedule, digest, loc)}
+	var sendErr error
+
+	for attempt := 1; attempt <= s.retryMaxAttempts; attempt++ {
+		if sendErr = notifier.Send(ctx, integration, notification); sendErr == nil {
+			return nil
+		}
+
+		if attempt == s.retryMaxAttempts {
+			break
+		}
+
+		if err := wait(ctx, s.retryDelay(sendErr, attempt)); err != nil {
+			sendErr = fmt.Errorf("%w; retries stopped: %w", sendErr, err)
+			break
+		}
+	}
+
+	return errors.New(RedactSecrets(sendErr.Error(), integration.Secret))
+}
+
+// shopLocation returns the shop's time zone, or UTC without WithShops.
+func (s *Service) shopLocation(ctx context.Context, shopID int64) (*time.Location, error) {
+	if s.shops == nil {
+		return time.UTC, nil
+	}
+
+	shop, err := s.shops.GetByID(ctx, shopID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return shopLocation(shop), nil
+}
+
+func renderDigest(schedule DigestSchedule, digest Digest, loc *time.Location) string {
+	var b strings.Builder
+	from, to := digest.From.In(loc), digest.To.In(loc)
+
+	if schedule.Frequency == DigestWeekly {
+		fmt.Fprintf(&b, "📊 Сводка за неделю %s – %s", from.Format("02.01.2006 15:04"), to.Format("02.01.2006 15:04"))
+	} else {
+		fmt.Fprintf(&b, "📊 Сводка за сутки %s – %s", from.Format("02.01.2006 15:04"), to.Format("02.01.2006 15:04"))
+	}
+
+	for _, section := range digestSections {
+		if !slices.Contains(schedule.Sections, section) {
+			continue
+		}
+
+		switch section {
+		case DigestSectionOrders:
+			fmt.Fprintf(&b, "\nЗаказов: %d", digest.OrderCount)
+		case DigestSectionRevenue:
+			fmt.Fprintf(&b, "\nВыручка: %.2f ₽", digest.Revenue)
+		case DigestSectionAverageCheck:
+			fmt.Fprintf(&b, "\nСредний чек: %.2f ₽", digest.AverageCheck)
+		case DigestSectionTopCustomers:
+			if len(digest.TopCustomers) == 0 {
+				continue
+			}
+
+			b.WriteString("\nЛучшие клиенты:")
+
+			for i, customer := range digest.TopCustomers {
+				fmt.Fprintf(&b, "\n%d. %s — %.2f ₽ (заказов: %d)", i+1, customer.Name, customer.Total, customer.Orders)
+			}
+		case DigestSectionFailedNotifications:
+			fmt.Fprintf(&b, "\nНеотправленных уведомлений: %d", digest.FailedNotifications)
+		}
+	}
+
+	return b.String()
+}
+
+func normalizeDigestSchedule(input DigestScheduleInput) (DigestScheduleInput, error) {
+	var fields []FieldError
+
+	if !slices.Contains(digestFrequencies, input.Frequency) {
+		fields = append(fields, FieldError{Field: "frequency", Code: "oneof", Message: "frequency must be daily or weekly"})
+	}
+
+	if input.Time == "" {
+		input.Time = defaultDigestTime
+	}
+
+	if clock, err := parseClock(input.Time); err != nil {
+		fields = append(fields, FieldError{Field: "time", Code: "time", Message: "time must be HH:MM"})
+	} else {
+		input.Time = clock
+	}
+
+	if input.Weekday == 0 {
+		input.Weekday = 1
+	}
+
+	if input.Weekday < 1 || input.Weekday > 7 {
+		fields = append(fields, FieldError{Field: "weekday", Code: "oneof", Message: "weekday must be from 1 (Monday) to 7 (Sunday)"})
+	}
+
+	if len(input.Sections) == 0 {
+		input.Sections = slices.Clone(digestSections)
+	}
+
+	sections := make([]DigestSection, 0, len(digestSections))
+
+	for _, section := range digestSections {
+		if slices.Contains(input.Sections, section) {
+			sections = append(sections, section)
+		}
+	}
+
+	for _, section := range input.Sections {
+		if !slices.Contains(digestSections, section) {
+			fields = append(fields, FieldError{Field: "sections", Code: "oneof", Message: fmt.Sprintf("unknown digest section %q", section)})
+		}
+	}
+
+	input.Sections = sections
+
+	if input.TopCustomers == 0 {
+		input.TopCustomers = defaultDigestTopCustomers
+	}
+
+	if input.TopCustomers < 1 || input.TopCustomers > maxDigestTopCustomers {
+		fields = append(fields, FieldError{Field: "topCustomers", Code: "max", Message: fmt.Sprintf("topCustomers must be from 1 to %d", maxDigestTopCustomers)})
+	}
+
+	if len(fields) > 0 {
+		return DigestScheduleInput{}, ValidationFailed(fields)
+	}
+
+	return input, nil
+}
@@ -9,16 +9,258 @@ const (
 	SendStatusPending = "pending"
 )
 
+const (
+	ImportRowStatusImported = "imported"
+	ImportRowStatusInvalid  = "invalid"
+	ImportRowStatusFailed   = "failed"
+)
+
+type ShopInput struct {
+	Name     string `json:"name" binding:"required"`
+	Timezone string `json:"timezone"`
+	Locale   string `json:"locale" binding:"omitempty,bcp47_language_tag"`
+	Currency string `json:"currency" binding:"omitempty,len=3,alpha"`
+	// QuietHours replaces the stored window; null removes it.
+	QuietHours *QuietHours `json:"quietHours"`
+}
+
+type ListShopsResult struct {
+	Items   []Shop `json:"items"`
+	Limit   int    `json:"limit"`
+	Offset  int    `json:"offset"`
+	HasMore bool   `json:"hasMore"`
+}
+
+type CreateAPIKeyInput struct {
+	Name   string  `json:"name" binding:"required"`
+	Scopes []Scope `json:"scopes" binding:"required,min=1"`
+}
+
+type NewAPIKey struct {
+	ShopID int64
+	Name   string
+	Prefix string
+	Hash   []byte
+	Scopes []Scope
+}
+
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type ListAPIKeysResult struct {
+	Items []APIKey `json:"items"`
+}
+
+type CreateUserInput struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type LoginInput struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type Me struct {
+	User        User         `json:"user"`
+	Memberships []ShopMember `json:"memberships"`
+	CSRFToken   string       `json:"csrfToken"`
+}
+
+type LoginResult struct {
+	Me
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type SetMemberInput struct {
+	Email string `json:"email" binding:"required"`
+	Role  Role   `json:"role" binding:"required"`
+}
+
+type ListMembersResult struct {
+	Items []ShopMember `json:"items"`
+}
+
 type ConnectTelegramInput struct {
 	BotToken string `json:"botToken" binding:"required"`
 	ChatID   string `json:"chatId" binding:"required"`
 	Enabled  bool   `json:"enabled"`
 }
 
+type UpdateTelegramInput struct {
+	ChatID  *string `json:"chatId"`
+	Enabled *bool   `json:"enabled"`
+}
+
+type ConnectIntegrationInput struct {
+	Settings           map[string]string `json:"settings"`
+	Secret             string            `json:"secret"`
+	Enabled            bool              `json:"enabled"`
+	BatchWindowSeconds int               `json:"batchWindowSeconds"`
+}
+
+// UpdateIntegrationInput changes only what is set; Settings keys are merged
+// into the stored settings.
+type UpdateIntegrationInput struct {
+	Settings           map[string]string `json:"settings"`
+	Secret             *string           `json:"secret"`
+	Enabled            *bool             `json:"enabled"`
+	BatchWindowSeconds *int              `json:"batchWindowSeconds"`
+}
+
+type ListIntegrationsResult struct {
+	Items []Integration `json:"items"`
+}
+
+type ListChannelsResult struct {
+	Items []ChannelType `json:"items"`
+}
+
+type IntegrationStatus struct {
+	Channel     ChannelType       `json:"channel"`
+	Enabled     bool              `json:"enabled"`
+	Settings    map[string]string `json:"settings"`
+	LastSentAt  *time.Time        `json:"lastSentAt"`
+	SentCount   int64             `json:"sentCount7d"`
+	FailedCount int64             `json:"failedCount7d"`
+	// Destinations is only set for Telegram: the integration's own chat
+	// first, then every destination.
+	Destinations []DestinationStatus `json:"destinations,omitempty"`
+}
+
+// DestinationStatus is the 7-day stats of one Telegram chat; ID is null for
+// the integration's own chat.
+type DestinationStatus struct {
+	ID          *int64     `json:"id"`
+	Name        string     `json:"name"`
+	ChatID      string     `json:"chatId"`
+	Enabled     bool       `json:"enabled"`
+	LastSentAt  *time.Time `json:"lastSentAt"`
+	SentCount   int64      `json:"sentCount7d"`
+	FailedCount int64      `json:"failedCount7d"`
+}
+
+type TelegramDestinationInput struct {
+	Name   string      `json:"name" binding:"required"`
+	ChatID string      `json:"chatId" binding:"required"`
+	Rule   RoutingRule `json:"rule"`
+	// Enabled defaults to true.
+	Enabled *bool `json:"enabled"`
+}
+
+type UpdateTelegramDestinationInput struct {
+	Name    *string      `json:"name"`
+	ChatID  *string      `json:"chatId"`
+	Rule    *RoutingRule `json:"rule"`
+	Enabled *bool        `json:"enabled"`
+}
+
+type ListTelegramDestinationsResult struct {
+	Items []TelegramDestination `json:"items"`
+}
+
+type NotificationRuleInput struct {
+	Position   int            `json:"position"`
+	Name       string         `json:"name" binding:"required"`
+	Conditions RuleConditions `json:"conditions"`
+	Action     RuleAction     `json:"action" binding:"required"`
+	Template   string         `json:"template"`
+	Enabled    bool           `json:"enabled"`
+}
+
+type UpdateNotificationRuleInput struct {
+	Position   *int            `json:"position"`
+	Name       *string         `json:"name"`
+	Conditions *RuleConditions `json:"conditions"`
+	Action     *RuleAction     `json:"action"`
+	Template   *string         `json:"template"`
+	Enabled    *bool           `json:"enabled"`
+}
+
+type ListNotificationRulesResult struct {
+	Items []NotificationRule `json:"items"`
+}
+
+// DigestScheduleInput replaces the shop's digest schedule. Time defaults to
+// 09:00, Weekday to Monday, Sections to all and TopCustomers to 3.
+type DigestScheduleInput struct {
+	Enabled      bool            `json:"enabled"`
+	Frequency    DigestFrequency `json:"frequency" binding:"required"`
+	Time         string          `json:"time"`
+	Weekday      int             `json:"weekday"`
+	Sections     []DigestSection `json:"sections"`
+	TopCustomers int             `json:"topCustomers"`
+}
+
+type DigestPreview struct {
+	Digest
+	Text string `json:"text"`
+}
+
+type WebhookInput struct {
+	URL     string   `json:"url" binding:"required"`
+	Secret  string   `json:"secret" binding:"required"`
+	Events  []string `json:"events" binding:"required"`
+	Enabled bool     `json:"enabled"`
+}
+
+type UpdateWebhookInput struct {
+	URL     *string  `json:"url"`
+	Secret  *string  `json:"secret"`
+	Events  []string `json:"events"`
+	Enabled *bool    `json:"enabled"`
+}
+
+type ListWebhooksResult struct {
+	Items []WebhookSubscription `json:"items"`
+}
+
+type ListWebhookDeliveriesResult struct {
+	Items   []WebhookDelivery `json:"items"`
+	Limit   int               `json:"limit"`
+	Offset  int               `json:"offset"`
+	HasMore bool              `json:"hasMore"`
+}
+
 type CreateOrderInput struct {
 	Number       string  `json:"number" binding:"required"`
-	Total        float64 `json:"total" binding:"required,gt=0"`
+	Total        float64 `json:"total" binding:"required,gt=0,lte=9999999999.99"`
 	CustomerName string  `json:"customerName" binding:"required"`
+	// Status defaults to OrderStatusNew.
+	Status string `json:"status,omitempty"`
+}
+
+type ImportOrderRow struct {
+	Line      int
+	Order     CreateOrderInput
+	CreatedAt *time.Time
+	Err       error
+}
+
+type ImportOrdersOptions struct {
+	Notify    bool
+	BatchSize int
+	// Concurrency limits the notifications sent at once; 0 means 4.
+	Concurrency int
+}
+
+type ImportRowResult struct {
+	Line       int     `json:"line"`
+	Number     string  `json:"number,omitempty"`
+	Status     string  `json:"status"`
+	OrderID    *int64  `json:"orderId,omitempty"`
+	SendStatus string  `json:"sendStatus,omitempty"`
+	Error      *string `json:"error,omitempty"`
+}
+
+type ImportOrdersResult struct {
+	Total    int               `json:"total"`
+	Imported int               `json:"imported"`
+	Invalid  int               `json:"invalid"`
+	Failed   int               `json:"failed"`
+	Rows     []ImportRowResult `json:"rows"`
 }
 
 type OrderSendResult struct {
@@ -41,3 +283,10 @@ type TelegramStatus struct {
 	SentCount    int64      `json:"sentCount7d"`
 	FailedCount  int64      `json:"failedCount7d"`
 }
+
+type ListAuditEventsResult struct {
+	Items   []AuditEvent `json:"items"`
+	Limit   int          `json:"limit"`
+	Offset  int          `json:"offset"`
+	HasMore bool         `json:"hasMore"`
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"strings"
+)
+
+type ErrorKind int
+
+const (
+	KindInternal ErrorKind = iota
+	KindInvalid
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindTooLarge
+	KindRateLimited
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is the error model shared with API clients: Code is stable and meant
+// to be switched on, Message is for humans. Two errors match with errors.Is
+// when their codes are equal, so sentinels keep working after wrapping.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	Fields  []FieldError
+}
+
+func NewError(kind ErrorKind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// InvalidField reports a single invalid input field.
+func InvalidField(field, code, message string) error {
+	return ValidationFailed([]FieldError{{Field: field, Code: code, Message: message}})
+}
+
+func ValidationFailed(fields []FieldError) error {
+	messages := make([]string, len(fields))
+
+	for i, field := range fields {
+		messages[i] = field.Message
+	}
+
+	return &Error{
+		Kind:    KindInvalid,
+		Code:    ErrInvalidInput.Code,
+		Message: ErrInvalidInput.Message + ": " + strings.Join(messages, "; "),
+		Fields:  fields,
+	}
+}
@@ -0,0 +1,295 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+)
+
+// TelegramSettingChatID is the only Telegram setting; the bot token is the secret.
+const TelegramSettingChatID = "chatId"
+
+func (s *Service) ListChannels() ListChannelsResult {
+	return ListChannelsResult{Items: s.notifiers.Channels()}
+}
+
+func (s *Service) ListIntegrations(ctx context.Context, shopID int64) (ListIntegrationsResult, error) {
+	integrations, err := s.integrations.ListByShop(ctx, shopID)
+
+	if err != nil {
+		return ListIntegrationsResult{}, err
+	}
+
+	items := make([]Integration, 0, len(integrations))
+
+	for _, integration := range integrations {
+		items = append(items, s.maskIntegration(integration))
+	}
+
+	return ListIntegrationsResult{Items: items}, nil
+}
+
+func (s *Service) ConnectIntegration(ctx context.Context, shopID int64, channel ChannelType, input ConnectIntegrationInput) (Integration, error) {
+	notifier, err := s.notifiers.Get(channel)
+
+	if err != nil {
+		return Integration{}, err
+	}
+
+	input.Settings, input.Secret, err = notifier.Normalize(input.Settings, input.Secret)
+
+	if err != nil {
+		return Integration{}, err
+	}
+
+	if err := normalizeBatchWindow(notifier, channel, input.BatchWindowSeconds); err != nil {
+		return Integration{}, err
+	}
+
+	before, found, err := s.integrations.Get(ctx, shopID, channel)
+
+	if err != nil {
+		return Integration{}, err
+	}
+
+	integration, err := s.integrations.Upsert(ctx, shopID, channel, input)
+
+	if err != nil {
+		return Integration{}, err
+	}
+
+	s.recordAudit(ctx, shopID, integrationAction(channel, "connected"), AuditEntityIntegration, &integration.ID,
+		s.integrationSnapshot(before, found), s.integrationSnapshot(integration, true))
+
+	return s.maskIntegration(integration), nil
+}
+
+func (s *Service) UpdateIntegration(ctx context.Context, shopID int64, channel ChannelType, input UpdateIntegrationInput) (Integration, error) {
+	if input.Settings == nil && input.Secret == nil && input.Enabled == nil && input.BatchWindowSeconds == nil {
+		return Integration{}, fmt.Errorf("%w: nothing to update", ErrInvalidInput)
+	}
+
+	notifier, err := s.notifiers.Get(channel)
+
+	if err != nil {
+		return Integration{}, err
+	}
+
+	if input.BatchWindowSeconds != nil {
+		if err := normalizeBatchWindow(notifier, channel, *input.BatchWindowSeconds); err != nil {
+			return Integration{}, err
+		}
+	}
+
+	before, found, err := s.integrations.Get(ctx, shopID, channel)
+
+	if err != nil {
+		return Integration{}, err
+	}
+
+	if !found {
+		return Integration{}, ErrShopNotIntegrated
+	}
+
+	if input.Settings != nil || input.Secret != nil {
+		settings := maps.Clone(before.Settings)
+
+		if settings == nil {
+			settings = map[string]string{}
+		}
+
+		maps.Copy(settings, input.Settings)
+		secret := before.Secret
+
+		if input.Secret != nil {
+			secret = *input.Secret
+		}
+
+		settings, secret, err = notifier.Normalize(settings, secret)
+
+		if err != nil {
+			return Integration{}, err
+		}
+
+		input.Settings = settings
+
+		if input.Secret != nil {
+			input.Secret = &secret
+		}
+	}
+
+	integration, err := s.integrations.Update(ctx, shopID, channel, input)
+
+	if err != nil {
+		return Integration{}, err
+	}
+
+	s.recordAudit(ctx, shopID, integrationAction(channel, "updated"), AuditEntityIntegration, &integration.ID,
+		s.integrationSnapshot(before, true), s.integrationSnapshot(integration, true))
+
+	return s.maskIntegration(integration), nil
+}
+
+func (s *Service) DisconnectIntegration(ctx context.Context, shopID int64, channel ChannelType) error {
+	if _, err := s.notifiers.Get(channel); err != nil {
+		return err
+	}
+
+	before, found, err := s.integrations.Get(ctx, shopID, channel)
+
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return ErrShopNotIntegrated
+	}
+
+	if err := s.integrations.Delete(ctx, shopID, channel); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, shopID, integrationAction(channel, "disconnected"), AuditEntityIntegration, &before.ID,
+		s.integrationSnapshot(before, true), nil)
+
+	return nil
+}
+
+func (s *Service) GetIntegrationStatus(ctx context.Context, shopID int64, channel ChannelType) (IntegrationStatus, error) {
+	if _, err := s.notifiers.Get(channel); err != nil {
+		return IntegrationStatus{}, err
+	}
+
+	integration, found, err := s.integrations.Get(ctx, shopID, channel)
+
+	if err != nil {
+		return IntegrationStatus{}, err
+	}
+
+	if !found {
+		return IntegrationStatus{Channel: channel, Settings: map[string]string{}}, nil
+	}
+
+	since, err := s.statsSince(ctx, shopID, time.Now())
+
+	if err != nil {
+		return IntegrationStatus{}, err
+	}
+
+	lastSentAt, sentCount, failedCount, err := s.notificationLogs.GetStatusStats(ctx, shopID, channel, since)
+
+	if err != nil {
+		return IntegrationStatus{}, err
+	}
+
+	status := IntegrationStatus{
+		Channel:     channel,
+		Enabled:     integration.Enabled,
+		Settings:    s.maskIntegration(integration).Settings,
+		LastSentAt:  lastSentAt,
+		SentCount:   sentCount,
+		FailedCount: failedCount,
+	}
+
+	if channel == ChannelTelegram && s.destinations != nil {
+		if status.Destinations, err = s.destinationStatuses(ctx, integration, since); err != nil {
+			return IntegrationStatus{}, err
+		}
+	}
+
+	return status, nil
+}
+
+func (s *Service) ConnectTelegram(ctx context.Context, shopID int64, input ConnectTelegramInput) (TelegramIntegration, error) {
+	integration, err := s.ConnectIntegration(ctx, shopID, ChannelTelegram, ConnectIntegrationInput{
+		Settings: map[string]string{TelegramSettingChatID: input.ChatID},
+		Secret:   input.BotToken,
+		Enabled:  input.Enabled,
+	})
+
+	if err != nil {
+		return TelegramIntegration{}, err
+	}
+
+	return telegramView(integration), nil
+}
+
+func (s *Service) UpdateTelegram(ctx context.Context, shopID int64, input UpdateTelegramInput) (TelegramIntegration, error) {
+	if input.ChatID == nil && input.Enabled == nil {
+		return TelegramIntegration{}, fmt.Errorf("%w: nothing to update", ErrInvalidInput)
+	}
+
+	update := UpdateIntegrationInput{Enabled: input.Enabled}
+
+	if input.ChatID != nil {
+		chatID := strings.TrimSpace(*input.ChatID)
+
+		if chatID == "" {
+			return TelegramIntegration{}, InvalidField("chatId", "required", "chatId must be non-empty")
+		}
+
+		update.Settings = map[string]string{TelegramSettingChatID: chatID}
+	}
+
+	integration, err := s.UpdateIntegration(ctx, shopID, ChannelTelegram, update)
+
+	if err != nil {
+		return TelegramIntegration{}, err
+	}
+
+	return telegramView(integration), nil
+}
+
+func (s *Service) DisconnectTelegram(ctx context.Context, shopID int64) error {
+	return s.DisconnectIntegration(ctx, shopID, ChannelTelegram)
+}
+
+func (s *Service) GetTelegramStatus(ctx context.Context, shopID int64) (TelegramStatus, error) {
+	status, err := s.GetIntegrationStatus(ctx, shopID, ChannelTelegram)
+
+	if err != nil {
+		return TelegramStatus{}, err
+	}
+
+	return TelegramStatus{
+		Enabled:      status.Enabled,
+		MaskedChatID: status.Settings[TelegramSettingChatID],
+		LastSentAt:   status.LastSentAt,
+		SentCount:    status.SentCount,
+		FailedCount:  status.FailedCount,
+	}, nil
+}
+
+// maskIntegration replaces the secret with its fingerprint and masks settings
+// through the channel's notifier. Settings of unregistered channels are hidden.
+func (s *Service) maskIntegration(integration Integration) Integration {
+	integration.SecretFingerprint = TokenFingerprint(integration.Secret)
+	integration.Secret = ""
+
+	if notifier, err := s.notifiers.Get(integration.Channel); err == nil {
+		integration.Settings = notifier.Mask(integration.Settings)
+	} else {
+		integration.Settings = map[string]string{}
+	}
+
+	return integration
+}
+
+func telegramView(integration Integration) TelegramIntegration {
+	return TelegramIntegration{
+		ID:                  integration.ID,
+		ShopID:              integration.ShopID,
+		BotTokenFingerprint: integration.SecretFingerprint,
+		ChatID:              integration.Settings[TelegramSettingChatID],
+		Enabled:             integration.Enabled,
+		CreatedAt:           integration.CreatedAt,
+		UpdatedAt:           integration.UpdatedAt,
+	}
+}
+
+// integrationAction keeps the pre-registry "telegram.connected" style names.
+func integrationAction(channel ChannelType, verb string) string {
+	return string(channel) + "." + verb
+}
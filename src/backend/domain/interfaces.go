@@ -5,22 +5,129 @@ import (
 	"time"
 )
 
+type ShopRepository interface {
+	Create(ctx context.Context, input ShopInput) (Shop, error)
+	GetByID(ctx context.Context, shopID int64) (Shop, error)
+	List(ctx context.Context, limit, offset int) ([]Shop, error)
+	Update(ctx context.Context, shopID int64, input ShopInput) (Shop, error)
+	Delete(ctx context.Context, shopID int64) error
+}
+
 type IntegrationRepository interface {
-	Upsert(ctx context.Context, shopID int64, input ConnectTelegramInput) (TelegramIntegration, error)
-	GetByShopID(ctx context.Context, shopID int64) (TelegramIntegration, bool, error)
+	Upsert(ctx context.Context, shopID int64, channel ChannelType, input ConnectIntegrationInput) (Integration, error)
+	Get(ctx context.Context, shopID int64, channel ChannelType) (Integration, bool, error)
+	ListByShop(ctx context.Context, shopID int64) ([]Integration, error)
+	Update(ctx context.Context, shopID int64, channel ChannelType, input UpdateIntegrationInput) (Integration, error)
+	Delete(ctx context.Context, shopID int64, channel ChannelType) error
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, shopID int64, input WebhookInput) (WebhookSubscription, error)
+	Get(ctx context.Context, shopID, webhookID int64) (WebhookSubscription, error)
+	ListByShop(ctx context.Context, shopID int64) ([]WebhookSubscription, error)
+	Update(ctx context.Context, shopID, webhookID int64, input UpdateWebhookInput) (WebhookSubscription, error)
+	Delete(ctx context.Context, shopID, webhookID int64) error
+}
+
+// TelegramDestinationRepository fails Create and Update with
+// ErrDestinationExists when the integration already has a destination for
+// the chat.
+type TelegramDestinationRepository interface {
+	Create(ctx context.Context, shopID, integrationID int64, input TelegramDestinationInput) (TelegramDestination, error)
+	Get(ctx context.Context, shopID, destinationID int64) (TelegramDestination, error)
+	ListByShop(ctx context.Context, shopID int64) ([]TelegramDestination, error)
+	Update(ctx context.Context, shopID, destinationID int64, input UpdateTelegramDestinationInput) (TelegramDestination, error)
+	Delete(ctx context.Context, shopID, destinationID int64) error
+}
+
+// NotificationRuleRepository lists rules in evaluation order. It keeps one
+// evaluation per order; RecordEvaluation replaces an earlier one.
+type NotificationRuleRepository interface {
+	Create(ctx context.Context, shopID int64, input NotificationRuleInput) (NotificationRule, error)
+	Get(ctx context.Context, shopID, ruleID int64) (NotificationRule, error)
+	ListByShop(ctx context.Context, shopID int64) ([]NotificationRule, error)
+	Update(ctx context.Context, shopID, ruleID int64, input UpdateNotificationRuleInput) (NotificationRule, error)
+	Delete(ctx context.Context, shopID, ruleID int64) error
+	RecordEvaluation(ctx context.Context, evaluation RuleEvaluation) error
+	GetEvaluation(ctx context.Context, shopID, orderID int64) (RuleEvaluation, error)
+}
+
+// DigestRepository stores one digest schedule per shop and sums up orders and
+// the send log for digests.
+type DigestRepository interface {
+	Get(ctx context.Context, shopID int64) (DigestSchedule, bool, error)
+	Upsert(ctx context.Context, shopID int64, input DigestScheduleInput) (DigestSchedule, error)
+	ListEnabled(ctx context.Context) ([]DigestSchedule, error)
+	// MarkSent records a digest attempt; errText is nil when it was sent.
+	MarkSent(ctx context.Context, shopID int64, periodEnd, sentAt time.Time, errText *string) error
+	// Summary returns the digest of orders created in [from, to) with up to
+	// top customers by total.
+	Summary(ctx context.Context, shopID int64, from, to time.Time, top int) (Digest, error)
 }
 
 type OrderRepository interface {
 	Create(ctx context.Context, shopID int64, input CreateOrderInput) (Order, error)
+	CreateBatch(ctx context.Context, shopID int64, rows []ImportOrderRow) ([]Order, error)
+	GetByID(ctx context.Context, shopID, orderID int64) (Order, error)
 	List(ctx context.Context, shopID int64, limit, offset int) ([]OrderListItem, error)
 }
 
-type SendLogRepository interface {
-	Reserve(ctx context.Context, shopID, orderID int64, message string, reservedAt time.Time) (bool, error)
-	Finalize(ctx context.Context, shopID, orderID int64, status TelegramSendStatus, errText *string, sentAt time.Time) error
-	GetStatusStats(ctx context.Context, shopID int64, since time.Time) (lastSentAt *time.Time, sentCount, failedCount int64, err error)
+// NotificationLogRepository keeps one row per order, integration and recipient,
+// and one per order, webhook subscription and event. Reserve, Retry and Redeliver
+// return the row ID that Finalize takes; reserved is false when the
+// notification was already sent or is still in flight.
+type NotificationLogRepository interface {
+	Reserve(ctx context.Context, entry NotificationLog, reservedAt time.Time) (logID int64, reserved bool, err error)
+	Retry(ctx context.Context, entry NotificationLog, reservedAt time.Time) (logID int64, reserved bool, err error)
+	Finalize(ctx context.Context, logID int64, status NotificationStatus, errText *string, sentAt time.Time) error
+	// GetStatusStats does not count reserved rows, in flight or waiting for
+	// their batch, as failed.
+	GetStatusStats(ctx context.Context, shopID int64, channel ChannelType, since time.Time) (lastSentAt *time.Time, sentCount, failedCount int64, err error)
+	// GetRecipientStats is GetStatusStats for one integration, keyed by recipient.
+	GetRecipientStats(ctx context.Context, integrationID int64, since time.Time) (map[string]RecipientStats, error)
+	ListWebhookDeliveries(ctx context.Context, webhookID int64, limit, offset int) ([]WebhookDelivery, error)
+	// Redeliver reserves a finished delivery of the subscription again and
+	// returns it with its stored payload.
+	Redeliver(ctx context.Context, webhookID, deliveryID int64, reservedAt time.Time) (entry NotificationLog, reserved bool, err error)
+	// ClaimDue returns up to limit reserved rows whose DeliverAfter has passed
+	// and clears it, so each row is claimed once across instances.
+	ClaimDue(ctx context.Context, now time.Time, limit int) ([]NotificationLog, error)
+	// OpenBatch returns the earliest DeliverAfter after now among the rows
+	// reserved for the integration and recipient, or nil when none waits.
+	OpenBatch(ctx context.Context, integrationID int64, recipient string, now time.Time) (*time.Time, error)
+	// LinkMessage records the ID of the message that carried the rows'
+	// notifications.
+	LinkMessage(ctx context.Context, messageID string, logIDs []int64) error
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key NewAPIKey) (APIKey, error)
+	List(ctx context.Context, shopID int64) ([]APIKey, error)
+	GetByHash(ctx context.Context, hash []byte) (APIKey, bool, error)
+	Revoke(ctx context.Context, shopID, keyID int64, revokedAt time.Time) error
+	TouchLastUsed(ctx context.Context, keyID int64, usedAt time.Time) error
+}
+
+type UserRepository interface {
+	Create(ctx context.Context, email, passwordHash string) (User, error)
+	GetByID(ctx context.Context, userID int64) (User, error)
+	GetByEmail(ctx context.Context, email string) (User, error)
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, session Session) error
+	Get(ctx context.Context, tokenHash []byte) (Session, bool, error)
+	Delete(ctx context.Context, tokenHash []byte) error
+}
+
+type MemberRepository interface {
+	Upsert(ctx context.Context, shopID, userID int64, role Role) (ShopMember, error)
+	ListByShop(ctx context.Context, shopID int64) ([]ShopMember, error)
+	ListByUser(ctx context.Context, userID int64) ([]ShopMember, error)
+	Delete(ctx context.Context, shopID, userID int64) error
 }
 
-type TelegramClient interface {
-	SendMessage(ctx context.Context, botToken, chatID, text string) error
+type AuditRepository interface {
+	Append(ctx context.Context, event AuditEvent) error
+	List(ctx context.Context, shopID int64, limit, offset int) ([]AuditEvent, error)
 }
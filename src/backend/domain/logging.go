@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"context"
+	"log/slog"
+)
+
+type loggerKey struct{}
+
+// ContextWithLogger carries a request-scoped logger, already annotated with
+// the request and trace IDs, into the service and its background work.
+func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	logger, _ := ctx.Value(loggerKey{}).(*slog.Logger)
+	return logger
+}
+
+func WithLogger(logger *slog.Logger) ServiceOption {
+	return func(s *Service) {
+		if logger != nil {
+			s.logger = logger
+		}
+	}
+}
+
+func (s *Service) log(ctx context.Context) *slog.Logger {
+	if logger := LoggerFromContext(ctx); logger != nil {
+		return logger
+	}
+	return s.logger
+}
@@ -0,0 +1,121 @@
+package domain
+
+import (
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// Masking policy for values that identify a merchant's chat, its bot or its
+// customers. Every response and log line that carries such a value goes
+// through one of these helpers.
+
+const maskChar = "*"
+
+// MaskChatID keeps the "-100" supergroup prefix (or "@" for usernames) and the
+// last few characters: "-1001234567890" becomes "-100******7890".
+func MaskChatID(chatID string) string {
+	if chatID == "" {
+		return ""
+	}
+
+	prefix := ""
+
+	switch {
+	case strings.HasPrefix(chatID, "-100"):
+		prefix = "-100"
+	case strings.HasPrefix(chatID, "-"), strings.HasPrefix(chatID, "@"):
+		prefix = chatID[:1]
+	}
+
+	rest := []rune(chatID[len(prefix):])
+	visible := 4
+
+	if len(rest) <= 6 {
+		visible = 2
+	}
+
+	if len(rest) <= visible {
+		return prefix + strings.Repeat(maskChar, len(rest))
+	}
+
+	return prefix + strings.Repeat(maskChar, len(rest)-visible) + string(rest[len(rest)-visible:])
+}
+
+// MaskToken keeps only the bot ID part of a "123456:secret" token.
+func MaskToken(token string) string {
+	if token == "" {
+		return ""
+	}
+
+	botID, _, found := strings.Cut(token, ":")
+
+	if !found || botID == "" {
+		return strings.Repeat(maskChar, 4)
+	}
+
+	return botID + ":" + strings.Repeat(maskChar, 4)
+}
+
+// MaskURL keeps the scheme and host and hides the path and query, which often
+// carry a token: "https://hooks.example.com/T0/B1?key=x" becomes
+// "https://hooks.example.com/***".
+func MaskURL(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+
+	parsed, err := url.Parse(rawURL)
+
+	if err != nil || parsed.Host == "" {
+		return strings.Repeat(maskChar, 4)
+	}
+
+	masked := parsed.Scheme + "://" + parsed.Host
+
+	if parsed.Path != "" && parsed.Path != "/" || parsed.RawQuery != "" {
+		masked += "/" + strings.Repeat(maskChar, 3)
+	}
+
+	return masked
+}
+
+// MaskEmail keeps the first letter of the local part and the domain:
+// "anna@example.com" becomes "a***@example.com".
+func MaskEmail(address string) string {
+	local, domain, found := strings.Cut(address, "@")
+
+	if !found || local == "" {
+		return strings.Repeat(maskChar, 4)
+	}
+
+	first, _ := utf8.DecodeRuneInString(local)
+
+	return string(first) + strings.Repeat(maskChar, 3) + "@" + domain
+}
+
+// MaskCustomerName keeps the first letter of every word: "Анна Иванова" becomes "А*** И***".
+func MaskCustomerName(name string) string {
+	words := strings.Fields(name)
+
+	for i, word := range words {
+		first, size := utf8.DecodeRuneInString(word)
+		words[i] = string(first) + strings.Repeat(maskChar, max(utf8.RuneCountInString(word[size:]), 1))
+	}
+
+	return strings.Join(words, " ")
+}
+
+// RedactSecrets replaces every occurrence of the given tokens in text, e.g. in
+// error strings that embed a request URL.
+func RedactSecrets(text string, tokens ...string) string {
+	for _, token := range tokens {
+		if token == "" {
+			continue
+		}
+
+		text = strings.ReplaceAll(text, token, MaskToken(token))
+	}
+
+	return text
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+)
+
+// Message templates a notification rule can pick. The default one is used
+// when no rule asks for another.
+const (
+	TemplateDefault = "default"
+	TemplateVIP     = "vip"
+	TemplateShort   = "short"
+)
+
+var messageTemplates = []string{TemplateDefault, TemplateVIP, TemplateShort}
+
+// OrderMessage is the new-order notification in every form a channel may
+// need. Chat channels send Text; email also uses Subject and HTML, Slack and
+// Discord lay out Subject and Fields.
+type OrderMessage struct {
+	Subject string
+	Text    string
+	HTML    string
+	Fields  []MessageField
+}
+
+// MessageField is one labelled value of a message, e.g. the order total.
+type MessageField struct {
+	Name  string
+	Value string
+}
+
+var orderHTML = template.Must(template.New("order").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<body style="font-family: sans-serif">
+<p>{{.Heading}} <b>{{.Order.Number}}</b></p>
+<table cellpadding="4">
+<tr><td>Сумма</td><td>{{printf "%.2f" .Order.Total}} ₽</td></tr>
+<tr><td>Клиент</td><td>{{.Order.CustomerName}}</td></tr>
+</table>
+</body>
+</html>
+`))
+
+// RenderBatchHeader heads a message that merges count order notifications.
+func RenderBatchHeader(count int) string {
+	return fmt.Sprintf("📦 Новых заказов: %d", count)
+}
+
+func RenderOrderMessage(order Order) OrderMessage {
+	return RenderOrderTemplate(order, TemplateDefault)
+}
+
+// RenderOrderTemplate renders order with one of the message templates; an
+// unknown name falls back to the default one.
+func RenderOrderTemplate(order Order, name string) OrderMessage {
+	heading := "Новый заказ"
+	text := fmt.Sprintf("Новый заказ %s на сумму %.2f ₽, клиент %s", order.Number, order.Total, order.CustomerName)
+
+	switch name {
+	case TemplateVIP:
+		heading = "🔥 VIP-заказ"
+		text = fmt.Sprintf("🔥 VIP-заказ %s на сумму %.2f ₽, клиент %s. Свяжитесь с клиентом как можно скорее!", order.Number, order.Total, order.CustomerName)
+	case TemplateShort:
+		text = fmt.Sprintf("%s · %.2f ₽", order.Number, order.Total)
+	}
+
+	var html bytes.Buffer
+
+	// the template only reads fields of a valid Order, so it cannot fail
+	_ = orderHTML.Execute(&html, struct {
+		Heading string
+		Order   Order
+	}{heading, order})
+
+	return OrderMessage{
+		Subject: fmt.Sprintf("%s %s", heading, order.Number),
+		Text:    text,
+		HTML:    html.String(),
+		Fields: []MessageField{
+			{Name: "Сумма", Value: fmt.Sprintf("%.2f ₽", order.Total)},
+			{Name: "Клиент", Value: order.CustomerName},
+		},
+	}
+}
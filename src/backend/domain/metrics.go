@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+const (
+	DeliveryOutcomeSent   = "sent"
+	DeliveryOutcomeFailed = "failed"
+)
+
+// Metrics receives measurements from the delivery pipeline. Implementations
+// must be safe for concurrent use; NopMetrics is used when none is configured.
+type Metrics interface {
+	// ObserveTelegramRequest records one Bot API call; errorClass is "none" on success.
+	ObserveTelegramRequest(errorClass string, duration time.Duration)
+	// ObserveDelivery records the final outcome of a notification on one channel after all attempts.
+	ObserveDelivery(channel, outcome string, attempts int)
+	// AddPendingNotifications tracks notifications reserved but not yet finalized.
+	AddPendingNotifications(delta int)
+}
+
+type NopMetrics struct{}
+
+func (NopMetrics) ObserveTelegramRequest(string, time.Duration) {}
+func (NopMetrics) ObserveDelivery(string, string, int)          {}
+func (NopMetrics) AddPendingNotifications(int)                  {}
+
+func WithMetrics(metrics Metrics) ServiceOption {
+	return func(s *Service) {
+		if metrics != nil {
+			s.metrics = metrics
+		}
+	}
+}
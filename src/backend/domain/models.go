@@ -2,29 +2,66 @@ package domain
 
 import "time"
 
-type TelegramSendStatus string
+type NotificationStatus string
 
 const (
-	TelegramSendStatusSent   TelegramSendStatus = "SENT"
-	TelegramSendStatusFailed TelegramSendStatus = "FAILED"
+	NotificationStatusSent   NotificationStatus = "SENT"
+	NotificationStatusFailed NotificationStatus = "FAILED"
 )
 
+type Shop struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Timezone string `json:"timezone"`
+	Locale   string `json:"locale"`
+	Currency string `json:"currency"`
+	// QuietHours is nil when the shop may be notified at any time.
+	QuietHours *QuietHours `json:"quietHours"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	UpdatedAt  time.Time   `json:"updatedAt"`
+}
+
+// Integration is one notification channel connected to a shop. Settings are
+// channel-specific and stored as-is; Secret is encrypted at rest.
+type Integration struct {
+	ID                int64             `json:"id"`
+	ShopID            int64             `json:"shopId"`
+	Channel           ChannelType       `json:"channel"`
+	Settings          map[string]string `json:"settings"`
+	Secret            string            `json:"-"`
+	SecretFingerprint string            `json:"secretFingerprint,omitempty"`
+	Enabled           bool              `json:"enabled"`
+	// BatchWindowSeconds merges the orders created within the window into one
+	// message; zero sends each order at once.
+	BatchWindowSeconds int       `json:"batchWindowSeconds"`
+	CreatedAt          time.Time `json:"createdAt"`
+	UpdatedAt          time.Time `json:"updatedAt"`
+}
+
+// TelegramIntegration is the response shape of the /telegram endpoints.
 type TelegramIntegration struct {
-	ID        int64     `json:"id"`
-	ShopID    int64     `json:"shopId"`
-	BotToken  string    `json:"botToken"`
-	ChatID    string    `json:"chatId"`
-	Enabled   bool      `json:"enabled"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID                  int64     `json:"id"`
+	ShopID              int64     `json:"shopId"`
+	BotToken            string    `json:"-"`
+	BotTokenFingerprint string    `json:"botTokenFingerprint"`
+	ChatID              string    `json:"chatId"`
+	Enabled             bool      `json:"enabled"`
+	CreatedAt           time.Time `json:"createdAt"`
+	UpdatedAt           time.Time `json:"updatedAt"`
 }
 
+// OrderStatusNew is the status of an order created without one.
+const OrderStatusNew = "new"
+
+// Order.Status is the shop's own status, e.g. "paid" or "shipped": a
+// lowercase token that notification rules can match on.
 type Order struct {
 	ID           int64     `json:"id"`
 	ShopID       int64     `json:"shopId"`
 	Number       string    `json:"number"`
 	Total        float64   `json:"total"`
 	CustomerName string    `json:"customerName"`
+	Status       string    `json:"status"`
 	CreatedAt    time.Time `json:"createdAt"`
 }
 
@@ -34,16 +71,37 @@ type OrderListItem struct {
 	Number       string    `json:"number"`
 	Total        float64   `json:"total"`
 	CustomerName string    `json:"customerName"`
+	Status       string    `json:"status"`
 	CreatedAt    time.Time `json:"createdAt"`
 	SendStatus   string    `json:"sendStatus"`
 }
 
-type TelegramSendLog struct {
-	ID      int64
-	ShopID  int64
-	OrderID int64
-	Message string
-	Status  TelegramSendStatus
-	Error   *string
-	SentAt  time.Time
+// RecipientStats counts sends like IntegrationStatus, for one recipient.
+type RecipientStats struct {
+	LastSentAt  *time.Time
+	SentCount   int64
+	FailedCount int64
+}
+
+// NotificationLog belongs to either an integration or a webhook subscription;
+// the other ID is zero. Event is only set for webhooks and Recipient only for
+// channels with several recipients per integration.
+type NotificationLog struct {
+	ID             int64
+	ShopID         int64
+	OrderID        int64
+	IntegrationID  int64
+	SubscriptionID int64
+	Channel        ChannelType
+	Recipient      string
+	Event          string
+	Message        string
+	Status         NotificationStatus
+	Error          *string
+	SentAt         time.Time
+	// DeliverAfter holds a reserved row back until the shop's quiet hours end.
+	DeliverAfter *time.Time
+	// MessageID is the channel's ID of the message that carried a batched
+	// notification; several rows share it.
+	MessageID *string
 }
@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+)
+
+type ChannelType string
+
+const (
+	ChannelTelegram ChannelType = "telegram"
+	ChannelEmail    ChannelType = "email"
+	ChannelSlack    ChannelType = "slack"
+	ChannelDiscord  ChannelType = "discord"
+)
+
+var ErrUnknownChannel = NewError(KindNotFound, "channel_not_found", "notification channel not found")
+
+// Notification is one rendered message for one integration. ID is the
+// notification_log row; Event is only set for webhooks and Recipient only for
+// a RecipientNotifier. Silent asks for a delivery without a sound; channels
+// that cannot do that ignore it.
+type Notification struct {
+	ID        int64
+	ShopID    int64
+	OrderID   int64
+	Event     string
+	Recipient string
+	Subject   string
+	Text      string
+	HTML      string
+	Fields    []MessageField
+	Silent    bool
+}
+
+// Notifier delivers notifications through one channel type. Implementations
+// live in adapters and receive the integration with its secret decrypted.
+type Notifier interface {
+	// Normalize validates settings and secret before they are stored and
+	// returns them trimmed. Field errors name "settings.<key>" or "secret".
+	Normalize(settings map[string]string, secret string) (map[string]string, string, error)
+	// Mask returns a copy of settings that is safe to return and audit.
+	Mask(settings map[string]string) map[string]string
+	Send(ctx context.Context, integration Integration, notification Notification) error
+}
+
+// maxRetryAfter caps how long a rate-limited send waits before the next
+// attempt, so a channel asking for minutes cannot hold a delivery that long.
+const maxRetryAfter = 30 * time.Second
+
+// RateLimitError is returned by Send when the channel answered that the sender
+// is over its rate limit. The next attempt waits RetryAfter instead of the
+// usual backoff.
+type RateLimitError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// RecipientNotifier is implemented by channels whose integration names several
+// recipients, e.g. email addresses. Each recipient gets its own
+// notification_log row and is sent to, retried and resent on its own.
+type RecipientNotifier interface {
+	Notifier
+	Recipients(integration Integration) []string
+	// MaskRecipient returns the recipient in a form that is safe to log.
+	MaskRecipient(recipient string) string
+}
+
+// BatchNotifier is implemented by channels that can merge several
+// notifications for one recipient into one message.
+type BatchNotifier interface {
+	Notifier
+	// SendBatch sends notifications in as few messages as the channel allows
+	// and returns, for each notification, the ID of the message that carried
+	// it. On error, the IDs of the messages sent before it are still returned;
+	// notifications without an ID were not sent.
+	SendBatch(ctx context.Context, integration Integration, notifications []Notification) ([]string, error)
+}
+
+// NotifierRegistry maps channel types to their notifiers. It is filled once at
+// startup and only read afterwards.
+type NotifierRegistry struct {
+	notifiers map[ChannelType]Notifier
+}
+
+func NewNotifierRegistry() *NotifierRegistry {
+	return &NotifierRegistry{notifiers: map[ChannelType]Notifier{}}
+}
+
+func (r *NotifierRegistry) Register(channel ChannelType, notifier Notifier) *NotifierRegistry {
+	r.notifiers[channel] = notifier
+	return r
+}
+
+func (r *NotifierRegistry) Get(channel ChannelType) (Notifier, error) {
+	notifier, ok := r.notifiers[channel]
+
+	if !ok {
+		return nil, ErrUnknownChannel
+	}
+
+	return notifier, nil
+}
+
+func (r *NotifierRegistry) Channels() []ChannelType {
+	return slices.Sorted(maps.Keys(r.notifiers))
+}
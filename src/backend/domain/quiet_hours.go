@@ -0,0 +1,273 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type QuietHoursMode string
+
+const (
+	// QuietHoursDefer holds sends until the window ends.
+	QuietHoursDefer QuietHoursMode = "defer"
+	// QuietHoursSilent sends at once without a sound where the channel
+	// supports it (Telegram, Discord).
+	QuietHoursSilent QuietHoursMode = "silent"
+)
+
+var quietHoursModes = []QuietHoursMode{QuietHoursDefer, QuietHoursSilent}
+
+// QuietHours is a daily window of the shop, e.g. 23:00-08:00, in which order
+// notifications must not ping the owner. From and To are "HH:MM" in the shop's
+// time zone; the window may wrap midnight.
+type QuietHours struct {
+	From string         `json:"from"`
+	To   string         `json:"to"`
+	Mode QuietHoursMode `json:"mode"`
+}
+
+// End returns the end of the window that contains now, or false when now is
+// outside the window. The quiet hours must have been normalized.
+func (q QuietHours) End(now time.Time, loc *time.Location) (time.Time, bool) {
+	local := now.In(loc)
+
+	if !inClockWindow(local.Format("15:04"), q.From, q.To) {
+		return time.Time{}, false
+	}
+
+	// normalized, so it parses
+	to, _ := time.Parse("15:04", q.To)
+	end := time.Date(local.Year(), local.Month(), local.Day(), to.Hour(), to.Minute(), 0, 0, loc)
+
+	if !end.After(local) {
+		end = time.Date(local.Year(), local.Month(), local.Day()+1, to.Hour(), to.Minute(), 0, 0, loc)
+	}
+
+	return end, true
+}
+
+// inClockWindow reports whether the zero-padded "HH:MM" clock is in
+// [from, to); a window with from after to wraps midnight.
+func inClockWindow(clock, from, to string) bool {
+	if from < to {
+		return clock >= from && clock < to
+	}
+
+	return clock >= from || clock < to
+}
+
+// parseClock validates "H:MM" or "HH:MM" and returns it zero-padded, which is
+// what inClockWindow compares.
+func parseClock(value string) (string, error) {
+	parsed, err := time.Parse("15:04", strings.TrimSpace(value))
+
+	if err != nil {
+		return "", err
+	}
+
+	return parsed.Format("15:04"), nil
+}
+
+func normalizeQuietHours(q *QuietHours) (*QuietHours, error) {
+	if q == nil {
+		return nil, nil
+	}
+
+	out := *q
+	var err error
+
+	if out.From, err = parseClock(q.From); err != nil {
+		return nil, InvalidField("quietHours.from", "time", "quietHours.from must be HH:MM")
+	}
+
+	if out.To, err = parseClock(q.To); err != nil {
+		return nil, InvalidField("quietHours.to", "time", "quietHours.to must be HH:MM")
+	}
+
+	if out.From == out.To {
+		return nil, InvalidField("quietHours.to", "ne", "quietHours.to must differ from quietHours.from")
+	}
+
+	if out.Mode == "" {
+		out.Mode = QuietHoursDefer
+	}
+
+	if !slices.Contains(quietHoursModes, out.Mode) {
+		return nil, InvalidField("quietHours.mode", "oneof", "quietHours.mode must be defer or silent")
+	}
+
+	return &out, nil
+}
+
+// shopLocation returns the shop's time zone; it was validated when the shop
+// was saved, so UTC is only a guard against a tzdata change.
+func shopLocation(shop Shop) *time.Location {
+	loc, err := time.LoadLocation(shop.Timezone)
+
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
+// WithShops gives the service the shop settings that affect notifications:
+// the time zone and quiet hours. Without it, shops have no quiet hours and
+// stats use a rolling week.
+func WithShops(shops ShopRepository) ServiceOption {
+	return func(s *Service) {
+		s.shops = shops
+	}
+}
+
+// location returns the shop's time zone, or UTC without WithShops.
+func (p notifyPlan) location() *time.Location {
+	if p.shop == nil {
+		return time.UTC
+	}
+
+	return shopLocation(*p.shop)
+}
+
+// quietHours reports how a send at now must respect the shop's quiet hours:
+// the time to defer it to, or whether to send it silently.
+func (p notifyPlan) quietHours(now time.Time) (*time.Time, bool) {
+	if p.shop == nil || p.shop.QuietHours == nil {
+		return nil, false
+	}
+
+	end, ok := p.shop.QuietHours.End(now, shopLocation(*p.shop))
+
+	if !ok {
+		return nil, false
+	}
+
+	if p.shop.QuietHours.Mode == QuietHoursSilent {
+		return nil, true
+	}
+
+	return &end, false
+}
+
+// statsSince returns the start of the 7-day stats window: midnight six days
+// ago in the shop's time zone, so the days are the shop's calendar days.
+func (s *Service) statsSince(ctx context.Context, shopID int64, now time.Time) (time.Time, error) {
+	if s.shops == nil {
+		return now.AddDate(0, 0, -7), nil
+	}
+
+	shop, err := s.shops.GetByID(ctx, shopID)
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	local := now.In(shopLocation(shop))
+
+	return time.Date(local.Year(), local.Month(), local.Day()-6, 0, 0, 0, 0, local.Location()), nil
+}
+
+// DeliverDue sends up to limit notifications whose quiet hours or batching
+// window are over and returns how many it started. Rows are claimed, so
+// several instances can run it at once. Batching integrations merge the rows
+// claimed for a recipient into one message; DeliverDue waits for those sends.
+// A row whose order or integration is gone, or whose integration was disabled
+// meanwhile, is marked failed and can be resent.
+func (s *Service) DeliverDue(ctx context.Context, limit int) (int, error) {
+	entries, err := s.notificationLogs.ClaimDue(ctx, time.Now(), limit)
+
+	if err != nil {
+		return 0, err
+	}
+
+	started := 0
+	var batches []*orderBatch
+	byKey := map[batchKey]*orderBatch{}
+
+	for _, entry := range entries {
+		logger := s.log(ctx).With("shopId", entry.ShopID, "orderId", entry.OrderID, "channel", string(entry.Channel))
+		notifier, integration, notification, err := s.deferredNotification(ctx, entry)
+
+		if err != nil {
+			errText := err.Error()
+			logger.Warn("deferred notification dropped", "error", errText)
+			s.finalize(ctx, logger, entry.ID, NotificationStatusFailed, &errText)
+			continue
+		}
+
+		started++
+		batchNotifier, ok := notifier.(BatchNotifier)
+
+		if !ok || integration.BatchWindowSeconds <= 0 {
+			s.dispatch(ctx, notifier, integration, notification)
+			continue
+		}
+
+		key := batchKey{integrationID: integration.ID, recipient: notification.Recipient}
+
+		if batch, ok := byKey[key]; ok {
+			batch.notifications = append(batch.notifications, notification)
+			continue
+		}
+
+		byKey[key] = &orderBatch{notifier: batchNotifier, integration: integration, notifications: []Notification{notification}}
+		batches = append(batches, byKey[key])
+	}
+
+	s.deliverBatches(ctx, batches)
+
+	return started, nil
+}
+
+// dueBatch bounds one DeliverDue call of DeliverAllDue.
+const dueBatch = 100
+
+// DeliverAllDue runs DeliverDue until no more notifications are due.
+func (s *Service) DeliverAllDue(ctx context.Context) error {
+	for {
+		started, err := s.DeliverDue(ctx, dueBatch)
+
+		if err != nil || started < dueBatch {
+			return err
+		}
+	}
+}
+
+// deferredNotification renders a claimed row again, as its rule evaluation
+// asked, for the integration as it is now.
+func (s *Service) deferredNotification(ctx context.Context, entry NotificationLog) (Notifier, Integration, Notification, error) {
+	order, err := s.orders.GetByID(ctx, entry.ShopID, entry.OrderID)
+
+	if err != nil {
+		return nil, Integration{}, Notification{}, err
+	}
+
+	integration, found, err := s.integrations.Get(ctx, entry.ShopID, entry.Channel)
+
+	if err != nil {
+		return nil, Integration{}, Notification{}, err
+	}
+
+	if !found || integration.ID != entry.IntegrationID || !integration.Enabled {
+		return nil, Integration{}, Notification{}, fmt.Errorf("%s integration was disabled or disconnected while the notification waited", entry.Channel)
+	}
+
+	notifier, err := s.notifiers.Get(integration.Channel)
+
+	if err != nil {
+		return nil, Integration{}, Notification{}, err
+	}
+
+	evaluation, err := s.recordedEvaluation(ctx, order)
+
+	if err != nil {
+		return nil, Integration{}, Notification{}, err
+	}
+
+	message, silent := evaluation.apply(order)
+
+	return notifier, integration, orderNotification(entry.ID, order, entry.Recipient, message, silent), nil
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit allows Requests per fixed Window. The zero value disables limiting.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+func (l RateLimit) Enabled() bool {
+	return l.Requests > 0 && l.Window > 0
+}
+
+func (l RateLimit) String() string {
+	if !l.Enabled() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Window)
+}
+
+// ParseRateLimit accepts "100/1m" style specs; "", "0" and "off" disable the limit.
+func ParseRateLimit(spec string) (RateLimit, error) {
+	spec = strings.TrimSpace(spec)
+
+	if spec == "" || spec == "0" || strings.EqualFold(spec, "off") {
+		return RateLimit{}, nil
+	}
+
+	rawRequests, rawWindow, ok := strings.Cut(spec, "/")
+
+	if !ok {
+		return RateLimit{}, fmt.Errorf("%w: rate limit must look like 100/1m", ErrInvalidInput)
+	}
+
+	requests, err := strconv.Atoi(strings.TrimSpace(rawRequests))
+
+	if err != nil || requests < 0 {
+		return RateLimit{}, fmt.Errorf("%w: invalid rate limit requests %q", ErrInvalidInput, rawRequests)
+	}
+
+	window, err := time.ParseDuration(strings.TrimSpace(rawWindow))
+
+	if err != nil || window <= 0 {
+		return RateLimit{}, fmt.Errorf("%w: invalid rate limit window %q", ErrInvalidInput, rawWindow)
+	}
+
+	return RateLimit{Requests: requests, Window: window}, nil
+}
+
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	ResetAt   time.Time
+}
+
+// RateLimitWindow returns the start of the fixed window that now falls into.
+func RateLimitWindow(limit RateLimit, now time.Time) time.Time {
+	return now.Truncate(limit.Window)
+}
+
+func NewRateLimitResult(limit RateLimit, windowStart time.Time, hits int) RateLimitResult {
+	return RateLimitResult{
+		Allowed:   hits <= limit.Requests,
+		Remaining: max(limit.Requests-hits, 0),
+		ResetAt:   windowStart.Add(limit.Window),
+	}
+}
+
+type RateLimitStore interface {
+	Hit(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+}
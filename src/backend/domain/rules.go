@@ -0,0 +1,468 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrRuleNotFound       = NewError(KindNotFound, "rule_not_found", "notification rule not found")
+	ErrRulesDisabled      = NewError(KindNotFound, "rules_disabled", "notification rules are not configured")
+	ErrEvaluationNotFound = NewError(KindNotFound, "evaluation_not_found", "the order was not evaluated against notification rules")
+)
+
+const (
+	maxRuleNameLength       = 100
+	maxRulePatternLength    = 200
+	maxRuleNumberPrefixSize = 50
+)
+
+type RuleAction string
+
+const (
+	// RuleActionSend notifies as usual; it lets a rule stop the evaluation
+	// before a broader skip rule below it.
+	RuleActionSend RuleAction = "send"
+	RuleActionSkip RuleAction = "skip"
+	// RuleActionSilent delivers without a sound where the channel supports it
+	// (Telegram, Discord) and as usual elsewhere.
+	RuleActionSilent   RuleAction = "silent"
+	RuleActionTemplate RuleAction = "template"
+)
+
+var ruleActions = []RuleAction{RuleActionSend, RuleActionSkip, RuleActionSilent, RuleActionTemplate}
+
+// NotificationRule decides how the shop is notified about an order. Rules are
+// evaluated by Position, then ID, and the first enabled rule whose conditions
+// all match picks the action; an order no rule matches is sent as usual.
+// Rules apply to notification channels only, not to webhooks.
+type NotificationRule struct {
+	ID         int64          `json:"id"`
+	ShopID     int64          `json:"shopId"`
+	Position   int            `json:"position"`
+	Name       string         `json:"name"`
+	Conditions RuleConditions `json:"conditions"`
+	Action     RuleAction     `json:"action"`
+	// Template is the message template of a template rule.
+	Template  string    `json:"template,omitempty"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// RuleConditions must all match; empty conditions match every order.
+// CustomerPattern is a case-insensitive regular expression searched in the
+// customer name; NumberPrefix is compared case-sensitively; Statuses matches
+// an order whose status is any of them.
+type RuleConditions struct {
+	MinTotal        *float64 `json:"minTotal,omitempty"`
+	MaxTotal        *float64 `json:"maxTotal,omitempty"`
+	CustomerPattern string   `json:"customerPattern,omitempty"`
+	NumberPrefix    string   `json:"numberPrefix,omitempty"`
+	Statuses        []string `json:"statuses,omitempty"`
+}
+
+// RuleEvaluation records which rule decided an order's notification and why,
+// so a skipped order can be explained. RuleID is null when no rule matched
+// and after the rule was deleted.
+type RuleEvaluation struct {
+	OrderID     int64      `json:"orderId"`
+	ShopID      int64      `json:"shopId"`
+	RuleID      *int64     `json:"ruleId"`
+	RuleName    string     `json:"ruleName"`
+	Action      RuleAction `json:"action"`
+	Template    string     `json:"template,omitempty"`
+	Reason      string     `json:"reason"`
+	EvaluatedAt time.Time  `json:"evaluatedAt"`
+}
+
+// compiledRule is a rule with its customer pattern compiled once per
+// evaluation run.
+type compiledRule struct {
+	NotificationRule
+	pattern *regexp.Regexp
+}
+
+// ruleSet is the shop's enabled rules in evaluation order. A nil set means
+// the shop has no rules and orders are not evaluated.
+type ruleSet []compiledRule
+
+// match reports whether order satisfies the conditions and, if so, which
+// conditions it satisfied.
+func (r compiledRule) match(order Order) (string, bool) {
+	var reasons []string
+	c := r.Conditions
+
+	if c.MinTotal != nil {
+		if order.Total < *c.MinTotal {
+			return "", false
+		}
+
+		reasons = append(reasons, fmt.Sprintf("total %.2f ≥ %.2f", order.Total, *c.MinTotal))
+	}
+
+	if c.MaxTotal != nil {
+		if order.Total > *c.MaxTotal {
+			return "", false
+		}
+
+		reasons = append(reasons, fmt.Sprintf("total %.2f ≤ %.2f", order.Total, *c.MaxTotal))
+	}
+
+	if r.pattern != nil {
+		if !r.pattern.MatchString(order.CustomerName) {
+			return "", false
+		}
+
+		reasons = append(reasons, fmt.Sprintf("customer name matches %q", c.CustomerPattern))
+	}
+
+	if c.NumberPrefix != "" {
+		if !strings.HasPrefix(order.Number, c.NumberPrefix) {
+			return "", false
+		}
+
+		reasons = append(reasons, fmt.Sprintf("number starts with %q", c.NumberPrefix))
+	}
+
+	if len(c.Statuses) > 0 {
+		if !slices.Contains(c.Statuses, order.Status) {
+			return "", false
+		}
+
+		reasons = append(reasons, fmt.Sprintf("status is %q", order.Status))
+	}
+
+	if len(reasons) == 0 {
+		return "rule has no conditions", true
+	}
+
+	return strings.Join(reasons, ", "), true
+}
+
+// evaluate returns the evaluation of order; ok is false when the shop has no
+// rules.
+func (rs ruleSet) evaluate(order Order, now time.Time) (RuleEvaluation, bool) {
+	if len(rs) == 0 {
+		return RuleEvaluation{}, false
+	}
+
+	out := RuleEvaluation{OrderID: order.ID, ShopID: order.ShopID, Action: RuleActionSend, Reason: "no rule matched", EvaluatedAt: now}
+
+	for _, rule := range rs {
+		reason, ok := rule.match(order)
+
+		if !ok {
+			continue
+		}
+
+		out.RuleID = &rule.ID
+		out.RuleName = rule.Name
+		out.Action = rule.Action
+		out.Template = rule.Template
+		out.Reason = reason
+		break
+	}
+
+	return out, true
+}
+
+// apply renders order as the evaluation asks; a nil evaluation renders the
+// default message.
+func (e *RuleEvaluation) apply(order Order) (OrderMessage, bool) {
+	if e == nil {
+		return RenderOrderMessage(order), false
+	}
+
+	return RenderOrderTemplate(order, e.Template), e.Action == RuleActionSilent
+}
+
+// WithNotificationRules lets shops decide per order whether and how they are
+// notified.
+func WithNotificationRules(rules NotificationRuleRepository) ServiceOption {
+	return func(s *Service) {
+		s.rules = rules
+	}
+}
+
+func (s *Service) ListNotificationRules(ctx context.Context, shopID int64) (ListNotificationRulesResult, error) {
+	if s.rules == nil {
+		return ListNotificationRulesResult{}, ErrRulesDisabled
+	}
+
+	rules, err := s.rules.ListByShop(ctx, shopID)
+
+	if err != nil {
+		return ListNotificationRulesResult{}, err
+	}
+
+	return ListNotificationRulesResult{Items: rules}, nil
+}
+
+func (s *Service) CreateNotificationRule(ctx context.Context, shopID int64, input NotificationRuleInput) (NotificationRule, error) {
+	if s.rules == nil {
+		return NotificationRule{}, ErrRulesDisabled
+	}
+
+	rule, err := normalizeRule(NotificationRule{
+		Position:   input.Position,
+		Name:       input.Name,
+		Conditions: input.Conditions,
+		Action:     input.Action,
+		Template:   input.Template,
+	})
+
+	if err != nil {
+		return NotificationRule{}, err
+	}
+
+	input.Name, input.Conditions, input.Action, input.Template = rule.Name, rule.Conditions, rule.Action, rule.Template
+	created, err := s.rules.Create(ctx, shopID, input)
+
+	if err != nil {
+		return NotificationRule{}, err
+	}
+
+	s.recordAudit(ctx, shopID, AuditRuleCreated, AuditEntityRule, &created.ID, nil, ruleSnapshot(created))
+
+	return created, nil
+}
+
+func (s *Service) UpdateNotificationRule(ctx context.Context, shopID, ruleID int64, input UpdateNotificationRuleInput) (NotificationRule, error) {
+	if s.rules == nil {
+		return NotificationRule{}, ErrRulesDisabled
+	}
+
+	if input.Position == nil && input.Name == nil && input.Conditions == nil && input.Action == nil && input.Template == nil && input.Enabled == nil {
+		return NotificationRule{}, fmt.Errorf("%w: nothing to update", ErrInvalidInput)
+	}
+
+	before, err := s.rules.Get(ctx, shopID, ruleID)
+
+	if err != nil {
+		return NotificationRule{}, err
+	}
+
+	rule := before
+
+	if input.Position != nil {
+		rule.Position = *input.Position
+	}
+
+	if input.Name != nil {
+		rule.Name = *input.Name
+	}
+
+	if input.Conditions != nil {
+		rule.Conditions = *input.Conditions
+	}
+
+	if input.Action != nil {
+		rule.Action = *input.Action
+
+		// a template belongs to template rules only
+		if rule.Action != RuleActionTemplate && input.Template == nil {
+			rule.Template = ""
+		}
+	}
+
+	if input.Template != nil {
+		rule.Template = *input.Template
+	}
+
+	rule, err = normalizeRule(rule)
+
+	if err != nil {
+		return NotificationRule{}, err
+	}
+
+	input.Position, input.Name, input.Conditions, input.Action, input.Template = &rule.Position, &rule.Name, &rule.Conditions, &rule.Action, &rule.Template
+	updated, err := s.rules.Update(ctx, shopID, ruleID, input)
+
+	if err != nil {
+		return NotificationRule{}, err
+	}
+
+	s.recordAudit(ctx, shopID, AuditRuleUpdated, AuditEntityRule, &updated.ID, ruleSnapshot(before), ruleSnapshot(updated))
+
+	return updated, nil
+}
+
+func (s *Service) DeleteNotificationRule(ctx context.Context, shopID, ruleID int64) error {
+	if s.rules == nil {
+		return ErrRulesDisabled
+	}
+
+	before, err := s.rules.Get(ctx, shopID, ruleID)
+
+	if err != nil {
+		return err
+	}
+
+	if err := s.rules.Delete(ctx, shopID, ruleID); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, shopID, AuditRuleDeleted, AuditEntityRule, &before.ID, ruleSnapshot(before), nil)
+
+	return nil
+}
+
+// GetRuleEvaluation explains how the order's notification was decided.
+func (s *Service) GetRuleEvaluation(ctx context.Context, shopID, orderID int64) (RuleEvaluation, error) {
+	if s.rules == nil {
+		return RuleEvaluation{}, ErrRulesDisabled
+	}
+
+	if _, err := s.orders.GetByID(ctx, shopID, orderID); err != nil {
+		return RuleEvaluation{}, err
+	}
+
+	return s.rules.GetEvaluation(ctx, shopID, orderID)
+}
+
+// shopRules loads the shop's enabled rules for evaluation. A rule whose
+// pattern no longer compiles is logged and ignored rather than failing the
+// order.
+func (s *Service) shopRules(ctx context.Context, shopID int64) (ruleSet, error) {
+	if s.rules == nil {
+		return nil, nil
+	}
+
+	rules, err := s.rules.ListByShop(ctx, shopID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var out ruleSet
+
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+
+		compiled := compiledRule{NotificationRule: rule}
+
+		if rule.Conditions.CustomerPattern != "" {
+			compiled.pattern, err = regexp.Compile("(?i)" + rule.Conditions.CustomerPattern)
+
+			if err != nil {
+				s.log(ctx).Error("notification rule has an invalid pattern", "shopId", shopID, "ruleId", rule.ID, "error", err)
+				continue
+			}
+		}
+
+		out = append(out, compiled)
+	}
+
+	return out, nil
+}
+
+// evaluateRules evaluates and records the rules for order. It returns nil
+// when the shop has no rules.
+func (s *Service) evaluateRules(ctx context.Context, rules ruleSet, order Order) *RuleEvaluation {
+	evaluation, ok := rules.evaluate(order, time.Now())
+
+	if !ok {
+		return nil
+	}
+
+	// the order is saved by then; failing here would make the client create it
+	// again, so a lost evaluation only costs the explanation
+	if err := s.rules.RecordEvaluation(ctx, evaluation); err != nil {
+		s.log(ctx).Error("failed to record rule evaluation", "shopId", order.ShopID, "orderId", order.ID, "error", err)
+	}
+
+	if evaluation.Action == RuleActionSkip {
+		s.log(ctx).Info("notification skipped by rule", "shopId", order.ShopID, "orderId", order.ID,
+			"ruleId", *evaluation.RuleID, "reason", evaluation.Reason)
+	}
+
+	return &evaluation
+}
+
+// recordedEvaluation returns the evaluation stored for order, or nil if it
+// was not evaluated.
+func (s *Service) recordedEvaluation(ctx context.Context, order Order) (*RuleEvaluation, error) {
+	if s.rules == nil {
+		return nil, nil
+	}
+
+	evaluation, err := s.rules.GetEvaluation(ctx, order.ShopID, order.ID)
+
+	if errors.Is(err, ErrEvaluationNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &evaluation, nil
+}
+
+func normalizeRule(rule NotificationRule) (NotificationRule, error) {
+	var fields []FieldError
+
+	rule.Name = strings.TrimSpace(rule.Name)
+	rule.Template = strings.TrimSpace(rule.Template)
+	c := &rule.Conditions
+	c.CustomerPattern = strings.TrimSpace(c.CustomerPattern)
+	c.NumberPrefix = strings.TrimSpace(c.NumberPrefix)
+
+	if rule.Name == "" {
+		fields = append(fields, FieldError{Field: "name", Code: "required", Message: "name must be non-empty"})
+	} else if utf8.RuneCountInString(rule.Name) > maxRuleNameLength {
+		fields = append(fields, FieldError{Field: "name", Code: "max", Message: fmt.Sprintf("name must be at most %d characters", maxRuleNameLength)})
+	}
+
+	if rule.Position < 0 {
+		fields = append(fields, FieldError{Field: "position", Code: "min", Message: "position must not be negative"})
+	}
+
+	if c.MinTotal != nil && *c.MinTotal < 0 {
+		fields = append(fields, FieldError{Field: "conditions.minTotal", Code: "min", Message: "minTotal must not be negative"})
+	}
+
+	if c.MaxTotal != nil && *c.MaxTotal < 0 {
+		fields = append(fields, FieldError{Field: "conditions.maxTotal", Code: "min", Message: "maxTotal must not be negative"})
+	} else if c.MinTotal != nil && c.MaxTotal != nil && *c.MaxTotal < *c.MinTotal {
+		fields = append(fields, FieldError{Field: "conditions.maxTotal", Code: "gtefield", Message: "maxTotal must not be less than minTotal"})
+	}
+
+	if utf8.RuneCountInString(c.CustomerPattern) > maxRulePatternLength {
+		fields = append(fields, FieldError{Field: "conditions.customerPattern", Code: "max", Message: fmt.Sprintf("customerPattern must be at most %d characters", maxRulePatternLength)})
+	} else if _, err := regexp.Compile("(?i)" + c.CustomerPattern); err != nil {
+		fields = append(fields, FieldError{Field: "conditions.customerPattern", Code: "regexp", Message: "customerPattern must be a valid regular expression"})
+	}
+
+	if utf8.RuneCountInString(c.NumberPrefix) > maxRuleNumberPrefixSize {
+		fields = append(fields, FieldError{Field: "conditions.numberPrefix", Code: "max", Message: fmt.Sprintf("numberPrefix must be at most %d characters", maxRuleNumberPrefixSize)})
+	}
+
+	statuses, statusFields := normalizeStatuses("conditions.statuses", c.Statuses)
+	c.Statuses = statuses
+	fields = append(fields, statusFields...)
+
+	switch {
+	case !slices.Contains(ruleActions, rule.Action):
+		fields = append(fields, FieldError{Field: "action", Code: "oneof", Message: "action must be one of send, skip, silent, template"})
+	case rule.Action == RuleActionTemplate && !slices.Contains(messageTemplates, rule.Template):
+		fields = append(fields, FieldError{Field: "template", Code: "oneof", Message: "template must be one of " + strings.Join(messageTemplates, ", ")})
+	case rule.Action != RuleActionTemplate && rule.Template != "":
+		fields = append(fields, FieldError{Field: "template", Code: "excluded_unless", Message: "template is only allowed for the template action"})
+	}
+
+	if len(fields) > 0 {
+		return NotificationRule{}, ValidationFailed(fields)
+	}
+
+	return rule, nil
+}
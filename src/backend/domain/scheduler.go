@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Locker takes named locks shared by every API instance.
+type Locker interface {
+	// TryLock does not wait: acquired is false while another holder has the
+	// lock. When acquired, unlock must be called once the work is done.
+	TryLock(ctx context.Context, name string) (unlock func(), acquired bool, err error)
+}
+
+// Job is periodic background work. Run gets the tick time; a run that is still
+// going when the next tick comes makes that tick be skipped.
+type Job struct {
+	Name  string
+	Every time.Duration
+	Run   func(ctx context.Context, now time.Time) error
+}
+
+// Scheduler runs jobs on their intervals. Each run holds the job's lock, so
+// with several instances a job runs on one of them at a time.
+type Scheduler struct {
+	locker Locker
+	logger *slog.Logger
+	jobs   []Job
+}
+
+func NewScheduler(locker Locker, logger *slog.Logger) *Scheduler {
+	return &Scheduler{locker: locker, logger: logger}
+}
+
+func (s *Scheduler) Add(job Job) *Scheduler {
+	s.jobs = append(s.jobs, job)
+	return s
+}
+
+// Start runs every job until ctx is cancelled and waits for the runs in
+// progress to return.
+func (s *Scheduler) Start(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, job := range s.jobs {
+		wg.Go(func() {
+			ticker := time.NewTicker(job.Every)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case now := <-ticker.C:
+					s.RunJob(ctx, job, now)
+				}
+			}
+		})
+	}
+
+	wg.Wait()
+}
+
+// RunJob runs job once if no other instance is running it and reports
+// whether it ran.
+func (s *Scheduler) RunJob(ctx context.Context, job Job, now time.Time) bool {
+	logger := s.logger.With("job", job.Name)
+	unlock, acquired, err := s.locker.TryLock(ctx, "job:"+job.Name)
+
+	if err != nil {
+		logger.Error("failed to lock scheduled job", "error", err)
+		return false
+	}
+
+	if !acquired {
+		logger.Debug("scheduled job is running elsewhere")
+		return false
+	}
+
+	defer unlock()
+
+	if err := job.Run(ctx, now); err != nil {
+		logger.Error("scheduled job failed", "error", err)
+	}
+
+	return true
+}
@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// TokenFingerprint identifies a bot token without revealing it, so clients can
+// tell whether the stored token changed.
+func TokenFingerprint(token string) string {
+	if token == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(token))
+
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
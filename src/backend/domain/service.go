@@ -4,18 +4,42 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 )
 
 var (
-	ErrShopNotIntegrated = errors.New("telegram integration not found")
+	ErrShopNotIntegrated = NewError(KindNotFound, "integration_not_found", "integration not found")
+	ErrShopNotFound      = NewError(KindNotFound, "shop_not_found", "shop not found")
+	ErrInvalidInput      = NewError(KindInvalid, "invalid_input", "invalid input")
+	ErrOrderNotFound     = NewError(KindNotFound, "order_not_found", "order not found")
+	ErrNotResendable     = NewError(KindConflict, "not_resendable", "order notification is already sent or in progress")
+)
+
+const (
+	defaultImportBatchSize   = 500
+	defaultImportConcurrency = 4
 )
 
 type Service struct {
-	integrations IntegrationRepository
-	orders       OrderRepository
-	sendLogs     SendLogRepository
-	telegram     TelegramClient
+	integrations     IntegrationRepository
+	orders           OrderRepository
+	notificationLogs NotificationLogRepository
+	notifiers        *NotifierRegistry
+	webhooks         WebhookRepository
+	webhookNotifier  Notifier
+	destinations     TelegramDestinationRepository
+	rules            NotificationRuleRepository
+	shops            ShopRepository
+	digests          DigestRepository
+	audit            AuditRepository
+	metrics          Metrics
+	logger           *slog.Logger
+	// batchTimers is set by WithBatchTimers; nil leaves batches to DeliverDue.
+	batchTimers context.Context
 
 	retryMaxAttempts int
 	retryBaseDelay   time.Duration
@@ -24,26 +48,31 @@ type Service struct {
 func NewService(
 	integrations IntegrationRepository,
 	orders OrderRepository,
-	sendLogs SendLogRepository,
-	telegram TelegramClient,
+	notificationLogs NotificationLogRepository,
+	notifiers *NotifierRegistry,
 	retryMaxAttempts int,
+	opts ...ServiceOption,
 ) *Service {
 	if retryMaxAttempts <= 0 {
 		retryMaxAttempts = 3
 	}
 
-	return &Service{
+	s := &Service{
 		integrations:     integrations,
 		orders:           orders,
-		sendLogs:         sendLogs,
-		telegram:         telegram,
+		notificationLogs: notificationLogs,
+		notifiers:        notifiers,
 		retryMaxAttempts: retryMaxAttempts,
 		retryBaseDelay:   500 * time.Millisecond,
+		metrics:          NopMetrics{},
+		logger:           slog.New(slog.DiscardHandler),
 	}
-}
 
-func (s *Service) ConnectTelegram(ctx context.Context, shopID int64, input ConnectTelegramInput) (TelegramIntegration, error) {
-	return s.integrations.Upsert(ctx, shopID, input)
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
 func (s *Service) ListOrders(ctx context.Context, shopID int64, limit, offset int) (ListOrdersResult, error) {
@@ -80,96 +109,572 @@ func (s *Service) ListOrders(ctx context.Context, shopID int64, limit, offset in
 }
 
 func (s *Service) CreateOrder(ctx context.Context, shopID int64, input CreateOrderInput) (OrderSendResult, error) {
-	order, err := s.orders.Create(ctx, shopID, input)
+	status, err := normalizeOrderStatus(input.Status)
 
 	if err != nil {
 		return OrderSendResult{}, err
 	}
 
-	integration, found, err := s.integrations.GetByShopID(ctx, shopID)
+	input.Status = status
+	order, err := s.orders.Create(ctx, shopID, input)
 
 	if err != nil {
 		return OrderSendResult{}, err
 	}
 
-	if !found || !integration.Enabled {
-		return OrderSendResult{
-			Order:      order,
-			SendStatus: SendStatusSkipped,
-		}, nil
-	}
+	s.recordAudit(ctx, shopID, AuditOrderCreated, AuditEntityOrder, &order.ID, nil, orderSnapshot(order))
 
-	message := fmt.Sprintf("Новый заказ %s на сумму %.2f ₽, клиент %s", order.Number, order.Total, order.CustomerName)
-	reservedAt := time.Now()
-	reserved, err := s.sendLogs.Reserve(ctx, shopID, order.ID, message, reservedAt)
+	plan, err := s.planNotifications(ctx, shopID)
 
 	if err != nil {
 		return OrderSendResult{}, err
 	}
 
-	if !reserved {
-		return OrderSendResult{
-			Order:      order,
-			SendStatus: SendStatusSkipped,
-		}, nil
+	sendStatus, err := s.notifyOrder(ctx, plan, order)
+
+	if err != nil {
+		return OrderSendResult{}, err
 	}
 
-	go s.trySendTelegram(shopID, order.ID, integration.BotToken, integration.ChatID, message)
+	s.notifyWebhooks(ctx, order, WebhookEventOrderCreated)
 
 	return OrderSendResult{
 		Order:      order,
-		SendStatus: SendStatusPending,
+		SendStatus: sendStatus,
 	}, nil
 }
 
-func (s *Service) trySendTelegram(shopID, orderID int64, botToken, chatID, message string) {
+// ImportOrders saves the valid rows in batches and, with opts.Notify, sends
+// their notifications at most opts.Concurrency at a time. When it stops early
+// it returns the report of the rows so far with the error: saved orders are
+// imported, the rows it did not get to are failed.
+func (s *Service) ImportOrders(ctx context.Context, shopID int64, rows []ImportOrderRow, opts ImportOrdersOptions) (ImportOrdersResult, error) {
+	batchSize := opts.BatchSize
+
+	if batchSize <= 0 {
+		batchSize = defaultImportBatchSize
+	}
+
+	concurrency := opts.Concurrency
+
+	if concurrency <= 0 {
+		concurrency = defaultImportConcurrency
+	}
+
+	result := ImportOrdersResult{
+		Total: len(rows),
+		Rows:  make([]ImportRowResult, len(rows)),
+	}
+	valid := make([]int, 0, len(rows))
+
+	for i, row := range rows {
+		result.Rows[i] = ImportRowResult{Line: row.Line, Number: row.Order.Number}
+
+		if row.Err == nil {
+			rows[i].Order.Status, row.Err = normalizeOrderStatus(row.Order.Status)
+		}
+
+		if row.Err != nil {
+			errText := row.Err.Error()
+			result.Rows[i].Status = ImportRowStatusInvalid
+			result.Rows[i].Error = &errText
+			result.Invalid++
+			continue
+		}
+
+		valid = append(valid, i)
+	}
+
+	var plan notifyPlan
+
+	if opts.Notify && len(valid) > 0 {
+		current, err := s.planNotifications(ctx, shopID)
+
+		if err != nil {
+			result.failRows(valid, err)
+			return result, err
+		}
+
+		plan = current
+		plan.slots = make(chan struct{}, concurrency)
+	}
+
+	var stopErr error
+
+	for start := 0; start < len(valid) && stopErr == nil; start += batchSize {
+		indexes := valid[start:min(start+batchSize, len(valid))]
+		batch := make([]ImportOrderRow, len(indexes))
+
+		for j, idx := range indexes {
+			batch[j] = rows[idx]
+		}
+
+		orders, err := s.orders.CreateBatch(ctx, shopID, batch)
+
+		if errors.Is(err, ErrShopNotFound) {
+			result.failRows(valid[start:], err)
+			return result, err
+		}
+
+		if err != nil {
+			result.failRows(indexes, err)
+			continue
+		}
+
+		for j, idx := range indexes {
+			order := orders[j]
+			result.Rows[idx].Status = ImportRowStatusImported
+			result.Rows[idx].OrderID = &order.ID
+			result.Imported++
+
+			// the order is saved either way; after a failure the rest are not notified
+			if stopErr != nil {
+				result.Rows[idx].SendStatus = SendStatusFailed
+				continue
+			}
+
+			sendStatus, err := s.notifyOrder(ctx, plan, order)
+
+			if err != nil {
+				errText := err.Error()
+				stopErr = err
+				result.Rows[idx].SendStatus = SendStatusFailed
+				result.Rows[idx].Error = &errText
+				s.log(ctx).Error("import stopped: failed to notify about an order", "shopId", shopID, "orderId", order.ID, "error", err)
+				continue
+			}
+
+			s.notifyWebhooks(ctx, order, WebhookEventOrderImported)
+			result.Rows[idx].SendStatus = sendStatus
+		}
+
+		if stopErr != nil {
+			result.failRows(valid[start+len(indexes):], fmt.Errorf("import stopped: %w", stopErr))
+		}
+	}
+
+	if result.Imported > 0 {
+		summary := map[string]int{"total": result.Total, "imported": result.Imported, "invalid": result.Invalid, "failed": result.Failed}
+
+		s.recordAudit(ctx, shopID, AuditOrdersImported, AuditEntityOrder, nil, nil, summary)
+	}
+
+	return result, stopErr
+}
+
+// failRows marks the rows at indexes as failed with err.
+func (r *ImportOrdersResult) failRows(indexes []int, err error) {
+	errText := err.Error()
+
+	for _, idx := range indexes {
+		r.Rows[idx].Status = ImportRowStatusFailed
+		r.Rows[idx].Error = &errText
+	}
+
+	r.Failed += len(indexes)
+}
+
+var orderStatusPattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// maxMatchedStatuses limits the statuses a rule or a routing rule matches on.
+const maxMatchedStatuses = 20
+
+// normalizeOrderStatus lowercases the status; an empty one is OrderStatusNew.
+func normalizeOrderStatus(status string) (string, error) {
+	status = strings.ToLower(strings.TrimSpace(status))
+
+	if status == "" {
+		return OrderStatusNew, nil
+	}
+
+	if !orderStatusPattern.MatchString(status) {
+		return "", InvalidField("status", "pattern", "status must be up to 32 lowercase letters, digits, '_' or '-'")
+	}
+
+	return status, nil
+}
+
+// normalizeStatuses lowercases and deduplicates the statuses an order may have
+// to match; field names the list in the errors.
+func normalizeStatuses(field string, statuses []string) ([]string, []FieldError) {
+	if len(statuses) > maxMatchedStatuses {
+		return nil, []FieldError{{Field: field, Code: "max", Message: fmt.Sprintf("statuses must have at most %d items", maxMatchedStatuses)}}
+	}
+
+	out := make([]string, 0, len(statuses))
+
+	for _, status := range statuses {
+		status = strings.ToLower(strings.TrimSpace(status))
+
+		if !orderStatusPattern.MatchString(status) {
+			return nil, []FieldError{{Field: field, Code: "pattern", Message: "statuses must be up to 32 lowercase letters, digits, '_' or '-'"}}
+		}
+
+		if !slices.Contains(out, status) {
+			out = append(out, status)
+		}
+	}
+
+	return out, nil
+}
+
+// ResendOrder retries the notification on every enabled channel whose previous
+// attempt for the order failed. The message is rendered as the order's rule
+// evaluation asked; a skip rule does not stop an explicit resend.
+func (s *Service) ResendOrder(ctx context.Context, shopID, orderID int64) (OrderSendResult, error) {
+	order, err := s.orders.GetByID(ctx, shopID, orderID)
+
+	if err != nil {
+		return OrderSendResult{}, err
+	}
+
+	all, err := s.integrations.ListByShop(ctx, shopID)
+
+	if err != nil {
+		return OrderSendResult{}, err
+	}
+
+	if len(all) == 0 {
+		return OrderSendResult{}, ErrShopNotIntegrated
+	}
+
+	integrations := s.deliverable(all)
+
+	if len(integrations) == 0 {
+		return OrderSendResult{}, fmt.Errorf("%w: all integrations are disabled", ErrInvalidInput)
+	}
+
+	evaluation, err := s.recordedEvaluation(ctx, order)
+
+	if err != nil {
+		return OrderSendResult{}, err
+	}
+
+	loc, err := s.shopLocation(ctx, shopID)
+
+	if err != nil {
+		return OrderSendResult{}, err
+	}
+
+	message, silent := evaluation.apply(order)
+	retried := make([]Notification, 0, len(integrations))
+	retriedFor := make([]Integration, 0, len(integrations))
+	channels := make([]ChannelType, 0, len(integrations))
+
+	for _, integration := range integrations {
+		notifier, _ := s.notifiers.Get(integration.Channel)
+		recipients, err := s.recipients(ctx, notifier, integration, order, loc)
+
+		if err != nil {
+			return OrderSendResult{}, err
+		}
+
+		for _, recipient := range recipients {
+			entry := NotificationLog{ShopID: shopID, OrderID: orderID, IntegrationID: integration.ID, Channel: integration.Channel, Recipient: recipient, Message: message.Text}
+			logID, ok, err := s.notificationLogs.Retry(ctx, entry, time.Now())
+
+			if err != nil {
+				return OrderSendResult{}, err
+			}
+
+			if !ok {
+				continue
+			}
+
+			retried = append(retried, orderNotification(logID, order, recipient, message, silent))
+			retriedFor = append(retriedFor, integration)
+
+			if !slices.Contains(channels, integration.Channel) {
+				channels = append(channels, integration.Channel)
+			}
+		}
+	}
+
+	if len(retried) == 0 {
+		return OrderSendResult{}, ErrNotResendable
+	}
+
+	after := map[string]any{"sendStatus": SendStatusPending, "channels": channels}
+
+	s.recordAudit(ctx, shopID, AuditOrderResent, AuditEntityOrder, &order.ID, nil, after)
+
+	for i, notification := range retried {
+		notifier, _ := s.notifiers.Get(retriedFor[i].Channel)
+		s.dispatch(ctx, notifier, retriedFor[i], notification)
+	}
+
+	return OrderSendResult{Order: order, SendStatus: SendStatusPending}, nil
+}
+
+// notifyPlan is what notifyOrder needs to know about the shop. It is loaded
+// once per request, or once per import.
+type notifyPlan struct {
+	integrations []Integration
+	rules        ruleSet
+	// shop is nil without WithShops.
+	shop *Shop
+	// slots limits the sends in flight; nil does not.
+	slots chan struct{}
+}
+
+// planNotifications loads the plan; the rules and shop settings are only
+// needed when the shop has something to notify.
+func (s *Service) planNotifications(ctx context.Context, shopID int64) (notifyPlan, error) {
+	integrations, err := s.enabledIntegrations(ctx, shopID)
+
+	if err != nil || len(integrations) == 0 {
+		return notifyPlan{}, err
+	}
+
+	rules, err := s.shopRules(ctx, shopID)
+
+	if err != nil {
+		return notifyPlan{}, err
+	}
+
+	plan := notifyPlan{integrations: integrations, rules: rules}
+
+	if s.shops != nil {
+		shop, err := s.shops.GetByID(ctx, shopID)
+
+		if err != nil {
+			return notifyPlan{}, err
+		}
+
+		plan.shop = &shop
+	}
+
+	return plan, nil
+}
+
+// enabledIntegrations returns the shop's integrations that can deliver right now.
+func (s *Service) enabledIntegrations(ctx context.Context, shopID int64) ([]Integration, error) {
+	integrations, err := s.integrations.ListByShop(ctx, shopID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.deliverable(integrations), nil
+}
+
+// deliverable drops disabled integrations and those whose channel is not
+// registered in this build.
+func (s *Service) deliverable(integrations []Integration) []Integration {
+	out := make([]Integration, 0, len(integrations))
+
+	for _, integration := range integrations {
+		if !integration.Enabled {
+			continue
+		}
+
+		if _, err := s.notifiers.Get(integration.Channel); err != nil {
+			continue
+		}
+
+		out = append(out, integration)
+	}
+
+	return out
+}
+
+// notifyOrder evaluates the shop's rules, then reserves a log row per
+// integration and recipient and starts the sends, or leaves them to
+// DeliverDue during deferring quiet hours. The order is pending if at least
+// one row was reserved.
+func (s *Service) notifyOrder(ctx context.Context, plan notifyPlan, order Order) (string, error) {
+	sendStatus := SendStatusSkipped
+
+	if len(plan.integrations) == 0 {
+		return sendStatus, nil
+	}
+
+	evaluation := s.evaluateRules(ctx, plan.rules, order)
+
+	if evaluation != nil && evaluation.Action == RuleActionSkip {
+		return sendStatus, nil
+	}
+
+	now := time.Now()
+	message, silent := evaluation.apply(order)
+	deliverAfter, quiet := plan.quietHours(now)
+	silent = silent || quiet
+	deferred := 0
+
+	for _, integration := range plan.integrations {
+		notifier, err := s.notifiers.Get(integration.Channel)
+
+		if err != nil {
+			continue
+		}
+
+		recipients, err := s.recipients(ctx, notifier, integration, order, plan.location())
+
+		if err != nil {
+			return "", err
+		}
+
+		for _, recipient := range recipients {
+			due := deliverAfter
+
+			if due == nil {
+				if due, err = s.batchDeadline(ctx, notifier, integration, recipient, now); err != nil {
+					s.log(ctx).Warn("failed to find the open batch, sending the order on its own", "shopId", order.ShopID,
+						"orderId", order.ID, "channel", string(integration.Channel), "error", err)
+				}
+			}
+
+			entry := NotificationLog{ShopID: order.ShopID, OrderID: order.ID, IntegrationID: integration.ID, Channel: integration.Channel, Recipient: recipient, Message: message.Text, DeliverAfter: due}
+			logID, reserved, err := s.notificationLogs.Reserve(ctx, entry, now)
+
+			if err != nil {
+				return "", err
+			}
+
+			if !reserved {
+				continue
+			}
+
+			sendStatus = SendStatusPending
+
+			if deliverAfter != nil {
+				deferred++
+				continue
+			}
+
+			// a batched row waits for DeliverDue at the end of its window
+			if due != nil {
+				continue
+			}
+
+			s.dispatchLimited(ctx, plan.slots, notifier, integration, orderNotification(logID, order, recipient, message, silent))
+		}
+	}
+
+	if deferred > 0 {
+		s.log(ctx).Info("notifications deferred until quiet hours end", "shopId", order.ShopID, "orderId", order.ID,
+			"count", deferred, "until", deliverAfter.Format(time.RFC3339))
+	}
+
+	return sendStatus, nil
+}
+
+func orderNotification(logID int64, order Order, recipient string, message OrderMessage, silent bool) Notification {
+	return Notification{
+		ID:        logID,
+		ShopID:    order.ShopID,
+		OrderID:   order.ID,
+		Recipient: recipient,
+		Subject:   message.Subject,
+		Text:      message.Text,
+		HTML:      message.HTML,
+		Fields:    message.Fields,
+		Silent:    silent,
+	}
+}
+
+// dispatch sends notification in the background; notification.ID must be a
+// reserved notification_log row.
+func (s *Service) dispatch(ctx context.Context, notifier Notifier, integration Integration, notification Notification) {
+	s.dispatchLimited(ctx, nil, notifier, integration, notification)
+}
+
+// dispatchLimited is dispatch that holds one of slots while sending, so an
+// import does not send all its orders at once. nil slots do not limit.
+func (s *Service) dispatchLimited(ctx context.Context, slots chan struct{}, notifier Notifier, integration Integration, notification Notification) {
+	s.metrics.AddPendingNotifications(1)
+
+	go func() {
+		if slots != nil {
+			slots <- struct{}{}
+			defer func() { <-slots }()
+		}
+
+		s.deliver(context.WithoutCancel(ctx), notifier, integration, notification)
+	}()
+}
+
+// deliver runs after the request has returned. ctx must already be detached
+// from its cancellation; it still carries the request ID and trace.
+func (s *Service) deliver(ctx context.Context, notifier Notifier, integration Integration, notification Notification) {
+	defer s.metrics.AddPendingNotifications(-1)
+
+	channel := string(integration.Channel)
+	logID := notification.ID
+	logger := s.log(ctx).With("shopId", notification.ShopID, "orderId", notification.OrderID, "channel", channel)
+
+	if notification.Recipient != "" {
+		logger = logger.With("recipient", maskRecipient(notifier, notification.Recipient))
+	}
+
 	var sendErr error
 
 	for attempt := 1; attempt <= s.retryMaxAttempts; attempt++ {
-		sendErr = s.telegram.SendMessage(context.Background(), botToken, chatID, message)
+		sendErr = notifier.Send(ctx, integration, notification)
 
 		if sendErr == nil {
-			_ = s.sendLogs.Finalize(context.Background(), shopID, orderID, TelegramSendStatusSent, nil, time.Now())
+			s.metrics.ObserveDelivery(channel, DeliveryOutcomeSent, attempt)
+			logger.Info("notification sent", "attempt", attempt)
+			s.finalize(ctx, logger, logID, NotificationStatusSent, nil)
 			return
 		}
 
+		logger.Warn("notification send attempt failed", "attempt", attempt, "maxAttempts", s.retryMaxAttempts,
+			"error", RedactSecrets(sendErr.Error(), integration.Secret))
+
 		if attempt < s.retryMaxAttempts {
-			time.Sleep(s.retryBaseDelay * time.Duration(attempt))
+			time.Sleep(s.retryDelay(sendErr, attempt))
 		}
 	}
 
-	errText := sendErr.Error()
-	_ = s.sendLogs.Finalize(context.Background(), shopID, orderID, TelegramSendStatusFailed, &errText, time.Now())
-}
+	s.metrics.ObserveDelivery(channel, DeliveryOutcomeFailed, s.retryMaxAttempts)
 
-func (s *Service) GetTelegramStatus(ctx context.Context, shopID int64) (TelegramStatus, error) {
-	integration, found, err := s.integrations.GetByShopID(ctx, shopID)
+	errText := RedactSecrets(sendErr.Error(), integration.Secret)
+	logger.Error("notification delivery failed", "attempts", s.retryMaxAttempts, "error", errText)
+	s.finalize(ctx, logger, logID, NotificationStatusFailed, &errText)
+}
 
-	if err != nil {
-		return TelegramStatus{}, err
+// maskRecipient masks with the notifier's own policy; other recipients are
+// Telegram destination chats.
+func maskRecipient(notifier Notifier, recipient string) string {
+	if r, ok := notifier.(RecipientNotifier); ok {
+		return r.MaskRecipient(recipient)
 	}
 
-	if !found {
-		return TelegramStatus{
-			Enabled:      false,
-			MaskedChatID: "",
-			SentCount:    0,
-			FailedCount:  0,
-		}, nil
+	return MaskChatID(recipient)
+}
+
+// retryDelay backs off linearly, but waits at least as long as a rate-limited
+// channel asked to.
+func (s *Service) retryDelay(err error, attempt int) time.Duration {
+	delay := s.retryBaseDelay * time.Duration(attempt)
+
+	var limited *RateLimitError
+
+	if errors.As(err, &limited) {
+		delay = max(delay, min(limited.RetryAfter, maxRetryAfter))
 	}
 
-	since := time.Now().AddDate(0, 0, -7)
-	lastSentAt, sentCount, failedCount, err := s.sendLogs.GetStatusStats(ctx, shopID, since)
+	return delay
+}
 
-	if err != nil {
-		return TelegramStatus{}, err
+// wait sleeps for d, returning early with ctx's error when it is cancelled.
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
+}
 
-	return TelegramStatus{
-		Enabled:      integration.Enabled,
-		MaskedChatID: integration.ChatID,
-		LastSentAt:   lastSentAt,
-		SentCount:    sentCount,
-		FailedCount:  failedCount,
-	}, nil
+// finalize has nobody to return an error to, so a failed write is logged; the
+// row stays reserved and the order cannot be resent until it is fixed by hand.
+func (s *Service) finalize(ctx context.Context, logger *slog.Logger, logID int64, status NotificationStatus, errText *string) {
+	if err := s.notificationLogs.Finalize(ctx, logID, status, errText, time.Now()); err != nil {
+		logger.Error("failed to finalize notification log", "status", status, "error", err)
+	}
 }
@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultShopTimezone = "Europe/Moscow"
+	DefaultShopLocale   = "ru-RU"
+	DefaultShopCurrency = "RUB"
+)
+
+type ShopService struct {
+	shops ShopRepository
+}
+
+func NewShopService(shops ShopRepository) *ShopService {
+	return &ShopService{shops: shops}
+}
+
+func (s *ShopService) CreateShop(ctx context.Context, input ShopInput) (Shop, error) {
+	input, err := normalizeShopInput(input)
+
+	if err != nil {
+		return Shop{}, err
+	}
+
+	return s.shops.Create(ctx, input)
+}
+
+func (s *ShopService) GetShop(ctx context.Context, shopID int64) (Shop, error) {
+	return s.shops.GetByID(ctx, shopID)
+}
+
+func (s *ShopService) ListShops(ctx context.Context, limit, offset int) (ListShopsResult, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	if limit > 100 {
+		limit = 100
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := s.shops.List(ctx, limit+1, offset)
+
+	if err != nil {
+		return ListShopsResult{}, err
+	}
+
+	hasMore := len(rows) > limit
+
+	if hasMore {
+		rows = rows[:limit]
+	}
+
+	return ListShopsResult{
+		Items:   rows,
+		Limit:   limit,
+		Offset:  offset,
+		HasMore: hasMore,
+	}, nil
+}
+
+func (s *ShopService) UpdateShop(ctx context.Context, shopID int64, input ShopInput) (Shop, error) {
+	input, err := normalizeShopInput(input)
+
+	if err != nil {
+		return Shop{}, err
+	}
+
+	return s.shops.Update(ctx, shopID, input)
+}
+
+func (s *ShopService) DeleteShop(ctx context.Context, shopID int64) error {
+	return s.shops.Delete(ctx, shopID)
+}
+
+func normalizeShopInput(input ShopInput) (ShopInput, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	input.Timezone = strings.TrimSpace(input.Timezone)
+	input.Locale = strings.TrimSpace(input.Locale)
+	input.Currency = strings.ToUpper(strings.TrimSpace(input.Currency))
+
+	if input.Name == "" {
+		return ShopInput{}, InvalidField("name", "required", "name must be non-empty")
+	}
+
+	if input.Timezone == "" {
+		input.Timezone = DefaultShopTimezone
+	}
+
+	if input.Locale == "" {
+		input.Locale = DefaultShopLocale
+	}
+
+	if input.Currency == "" {
+		input.Currency = DefaultShopCurrency
+	}
+
+	if _, err := time.LoadLocation(input.Timezone); err != nil {
+		return ShopInput{}, InvalidField("timezone", "timezone", fmt.Sprintf("unknown timezone %q", input.Timezone))
+	}
+
+	quietHours, err := normalizeQuietHours(input.QuietHours)
+
+	if err != nil {
+		return ShopInput{}, err
+	}
+
+	input.QuietHours = quietHours
+
+	return input, nil
+}
@@ -0,0 +1,263 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrUserNotFound   = NewError(KindNotFound, "user_not_found", "user not found")
+	ErrUserExists     = NewError(KindConflict, "user_exists", "user already exists")
+	ErrMemberNotFound = NewError(KindNotFound, "member_not_found", "shop member not found")
+)
+
+const minPasswordLength = 8
+
+type User struct {
+	ID           int64     `json:"id"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+type ShopMember struct {
+	ShopID    int64     `json:"shopId"`
+	UserID    int64     `json:"userId"`
+	Email     string    `json:"email"`
+	Role      Role      `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type Session struct {
+	TokenHash []byte
+	UserID    int64
+	CSRFToken string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type AuthService struct {
+	users      UserRepository
+	sessions   SessionRepository
+	members    MemberRepository
+	sessionTTL time.Duration
+
+	// compared against when the e-mail is unknown, so login timing does not reveal which accounts exist
+	dummyHash []byte
+}
+
+func NewAuthService(users UserRepository, sessions SessionRepository, members MemberRepository, sessionTTL time.Duration) *AuthService {
+	if sessionTTL <= 0 {
+		sessionTTL = 12 * time.Hour
+	}
+
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+	return &AuthService{
+		users:      users,
+		sessions:   sessions,
+		members:    members,
+		sessionTTL: sessionTTL,
+		dummyHash:  dummyHash,
+	}
+}
+
+func (s *AuthService) CreateUser(ctx context.Context, input CreateUserInput) (User, error) {
+	email, err := normalizeEmail(input.Email)
+
+	if err != nil {
+		return User{}, err
+	}
+
+	if len(input.Password) < minPasswordLength {
+		return User{}, InvalidField("password", "min", fmt.Sprintf("password must be at least %d characters", minPasswordLength))
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+
+	if err != nil {
+		return User{}, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	return s.users.Create(ctx, email, string(hash))
+}
+
+func (s *AuthService) Login(ctx context.Context, input LoginInput) (LoginResult, string, error) {
+	email, err := normalizeEmail(input.Email)
+
+	if err != nil {
+		return LoginResult{}, "", ErrUnauthorized
+	}
+
+	user, err := s.users.GetByEmail(ctx, email)
+
+	if errors.Is(err, ErrUserNotFound) {
+		_ = bcrypt.CompareHashAndPassword(s.dummyHash, []byte(input.Password))
+		return LoginResult{}, "", ErrUnauthorized
+	}
+
+	if err != nil {
+		return LoginResult{}, "", err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password)) != nil {
+		return LoginResult{}, "", ErrUnauthorized
+	}
+
+	token, err := randomToken()
+
+	if err != nil {
+		return LoginResult{}, "", err
+	}
+
+	csrfToken, err := randomToken()
+
+	if err != nil {
+		return LoginResult{}, "", err
+	}
+
+	now := time.Now()
+	session := Session{
+		TokenHash: hashSessionToken(token),
+		UserID:    user.ID,
+		CSRFToken: csrfToken,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.sessionTTL),
+	}
+
+	if err := s.sessions.Create(ctx, session); err != nil {
+		return LoginResult{}, "", err
+	}
+
+	me, err := s.describe(ctx, user, csrfToken)
+
+	if err != nil {
+		return LoginResult{}, "", err
+	}
+
+	return LoginResult{Me: me, ExpiresAt: session.ExpiresAt}, token, nil
+}
+
+func (s *AuthService) Logout(ctx context.Context, token string) error {
+	return s.sessions.Delete(ctx, hashSessionToken(token))
+}
+
+func (s *AuthService) AuthenticateSession(ctx context.Context, token string) (Principal, error) {
+	if token == "" {
+		return Principal{}, ErrUnauthorized
+	}
+
+	session, found, err := s.sessions.Get(ctx, hashSessionToken(token))
+
+	if err != nil {
+		return Principal{}, err
+	}
+
+	if !found || time.Now().After(session.ExpiresAt) {
+		return Principal{}, ErrUnauthorized
+	}
+
+	members, err := s.members.ListByUser(ctx, session.UserID)
+
+	if err != nil {
+		return Principal{}, err
+	}
+
+	roles := make(map[int64]Role, len(members))
+
+	for _, member := range members {
+		roles[member.ShopID] = member.Role
+	}
+
+	return Principal{UserID: session.UserID, Roles: roles, CSRFToken: session.CSRFToken}, nil
+}
+
+func (s *AuthService) Me(ctx context.Context, principal Principal) (Me, error) {
+	user, err := s.users.GetByID(ctx, principal.UserID)
+
+	if err != nil {
+		return Me{}, err
+	}
+
+	return s.describe(ctx, user, principal.CSRFToken)
+}
+
+func (s *AuthService) ListMembers(ctx context.Context, shopID int64) (ListMembersResult, error) {
+	members, err := s.members.ListByShop(ctx, shopID)
+
+	if err != nil {
+		return ListMembersResult{}, err
+	}
+
+	return ListMembersResult{Items: members}, nil
+}
+
+func (s *AuthService) SetMember(ctx context.Context, shopID int64, input SetMemberInput) (ShopMember, error) {
+	if !input.Role.Valid() {
+		return ShopMember{}, InvalidField("role", "oneof", fmt.Sprintf("unknown role %q", input.Role))
+	}
+
+	email, err := normalizeEmail(input.Email)
+
+	if err != nil {
+		return ShopMember{}, err
+	}
+
+	user, err := s.users.GetByEmail(ctx, email)
+
+	if err != nil {
+		return ShopMember{}, err
+	}
+
+	return s.members.Upsert(ctx, shopID, user.ID, input.Role)
+}
+
+func (s *AuthService) RemoveMember(ctx context.Context, shopID, userID int64) error {
+	return s.members.Delete(ctx, shopID, userID)
+}
+
+func (s *AuthService) describe(ctx context.Context, user User, csrfToken string) (Me, error) {
+	members, err := s.members.ListByUser(ctx, user.ID)
+
+	if err != nil {
+		return Me{}, err
+	}
+
+	return Me{User: user, Memberships: members, CSRFToken: csrfToken}, nil
+}
+
+func normalizeEmail(raw string) (string, error) {
+	addr, err := mail.ParseAddress(strings.TrimSpace(raw))
+
+	if err != nil || addr.Name != "" {
+		return "", InvalidField("email", "email", "invalid email")
+	}
+
+	return strings.ToLower(addr.Address), nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func hashSessionToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
@@ -0,0 +1,430 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrWebhookNotFound  = NewError(KindNotFound, "webhook_not_found", "webhook not found")
+	ErrDeliveryNotFound = NewError(KindNotFound, "delivery_not_found", "webhook delivery not found")
+	ErrDeliveryInFlight = NewError(KindConflict, "delivery_in_progress", "webhook delivery is still in progress")
+	ErrWebhooksDisabled = NewError(KindNotFound, "webhooks_disabled", "webhooks are not configured")
+)
+
+const minWebhookSecretLength = 16
+
+// ChannelWebhook labels webhook rows in notification_log and delivery metrics.
+// Webhooks are not in the NotifierRegistry: a shop can have many subscriptions.
+const ChannelWebhook ChannelType = "webhook"
+
+// WebhookSettingURL is the only setting the webhook notifier reads.
+const WebhookSettingURL = "url"
+
+const (
+	WebhookEventOrderCreated  = "order.created"
+	WebhookEventOrderImported = "order.imported"
+)
+
+var WebhookEvents = []string{WebhookEventOrderCreated, WebhookEventOrderImported}
+
+// DeliveryStatusPending is reported for deliveries that are still reserved.
+const DeliveryStatusPending NotificationStatus = "PENDING"
+
+type WebhookSubscription struct {
+	ID                int64     `json:"id"`
+	ShopID            int64     `json:"shopId"`
+	URL               string    `json:"url"`
+	Secret            string    `json:"-"`
+	SecretFingerprint string    `json:"secretFingerprint"`
+	Events            []string  `json:"events"`
+	Enabled           bool      `json:"enabled"`
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+type WebhookDelivery struct {
+	ID        int64              `json:"id"`
+	WebhookID int64              `json:"webhookId"`
+	OrderID   int64              `json:"orderId"`
+	Event     string             `json:"event"`
+	Status    NotificationStatus `json:"status"`
+	Error     *string            `json:"error"`
+	Payload   json.RawMessage    `json:"payload"`
+	SentAt    time.Time          `json:"sentAt"`
+}
+
+// WebhookPayload is the body POSTed to subscribers. It is stored with the
+// delivery, so a redelivery sends exactly the same bytes.
+type WebhookPayload struct {
+	Event      string    `json:"event"`
+	ShopID     int64     `json:"shopId"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Order      Order     `json:"order"`
+}
+
+// WithWebhooks enables webhook subscriptions. notifier signs and sends the
+// payloads; it receives each subscription as an Integration so webhooks share
+// the delivery loop, retries and notification_log with the other channels.
+func WithWebhooks(webhooks WebhookRepository, notifier Notifier) ServiceOption {
+	return func(s *Service) {
+		s.webhooks = webhooks
+		s.webhookNotifier = notifier
+	}
+}
+
+func (s *Service) ListWebhooks(ctx context.Context, shopID int64) (ListWebhooksResult, error) {
+	if s.webhooks == nil {
+		return ListWebhooksResult{}, ErrWebhooksDisabled
+	}
+
+	subscriptions, err := s.webhooks.ListByShop(ctx, shopID)
+
+	if err != nil {
+		return ListWebhooksResult{}, err
+	}
+
+	items := make([]WebhookSubscription, 0, len(subscriptions))
+
+	for _, subscription := range subscriptions {
+		items = append(items, maskWebhook(subscription))
+	}
+
+	return ListWebhooksResult{Items: items}, nil
+}
+
+func (s *Service) GetWebhook(ctx context.Context, shopID, webhookID int64) (WebhookSubscription, error) {
+	if s.webhooks == nil {
+		return WebhookSubscription{}, ErrWebhooksDisabled
+	}
+
+	subscription, err := s.webhooks.Get(ctx, shopID, webhookID)
+
+	if err != nil {
+		return WebhookSubscription{}, err
+	}
+
+	return maskWebhook(subscription), nil
+}
+
+func (s *Service) CreateWebhook(ctx context.Context, shopID int64, input WebhookInput) (WebhookSubscription, error) {
+	if s.webhooks == nil {
+		return WebhookSubscription{}, ErrWebhooksDisabled
+	}
+
+	target, secret, err := normalizeWebhookTarget(input.URL, input.Secret)
+
+	if err != nil {
+		return WebhookSubscription{}, err
+	}
+
+	events, err := normalizeWebhookEvents(input.Events)
+
+	if err != nil {
+		return WebhookSubscription{}, err
+	}
+
+	input.URL, input.Secret, input.Events = target, secret, events
+	subscription, err := s.webhooks.Create(ctx, shopID, input)
+
+	if err != nil {
+		return WebhookSubscription{}, err
+	}
+
+	s.recordAudit(ctx, shopID, AuditWebhookCreated, AuditEntityWebhook, &subscription.ID, nil, s.webhookSnapshot(subscription))
+
+	return maskWebhook(subscription), nil
+}
+
+func (s *Service) UpdateWebhook(ctx context.Context, shopID, webhookID int64, input UpdateWebhookInput) (WebhookSubscription, error) {
+	if s.webhooks == nil {
+		return WebhookSubscription{}, ErrWebhooksDisabled
+	}
+
+	if input.URL == nil && input.Secret == nil && input.Events == nil && input.Enabled == nil {
+		return WebhookSubscription{}, fmt.Errorf("%w: nothing to update", ErrInvalidInput)
+	}
+
+	before, err := s.webhooks.Get(ctx, shopID, webhookID)
+
+	if err != nil {
+		return WebhookSubscription{}, err
+	}
+
+	if input.URL != nil || input.Secret != nil {
+		target, secret := before.URL, before.Secret
+
+		if input.URL != nil {
+			target = *input.URL
+		}
+
+		if input.Secret != nil {
+			secret = *input.Secret
+		}
+
+		target, secret, err = normalizeWebhookTarget(target, secret)
+
+		if err != nil {
+			return WebhookSubscription{}, err
+		}
+
+		input.URL = &target
+
+		if input.Secret != nil {
+			input.Secret = &secret
+		}
+	}
+
+	if input.Events != nil {
+		if input.Events, err = normalizeWebhookEvents(input.Events); err != nil {
+			return WebhookSubscription{}, err
+		}
+	}
+
+	subscription, err := s.webhooks.Update(ctx, shopID, webhookID, input)
+
+	if err != nil {
+		return WebhookSubscription{}, err
+	}
+
+	s.recordAudit(ctx, shopID, AuditWebhookUpdated, AuditEntityWebhook, &subscription.ID,
+		s.webhookSnapshot(before), s.webhookSnapshot(subscription))
+
+	return maskWebhook(subscription), nil
+}
+
+func (s *Service) DeleteWebhook(ctx context.Context, shopID, webhookID int64) error {
+	if s.webhooks == nil {
+		return ErrWebhooksDisabled
+	}
+
+	before, err := s.webhooks.Get(ctx, shopID, webhookID)
+
+	if err != nil {
+		return err
+	}
+
+	if err := s.webhooks.Delete(ctx, shopID, webhookID); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, shopID, AuditWebhookDeleted, AuditEntityWebhook, &before.ID, s.webhookSnapshot(before), nil)
+
+	return nil
+}
+
+func (s *Service) ListWebhookDeliveries(ctx context.Context, shopID, webhookID int64, limit, offset int) (ListWebhookDeliveriesResult, error) {
+	if s.webhooks == nil {
+		return ListWebhookDeliveriesResult{}, ErrWebhooksDisabled
+	}
+
+	if limit <= 0 {
+		limit = 20
+	}
+
+	if limit > 100 {
+		limit = 100
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	if _, err := s.webhooks.Get(ctx, shopID, webhookID); err != nil {
+		return ListWebhookDeliveriesResult{}, err
+	}
+
+	rows, err := s.notificationLogs.ListWebhookDeliveries(ctx, webhookID, limit+1, offset)
+
+	if err != nil {
+		return ListWebhookDeliveriesResult{}, err
+	}
+
+	hasMore := len(rows) > limit
+
+	if hasMore {
+		rows = rows[:limit]
+	}
+
+	return ListWebhookDeliveriesResult{
+		Items:   rows,
+		Limit:   limit,
+		Offset:  offset,
+		HasMore: hasMore,
+	}, nil
+}
+
+// RedeliverWebhook sends a finished delivery again with its original payload,
+// whether it failed or succeeded; the signature gets a fresh timestamp.
+func (s *Service) RedeliverWebhook(ctx context.Context, shopID, webhookID, deliveryID int64) (WebhookDelivery, error) {
+	if s.webhooks == nil {
+		return WebhookDelivery{}, ErrWebhooksDisabled
+	}
+
+	subscription, err := s.webhooks.Get(ctx, shopID, webhookID)
+
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+
+	entry, reserved, err := s.notificationLogs.Redeliver(ctx, webhookID, deliveryID, time.Now())
+
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+
+	if !reserved {
+		return WebhookDelivery{}, ErrDeliveryInFlight
+	}
+
+	after := map[string]any{"deliveryId": entry.ID, "event": entry.Event}
+
+	s.recordAudit(ctx, shopID, AuditWebhookRedelivered, AuditEntityWebhook, &subscription.ID, nil, after)
+
+	s.dispatchWebhook(ctx, subscription, entry)
+
+	return WebhookDelivery{
+		ID:        entry.ID,
+		WebhookID: webhookID,
+		OrderID:   entry.OrderID,
+		Event:     entry.Event,
+		Status:    DeliveryStatusPending,
+		Payload:   json.RawMessage(entry.Message),
+		SentAt:    entry.SentAt,
+	}, nil
+}
+
+// notifyWebhooks reserves one delivery per enabled subscription to the event.
+// Webhooks do not affect the order's sendStatus. The order is saved by then,
+// so failures are logged per subscription instead of failing the request: a
+// retry would create the order again.
+func (s *Service) notifyWebhooks(ctx context.Context, order Order, event string) {
+	if s.webhooks == nil {
+		return
+	}
+
+	logger := s.log(ctx).With("shopId", order.ShopID, "orderId", order.ID, "event", event)
+	subscriptions, err := s.webhooks.ListByShop(ctx, order.ShopID)
+
+	if err != nil {
+		logger.Error("failed to load webhook subscriptions", "error", err)
+		return
+	}
+
+	var payload []byte
+
+	for _, subscription := range subscriptions {
+		if !subscription.Enabled || !slices.Contains(subscription.Events, event) {
+			continue
+		}
+
+		if payload == nil {
+			payload, err = json.Marshal(WebhookPayload{Event: event, ShopID: order.ShopID, OccurredAt: time.Now().UTC(), Order: order})
+
+			if err != nil {
+				logger.Error("failed to encode webhook payload", "error", err)
+				return
+			}
+		}
+
+		entry := NotificationLog{
+			ShopID:         order.ShopID,
+			OrderID:        order.ID,
+			SubscriptionID: subscription.ID,
+			Channel:        ChannelWebhook,
+			Event:          event,
+			Message:        string(payload),
+		}
+		logID, reserved, err := s.notificationLogs.Reserve(ctx, entry, time.Now())
+
+		if err != nil {
+			logger.Error("failed to queue webhook delivery", "webhookId", subscription.ID, "error", err)
+			continue
+		}
+
+		if !reserved {
+			continue
+		}
+
+		entry.ID = logID
+		s.dispatchWebhook(ctx, subscription, entry)
+	}
+}
+
+func (s *Service) dispatchWebhook(ctx context.Context, subscription WebhookSubscription, entry NotificationLog) {
+	integration := Integration{
+		ID:       subscription.ID,
+		ShopID:   subscription.ShopID,
+		Channel:  ChannelWebhook,
+		Settings: map[string]string{WebhookSettingURL: subscription.URL},
+		Secret:   subscription.Secret,
+		Enabled:  subscription.Enabled,
+	}
+	notification := Notification{ID: entry.ID, ShopID: entry.ShopID, OrderID: entry.OrderID, Event: entry.Event, Text: entry.Message}
+
+	s.dispatch(ctx, s.webhookNotifier, integration, notification)
+}
+
+// NormalizeWebhookURL trims rawURL and requires an absolute https URL; the
+// payload and its signature must not travel in cleartext.
+func NormalizeWebhookURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	parsed, err := url.Parse(rawURL)
+
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return "", InvalidField("url", "url", "url must be an absolute https URL")
+	}
+
+	return rawURL, nil
+}
+
+func normalizeWebhookTarget(rawURL, secret string) (string, string, error) {
+	rawURL, err := NormalizeWebhookURL(rawURL)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	secret = strings.TrimSpace(secret)
+
+	if len(secret) < minWebhookSecretLength {
+		return "", "", InvalidField("secret", "min", fmt.Sprintf("secret must be at least %d characters", minWebhookSecretLength))
+	}
+
+	return rawURL, secret, nil
+}
+
+func normalizeWebhookEvents(events []string) ([]string, error) {
+	out := make([]string, 0, len(events))
+
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+
+		if !slices.Contains(WebhookEvents, event) {
+			return nil, InvalidField("events", "oneof", fmt.Sprintf("unknown event %q", event))
+		}
+
+		if !slices.Contains(out, event) {
+			out = append(out, event)
+		}
+	}
+
+	if len(out) == 0 {
+		return nil, InvalidField("events", "required", "at least one event is required")
+	}
+
+	return out, nil
+}
+
+func maskWebhook(subscription WebhookSubscription) WebhookSubscription {
+	subscription.URL = MaskURL(subscription.URL)
+	subscription.SecretFingerprint = TokenFingerprint(subscription.Secret)
+	subscription.Secret = ""
+
+	return subscription
+}
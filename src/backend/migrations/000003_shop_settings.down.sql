@@ -0,0 +1,6 @@
+ALTER TABLE shops
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS currency,
+    DROP COLUMN IF EXISTS locale,
+    DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,9 @@
+ALTER TABLE shops
+    ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'Europe/Moscow',
+    ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT 'ru-RU',
+    ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT 'RUB',
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- the seed inserts shop 1 with an explicit id, so the sequence has to catch up
+SELECT setval('shops_id_seq', GREATEST((SELECT MAX(id) FROM shops), 1));
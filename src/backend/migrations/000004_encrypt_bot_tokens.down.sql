@@ -0,0 +1,11 @@
+-- encrypted tokens cannot be decrypted in SQL, so rows without a plaintext token are dropped
+DELETE FROM telegram_integrations WHERE bot_token IS NULL;
+
+DROP INDEX IF EXISTS idx_telegram_integrations_key_id;
+
+ALTER TABLE telegram_integrations
+    DROP CONSTRAINT IF EXISTS telegram_integrations_bot_token_present,
+    DROP COLUMN IF EXISTS bot_token_key_id,
+    DROP COLUMN IF EXISTS bot_token_dek,
+    DROP COLUMN IF EXISTS bot_token_ciphertext,
+    ALTER COLUMN bot_token SET NOT NULL;
@@ -0,0 +1,11 @@
+ALTER TABLE telegram_integrations
+    ADD COLUMN IF NOT EXISTS bot_token_ciphertext BYTEA NULL,
+    ADD COLUMN IF NOT EXISTS bot_token_dek BYTEA NULL,
+    ADD COLUMN IF NOT EXISTS bot_token_key_id TEXT NULL,
+    ALTER COLUMN bot_token DROP NOT NULL;
+
+ALTER TABLE telegram_integrations
+    ADD CONSTRAINT telegram_integrations_bot_token_present
+    CHECK (bot_token IS NOT NULL OR (bot_token_ciphertext IS NOT NULL AND bot_token_dek IS NOT NULL AND bot_token_key_id IS NOT NULL));
+
+CREATE INDEX IF NOT EXISTS idx_telegram_integrations_key_id ON telegram_integrations(bot_token_key_id);
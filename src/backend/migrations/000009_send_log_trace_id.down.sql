@@ -0,0 +1 @@
+ALTER TABLE telegram_send_log DROP COLUMN IF EXISTS trace_id;
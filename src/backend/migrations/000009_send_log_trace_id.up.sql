@@ -0,0 +1 @@
+ALTER TABLE telegram_send_log ADD COLUMN IF NOT EXISTS trace_id TEXT;
@@ -0,0 +1,52 @@
+CREATE TYPE telegram_send_status AS ENUM ('SENT', 'FAILED');
+
+CREATE TABLE telegram_integrations (
+    id BIGSERIAL PRIMARY KEY,
+    shop_id BIGINT NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    bot_token TEXT NULL,
+    chat_id TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    bot_token_ciphertext BYTEA NULL,
+    bot_token_dek BYTEA NULL,
+    bot_token_key_id TEXT NULL,
+    UNIQUE (shop_id),
+    CONSTRAINT telegram_integrations_bot_token_present
+        CHECK (bot_token IS NOT NULL OR (bot_token_ciphertext IS NOT NULL AND bot_token_dek IS NOT NULL AND bot_token_key_id IS NOT NULL))
+);
+
+CREATE INDEX idx_telegram_integrations_key_id ON telegram_integrations(bot_token_key_id);
+
+CREATE TABLE telegram_send_log (
+    id BIGSERIAL PRIMARY KEY,
+    shop_id BIGINT NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    message TEXT NOT NULL,
+    status telegram_send_status NOT NULL,
+    error TEXT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    trace_id TEXT NULL,
+    UNIQUE (shop_id, order_id)
+);
+
+CREATE INDEX idx_telegram_send_log_shop_sent_at ON telegram_send_log(shop_id, sent_at DESC);
+
+-- only Telegram rows can be represented in the old schema; other channels are dropped
+INSERT INTO telegram_integrations (id, shop_id, bot_token, chat_id, enabled, created_at, updated_at, bot_token_ciphertext, bot_token_dek, bot_token_key_id)
+SELECT id, shop_id, secret, COALESCE(settings->>'chatId', ''), enabled, created_at, updated_at, secret_ciphertext, secret_dek, secret_key_id
+FROM integrations
+WHERE channel = 'telegram';
+
+SELECT setval(pg_get_serial_sequence('telegram_integrations', 'id'), GREATEST((SELECT MAX(id) FROM telegram_integrations), 1));
+
+INSERT INTO telegram_send_log (id, shop_id, order_id, message, status, error, sent_at, trace_id)
+SELECT DISTINCT ON (shop_id, order_id) id, shop_id, order_id, message, status::telegram_send_status, error, sent_at, trace_id
+FROM notification_log
+WHERE channel = 'telegram'
+ORDER BY shop_id, order_id, sent_at DESC;
+
+SELECT setval(pg_get_serial_sequence('telegram_send_log', 'id'), GREATEST((SELECT MAX(id) FROM telegram_send_log), 1));
+
+DROP TABLE IF EXISTS notification_log;
+DROP TABLE IF EXISTS integrations;
@@ -0,0 +1,50 @@
+CREATE TABLE IF NOT EXISTS integrations (
+    id BIGSERIAL PRIMARY KEY,
+    shop_id BIGINT NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    channel TEXT NOT NULL,
+    settings JSONB NOT NULL DEFAULT '{}'::jsonb,
+    secret TEXT NULL,
+    secret_ciphertext BYTEA NULL,
+    secret_dek BYTEA NULL,
+    secret_key_id TEXT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (shop_id, channel)
+);
+
+CREATE INDEX IF NOT EXISTS idx_integrations_key_id ON integrations(secret_key_id);
+
+CREATE TABLE IF NOT EXISTS notification_log (
+    id BIGSERIAL PRIMARY KEY,
+    shop_id BIGINT NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    integration_id BIGINT NULL REFERENCES integrations(id) ON DELETE SET NULL,
+    channel TEXT NOT NULL,
+    message TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('SENT', 'FAILED')),
+    error TEXT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    trace_id TEXT NULL
+);
+
+-- history of a disconnected integration keeps integration_id NULL and no longer conflicts
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_log_integration_order ON notification_log(integration_id, order_id);
+CREATE INDEX IF NOT EXISTS idx_notification_log_shop_channel_sent_at ON notification_log(shop_id, channel, sent_at DESC);
+
+INSERT INTO integrations (id, shop_id, channel, settings, secret, secret_ciphertext, secret_dek, secret_key_id, enabled, created_at, updated_at)
+SELECT id, shop_id, 'telegram', jsonb_build_object('chatId', chat_id), bot_token, bot_token_ciphertext, bot_token_dek, bot_token_key_id, enabled, created_at, updated_at
+FROM telegram_integrations;
+
+SELECT setval(pg_get_serial_sequence('integrations', 'id'), GREATEST((SELECT MAX(id) FROM integrations), 1));
+
+INSERT INTO notification_log (id, shop_id, order_id, integration_id, channel, message, status, error, sent_at, trace_id)
+SELECT l.id, l.shop_id, l.order_id, i.id, 'telegram', l.message, l.status::text, l.error, l.sent_at, l.trace_id
+FROM telegram_send_log l
+LEFT JOIN telegram_integrations i ON i.shop_id = l.shop_id;
+
+SELECT setval(pg_get_serial_sequence('notification_log', 'id'), GREATEST((SELECT MAX(id) FROM notification_log), 1));
+
+DROP TABLE IF EXISTS telegram_send_log;
+DROP TABLE IF EXISTS telegram_integrations;
+DROP TYPE IF EXISTS telegram_send_status;
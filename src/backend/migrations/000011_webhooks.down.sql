@@ -0,0 +1,10 @@
+DELETE FROM notification_log WHERE channel = 'webhook';
+
+DROP INDEX IF EXISTS idx_notification_log_webhook_sent_at;
+DROP INDEX IF EXISTS idx_notification_log_webhook_order_event;
+
+ALTER TABLE notification_log
+    DROP COLUMN IF EXISTS event,
+    DROP COLUMN IF EXISTS webhook_subscription_id;
+
+DROP TABLE IF EXISTS webhook_subscriptions;
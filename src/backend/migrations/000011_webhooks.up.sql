@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    shop_id BIGINT NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT NULL,
+    secret_ciphertext BYTEA NULL,
+    secret_dek BYTEA NULL,
+    secret_key_id TEXT NULL,
+    events TEXT[] NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_shop_id ON webhook_subscriptions(shop_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_key_id ON webhook_subscriptions(secret_key_id);
+
+-- webhook deliveries live in notification_log next to integration sends; a row
+-- has either integration_id or webhook_subscription_id
+ALTER TABLE notification_log
+    ADD COLUMN IF NOT EXISTS webhook_subscription_id BIGINT NULL REFERENCES webhook_subscriptions(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS event TEXT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_log_webhook_order_event ON notification_log(webhook_subscription_id, order_id, event);
+CREATE INDEX IF NOT EXISTS idx_notification_log_webhook_sent_at ON notification_log(webhook_subscription_id, sent_at DESC);
@@ -0,0 +1,9 @@
+-- keep the first row per integration and order so the old unique index can be rebuilt
+DELETE FROM notification_log a
+USING notification_log b
+WHERE a.integration_id = b.integration_id AND a.order_id = b.order_id AND a.id > b.id;
+
+DROP INDEX IF EXISTS idx_notification_log_integration_order_recipient;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_log_integration_order ON notification_log(integration_id, order_id);
+
+ALTER TABLE notification_log DROP COLUMN IF EXISTS recipient;
@@ -0,0 +1,6 @@
+-- channels such as email send to several recipients per integration and keep a
+-- row for each; '' stands for the integration as a whole
+ALTER TABLE notification_log ADD COLUMN IF NOT EXISTS recipient TEXT NOT NULL DEFAULT '';
+
+DROP INDEX IF EXISTS idx_notification_log_integration_order;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_log_integration_order_recipient ON notification_log(integration_id, order_id, recipient);
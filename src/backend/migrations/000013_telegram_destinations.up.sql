@@ -0,0 +1,16 @@
+-- extra chats of a shop's Telegram integration; their sends are notification_log
+-- rows of the integration with the chat ID as recipient
+CREATE TABLE IF NOT EXISTS telegram_destinations (
+    id BIGSERIAL PRIMARY KEY,
+    shop_id BIGINT NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    integration_id BIGINT NOT NULL REFERENCES integrations(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    chat_id TEXT NOT NULL,
+    rule JSONB NOT NULL DEFAULT '{}'::jsonb,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (integration_id, chat_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_telegram_destinations_shop_id ON telegram_destinations(shop_id);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS notification_rule_evaluations;
+DROP TABLE IF EXISTS notification_rules;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS status;
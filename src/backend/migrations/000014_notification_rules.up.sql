@@ -0,0 +1,31 @@
+-- the shop's own order status, e.g. paid or shipped; notification rules can match on it
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'new';
+
+-- per-shop rules evaluated before an order's notifications are reserved
+CREATE TABLE IF NOT EXISTS notification_rules (
+    id BIGSERIAL PRIMARY KEY,
+    shop_id BIGINT NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    name TEXT NOT NULL,
+    conditions JSONB NOT NULL DEFAULT '{}'::jsonb,
+    action TEXT NOT NULL,
+    template TEXT NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_rules_shop_id ON notification_rules(shop_id, position, id);
+
+-- the latest evaluation of each order; rule_name survives the rule's deletion
+CREATE TABLE IF NOT EXISTS notification_rule_evaluations (
+    order_id BIGINT PRIMARY KEY REFERENCES orders(id) ON DELETE CASCADE,
+    shop_id BIGINT NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    rule_id BIGINT REFERENCES notification_rules(id) ON DELETE SET NULL,
+    rule_name TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL,
+    template TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL,
+    evaluated_at TIMESTAMPTZ NOT NULL
+);
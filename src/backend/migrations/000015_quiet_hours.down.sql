@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_notification_log_deliver_after;
+
+ALTER TABLE notification_log
+    DROP COLUMN IF EXISTS deliver_after;
+
+ALTER TABLE shops
+    DROP COLUMN IF EXISTS quiet_hours;
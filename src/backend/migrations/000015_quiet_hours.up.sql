@@ -0,0 +1,10 @@
+-- quiet hours are {"from":"23:00","to":"08:00","mode":"defer|silent"} in the shop's time zone
+ALTER TABLE shops
+    ADD COLUMN IF NOT EXISTS quiet_hours JSONB;
+
+-- a reserved row with deliver_after waits for the shop's quiet hours to end
+ALTER TABLE notification_log
+    ADD COLUMN IF NOT EXISTS deliver_after TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_notification_log_deliver_after ON notification_log(deliver_after)
+    WHERE deliver_after IS NOT NULL;
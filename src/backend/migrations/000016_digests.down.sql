@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_orders_shop_created_at;
+DROP TABLE IF EXISTS digest_schedules;
@@ -0,0 +1,16 @@
+-- one digest schedule per shop; time is "HH:MM" and weekday 1 (Monday) to 7 in the shop's time zone
+CREATE TABLE IF NOT EXISTS digest_schedules (
+    shop_id BIGINT PRIMARY KEY REFERENCES shops(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    frequency TEXT NOT NULL,
+    time TEXT NOT NULL,
+    weekday INTEGER NOT NULL DEFAULT 1,
+    sections JSONB NOT NULL DEFAULT '[]'::jsonb,
+    top_customers INTEGER NOT NULL DEFAULT 3,
+    last_period_end TIMESTAMPTZ NULL,
+    last_sent_at TIMESTAMPTZ NULL,
+    last_error TEXT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_shop_created_at ON orders(shop_id, created_at);
@@ -0,0 +1,5 @@
+ALTER TABLE notification_log
+    DROP COLUMN IF EXISTS message_id;
+
+ALTER TABLE integrations
+    DROP COLUMN IF EXISTS batch_window_seconds;
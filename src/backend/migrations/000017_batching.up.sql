@@ -0,0 +1,7 @@
+-- orders created within batch_window_seconds are merged into one message; 0 sends each on its own
+ALTER TABLE integrations
+    ADD COLUMN IF NOT EXISTS batch_window_seconds INT NOT NULL DEFAULT 0;
+
+-- rows merged into one message share its channel message ID
+ALTER TABLE notification_log
+    ADD COLUMN IF NOT EXISTS message_id TEXT;
@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/domain"
+)
+
+type MockAPIKeyRepo struct {
+	keys   []domain.APIKey
+	hashes map[string]int
+}
+
+func NewMockAPIKeyRepo() *MockAPIKeyRepo {
+	return &MockAPIKeyRepo{hashes: map[string]int{}}
+}
+
+func (f *MockAPIKeyRepo) Create(_ context.Context, key domain.NewAPIKey) (domain.APIKey, error) {
+	out := domain.APIKey{
+		ID:        int64(len(f.keys) + 1),
+		ShopID:    key.ShopID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		CreatedAt: time.Now(),
+	}
+	f.hashes[string(key.Hash)] = len(f.keys)
+	f.keys = append(f.keys, out)
+	return out, nil
+}
+
+func (f *MockAPIKeyRepo) List(_ context.Context, shopID int64) ([]domain.APIKey, error) {
+	out := []domain.APIKey{}
+	for _, key := range f.keys {
+		if key.ShopID == shopID {
+			out = append(out, key)
+		}
+	}
+	return out, nil
+}
+
+func (f *MockAPIKeyRepo) GetByHash(_ context.Context, hash []byte) (domain.APIKey, bool, error) {
+	idx, ok := f.hashes[string(hash)]
+	if !ok {
+		return domain.APIKey{}, false, nil
+	}
+	return f.keys[idx], true, nil
+}
+
+func (f *MockAPIKeyRepo) Revoke(_ context.Context, shopID, keyID int64, revokedAt time.Time) error {
+	for i, key := range f.keys {
+		if key.ShopID == shopID && key.ID == keyID {
+			f.keys[i].RevokedAt = &revokedAt
+			return nil
+		}
+	}
+	return domain.ErrAPIKeyNotFound
+}
+
+func (f *MockAPIKeyRepo) TouchLastUsed(_ context.Context, keyID int64, usedAt time.Time) error {
+	f.keys[keyID-1].LastUsedAt = &usedAt
+	return nil
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	repo := NewMockAPIKeyRepo()
+	svc := domain.NewAPIKeyService(repo, "")
+
+	created, err := svc.CreateKey(context.Background(), 1, domain.CreateAPIKeyInput{
+		Name:   "crm",
+		Scopes: []domain.Scope{domain.ScopeOrdersWrite, domain.ScopeOrdersWrite},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(created.Key, created.Prefix) || len(created.Scopes) != 1 {
+		t.Fatalf("unexpected created key: %+v", created)
+	}
+
+	principal, err := svc.Authenticate(context.Background(), created.Key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !principal.Can(1, domain.ScopeOrdersWrite) {
+		t.Fatal("expected key to be allowed to write orders of shop 1")
+	}
+	if principal.Can(2, domain.ScopeOrdersWrite) || principal.Can(1, domain.ScopeOrdersRead) {
+		t.Fatal("expected key to be limited to its shop and scopes")
+	}
+	if repo.keys[0].LastUsedAt == nil {
+		t.Fatal("expected last_used_at to be recorded")
+	}
+
+	if err := svc.RevokeKey(context.Background(), 1, created.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.Authenticate(context.Background(), created.Key); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Fatalf("expected revoked key to be rejected, got %v", err)
+	}
+}
+
+func TestCreateAPIKeyRejectsUnknownScope(t *testing.T) {
+	svc := domain.NewAPIKeyService(NewMockAPIKeyRepo(), "")
+
+	_, err := svc.CreateKey(context.Background(), 1, domain.CreateAPIKeyInput{Name: "crm", Scopes: []domain.Scope{"orders:delete"}})
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput, got %v", err)
+	}
+}
+
+func TestRoutesAuthorizeAgainstShopID(t *testing.T) {
+	keys := domain.NewAPIKeyService(NewMockAPIKeyRepo(), "admin-secret")
+	router := newTestRouter(t, nil, keys, newAuthService())
+
+	reader, err := keys.CreateKey(context.Background(), 1, domain.CreateAPIKeyInput{Name: "reader", Scopes: []domain.Scope{domain.ScopeOrdersRead}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		want   int
+	}{
+		{"no key", http.MethodGet, "/shops/1/orders", "", http.StatusUnauthorized},
+		{"unknown key", http.MethodGet, "/shops/1/orders", "gmk_unknown", http.StatusUnauthorized},
+		{"own shop with scope", http.MethodGet, "/shops/1/orders", reader.Key, http.StatusOK},
+		{"other shop", http.MethodGet, "/shops/2/orders", reader.Key, http.StatusForbidden},
+		{"missing scope", http.MethodGet, "/shops/1/telegram/status", reader.Key, http.StatusForbidden},
+		{"admin only route", http.MethodGet, "/shops", reader.Key, http.StatusForbidden},
+		{"admin key", http.MethodGet, "/shops/2/orders", "admin-secret", http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.key != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.key)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.want {
+				t.Fatalf("expected %d, got %d: %s", tc.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/domain"
+)
+
+type MockAuditRepo struct {
+	mu     sync.Mutex
+	events []domain.AuditEvent
+	err    error
+}
+
+func (f *MockAuditRepo) Append(_ context.Context, event domain.AuditEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return f.err
+	}
+	event.ID = int64(len(f.events) + 1)
+	event.CreatedAt = time.Now()
+	f.events = append(f.events, event)
+	return nil
+}
+
+func (f *MockAuditRepo) List(_ context.Context, shopID int64, limit, offset int) ([]domain.AuditEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := []domain.AuditEvent{}
+	for i := len(f.events) - 1; i >= 0; i-- {
+		if f.events[i].ShopID == shopID {
+			out = append(out, f.events[i])
+		}
+	}
+	if offset >= len(out) {
+		return []domain.AuditEvent{}, nil
+	}
+	return out[offset:min(offset+limit, len(out))], nil
+}
+
+func TestAuditFailureDoesNotFailAppliedChange(t *testing.T) {
+	orders := &MockOrderRepo{}
+	audit := &MockAuditRepo{err: errors.New("audit table is unavailable")}
+	svc := domain.NewService(&MockIntegrationRepo{}, orders, NewMockNotificationLogRepo(), telegramNotifiers(&MockTelegramClient{}), 3, domain.WithAuditLog(audit))
+
+	// the order is saved before the audit row, so a 500 would make the client create it twice
+	out, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{Number: "A-1", Total: 100, CustomerName: "Anna"})
+	if err != nil {
+		t.Fatalf("expected the created order despite the audit failure, got %v", err)
+	}
+	if out.Order.ID == 0 {
+		t.Fatalf("expected the saved order, got %+v", out.Order)
+	}
+}
+
+func TestTelegramChangesAreAudited(t *testing.T) {
+	audit := &MockAuditRepo{}
+	svc := domain.NewService(&MockIntegrationRepo{}, &MockOrderRepo{}, NewMockNotificationLogRepo(), telegramNotifiers(&MockTelegramClient{}), 3, domain.WithAuditLog(audit))
+
+	ctx := domain.WithPrincipal(context.Background(), domain.Principal{UserID: 7, Roles: map[int64]domain.Role{1: domain.RoleOwner}})
+	ctx = domain.WithRequestID(ctx, "req-1")
+	token := "123456:SECRET-TOKEN"
+
+	if _, err := svc.ConnectTelegram(ctx, 1, domain.ConnectTelegramInput{BotToken: token, ChatID: "-1001234567890", Enabled: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	disabled := false
+	if _, err := svc.UpdateTelegram(ctx, 1, domain.UpdateTelegramInput{Enabled: &disabled}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.DisconnectTelegram(ctx, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out, err := svc.ListAuditEvents(context.Background(), 1, 10, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantActions := []string{"telegram.disconnected", "telegram.updated", "telegram.connected"}
+	if len(out.Items) != len(wantActions) {
+		t.Fatalf("expected %d events, got %+v", len(wantActions), out.Items)
+	}
+
+	for i, event := range out.Items {
+		if event.Action != wantActions[i] || event.Actor != "user:7" || event.RequestID != "req-1" {
+			t.Fatalf("unexpected event %d: %+v", i, event)
+		}
+		if strings.Contains(string(event.Before)+string(event.After), "SECRET") {
+			t.Fatalf("bot token leaked into audit event: %s %s", event.Before, event.After)
+		}
+	}
+
+	updated := out.Items[1]
+	if !strings.Contains(string(updated.Before), `"enabled":true`) || !strings.Contains(string(updated.After), `"enabled":false`) {
+		t.Fatalf("expected before/after enabled values, got %s -> %s", updated.Before, updated.After)
+	}
+	if out.Items[2].Before != nil || out.Items[0].After != nil {
+		t.Fatalf("connect should have no before and disconnect no after: %+v", out.Items)
+	}
+}
+
+func TestResendOrderOnlyRetriesFailedNotifications(t *testing.T) {
+	audit := &MockAuditRepo{}
+	sendLogs := NewMockNotificationLogRepo()
+	client := &MockTelegramClient{errs: []error{errors.New("chat not found")}}
+	integrations := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "token", "-100", true)}}
+	svc := domain.NewService(integrations, &MockOrderRepo{}, sendLogs, telegramNotifiers(client), 1, domain.WithAuditLog(audit))
+
+	created, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{Number: "A-1", Total: 10, CustomerName: "Anna"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := svc.ResendOrder(context.Background(), 1, created.Order.ID); !errors.Is(err, domain.ErrNotResendable) {
+		t.Fatalf("expected ErrNotResendable while the first attempt is in flight, got %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		sendLogs.mu.Lock()
+		failed := sendLogs.logs[key(1, created.Order.ID)].Error != nil
+		sendLogs.mu.Unlock()
+		if failed {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	out, err := svc.ResendOrder(context.Background(), 1, created.Order.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.SendStatus != domain.SendStatusPending {
+		t.Fatalf("expected pending resend, got %s", out.SendStatus)
+	}
+
+	waitForLogStatus(t, sendLogs, 1, created.Order.ID, domain.NotificationStatusSent, time.Second)
+
+	if _, err := svc.ResendOrder(context.Background(), 1, 999); !errors.Is(err, domain.ErrOrderNotFound) {
+		t.Fatalf("expected ErrOrderNotFound, got %v", err)
+	}
+
+	events, _ := audit.List(context.Background(), 1, 10, 0)
+	if len(events) != 2 || events[0].Action != domain.AuditOrderResent || events[1].Action != domain.AuditOrderCreated {
+		t.Fatalf("unexpected audit events: %+v", events)
+	}
+	if strings.Contains(string(events[1].After), "Anna") {
+		t.Fatalf("customer name should be masked, got %s", events[1].After)
+	}
+}
+
+func TestAuditEndpointUsesRequestID(t *testing.T) {
+	audit := &MockAuditRepo{}
+	svc := domain.NewService(&MockIntegrationRepo{}, &MockOrderRepo{}, NewMockNotificationLogRepo(), telegramNotifiers(&MockTelegramClient{}), 3, domain.WithAuditLog(audit))
+	router := newTestRouter(t, svc, domain.NewAPIKeyService(NewMockAPIKeyRepo(), "admin-secret"), newAuthService())
+
+	req := httptest.NewRequest(http.MethodPost, "/shops/1/telegram/connect", strings.NewReader(`{"botToken":"1:abc","chatId":"-100","enabled":true}`))
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	req.Header.Set("X-Request-ID", "trace-42")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Request-ID") != "trace-42" {
+		t.Fatalf("unexpected response %d %q: %s", rec.Code, rec.Header().Get("X-Request-ID"), rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/shops/1/audit?limit=1", nil)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Request-ID") == "" {
+		t.Fatalf("unexpected response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var out domain.ListAuditEventsResult
+	decodeJSON(t, rec.Body.Bytes(), &out)
+
+	if len(out.Items) != 1 || out.Items[0].Actor != "admin" || out.Items[0].RequestID != "trace-42" {
+		t.Fatalf("unexpected audit page: %+v", out)
+	}
+}
@@ -0,0 +1,249 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/api"
+	"growth-mvp/backend/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MockUserRepo struct {
+	users []domain.User
+}
+
+func (f *MockUserRepo) Create(_ context.Context, email, passwordHash string) (domain.User, error) {
+	for _, user := range f.users {
+		if user.Email == email {
+			return domain.User{}, domain.ErrUserExists
+		}
+	}
+	user := domain.User{ID: int64(len(f.users) + 1), Email: email, PasswordHash: passwordHash, CreatedAt: time.Now()}
+	f.users = append(f.users, user)
+	return user, nil
+}
+
+func (f *MockUserRepo) GetByID(_ context.Context, userID int64) (domain.User, error) {
+	for _, user := range f.users {
+		if user.ID == userID {
+			return user, nil
+		}
+	}
+	return domain.User{}, domain.ErrUserNotFound
+}
+
+func (f *MockUserRepo) GetByEmail(_ context.Context, email string) (domain.User, error) {
+	for _, user := range f.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return domain.User{}, domain.ErrUserNotFound
+}
+
+type MockSessionRepo struct {
+	sessions map[string]domain.Session
+}
+
+func NewMockSessionRepo() *MockSessionRepo {
+	return &MockSessionRepo{sessions: map[string]domain.Session{}}
+}
+
+func (f *MockSessionRepo) Create(_ context.Context, session domain.Session) error {
+	f.sessions[string(session.TokenHash)] = session
+	return nil
+}
+
+func (f *MockSessionRepo) Get(_ context.Context, tokenHash []byte) (domain.Session, bool, error) {
+	session, ok := f.sessions[string(tokenHash)]
+	return session, ok, nil
+}
+
+func (f *MockSessionRepo) Delete(_ context.Context, tokenHash []byte) error {
+	delete(f.sessions, string(tokenHash))
+	return nil
+}
+
+type MockMemberRepo struct {
+	members []domain.ShopMember
+}
+
+func (f *MockMemberRepo) Upsert(_ context.Context, shopID, userID int64, role domain.Role) (domain.ShopMember, error) {
+	for i, member := range f.members {
+		if member.ShopID == shopID && member.UserID == userID {
+			f.members[i].Role = role
+			return f.members[i], nil
+		}
+	}
+	member := domain.ShopMember{ShopID: shopID, UserID: userID, Role: role, CreatedAt: time.Now()}
+	f.members = append(f.members, member)
+	return member, nil
+}
+
+func (f *MockMemberRepo) ListByShop(_ context.Context, shopID int64) ([]domain.ShopMember, error) {
+	out := []domain.ShopMember{}
+	for _, member := range f.members {
+		if member.ShopID == shopID {
+			out = append(out, member)
+		}
+	}
+	return out, nil
+}
+
+func (f *MockMemberRepo) ListByUser(_ context.Context, userID int64) ([]domain.ShopMember, error) {
+	out := []domain.ShopMember{}
+	for _, member := range f.members {
+		if member.UserID == userID {
+			out = append(out, member)
+		}
+	}
+	return out, nil
+}
+
+func (f *MockMemberRepo) Delete(_ context.Context, shopID, userID int64) error {
+	for i, member := range f.members {
+		if member.ShopID == shopID && member.UserID == userID {
+			f.members = append(f.members[:i], f.members[i+1:]...)
+			return nil
+		}
+	}
+	return domain.ErrMemberNotFound
+}
+
+func newAuthService() *domain.AuthService {
+	return domain.NewAuthService(&MockUserRepo{}, NewMockSessionRepo(), &MockMemberRepo{}, time.Hour)
+}
+
+func newTestRouter(t *testing.T, svc *domain.Service, keys *domain.APIKeyService, auth *domain.AuthService, opts ...api.HandlerOption) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	if svc == nil {
+		svc = domain.NewService(&MockIntegrationRepo{}, &MockOrderRepo{}, NewMockNotificationLogRepo(), telegramNotifiers(&MockTelegramClient{}), 3)
+	}
+	shops := NewMockShopRepo()
+	shops.shops[1] = domain.Shop{ID: 1, Name: "Demo Shop", Timezone: "Europe/Moscow", Locale: "ru-RU", Currency: "RUB"}
+	shops.nextID = 1
+	handler := api.NewHandler(svc, domain.NewShopService(shops), keys, auth, opts...)
+
+	router := gin.New()
+	router.Use(api.RequestID())
+	handler.RegisterRoutes(router)
+	return router
+}
+
+func TestLoginRejectsWrongPassword(t *testing.T) {
+	auth := newAuthService()
+
+	if _, err := auth.CreateUser(context.Background(), domain.CreateUserInput{Email: "Owner@Example.com", Password: "correct horse"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, _, err := auth.Login(context.Background(), domain.LoginInput{Email: "owner@example.com", Password: "wrong"}); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+	if _, _, err := auth.Login(context.Background(), domain.LoginInput{Email: "nobody@example.com", Password: "correct horse"}); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized for unknown user, got %v", err)
+	}
+
+	out, token, err := auth.Login(context.Background(), domain.LoginInput{Email: "owner@example.com", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token == "" || out.CSRFToken == "" || out.User.PasswordHash == "" {
+		t.Fatalf("expected session and csrf tokens, got %+v", out)
+	}
+}
+
+func TestCreateUserValidatesInput(t *testing.T) {
+	auth := newAuthService()
+
+	if _, err := auth.CreateUser(context.Background(), domain.CreateUserInput{Email: "not-an-email", Password: "long enough"}); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput for bad email, got %v", err)
+	}
+	if _, err := auth.CreateUser(context.Background(), domain.CreateUserInput{Email: "a@example.com", Password: "short"}); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput for short password, got %v", err)
+	}
+}
+
+func TestSessionRolesAndCSRF(t *testing.T) {
+	auth := newAuthService()
+	router := newTestRouter(t, nil, domain.NewAPIKeyService(NewMockAPIKeyRepo(), ""), auth)
+
+	for email, role := range map[string]domain.Role{"viewer@example.com": domain.RoleViewer, "manager@example.com": domain.RoleManager} {
+		if _, err := auth.CreateUser(context.Background(), domain.CreateUserInput{Email: email, Password: "password123"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := auth.SetMember(context.Background(), 1, domain.SetMemberInput{Email: email, Role: role}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	login := func(email string) (*http.Cookie, string) {
+		rec := httptest.NewRecorder()
+		body := `{"email":"` + email + `","password":"password123"}`
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("login failed: %d %s", rec.Code, rec.Body.String())
+		}
+		cookies := rec.Result().Cookies()
+		if len(cookies) != 1 || !cookies[0].HttpOnly {
+			t.Fatalf("expected one http-only session cookie, got %+v", cookies)
+		}
+		var csrf struct {
+			CSRFToken string `json:"csrfToken"`
+		}
+		decodeJSON(t, rec.Body.Bytes(), &csrf)
+		return cookies[0], csrf.CSRFToken
+	}
+
+	do := func(cookie *http.Cookie, csrf, method, path, body string) int {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.AddCookie(cookie)
+		if csrf != "" {
+			req.Header.Set("X-CSRF-Token", csrf)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	order := `{"number":"A-1","total":10,"customerName":"Anna"}`
+
+	viewer, viewerCSRF := login("viewer@example.com")
+	if code := do(viewer, "", http.MethodGet, "/shops/1/orders", ""); code != http.StatusOK {
+		t.Fatalf("viewer should read orders, got %d", code)
+	}
+	if code := do(viewer, viewerCSRF, http.MethodPost, "/shops/1/orders", order); code != http.StatusForbidden {
+		t.Fatalf("viewer should not create orders, got %d", code)
+	}
+	if code := do(viewer, "", http.MethodGet, "/shops/2/orders", ""); code != http.StatusForbidden {
+		t.Fatalf("viewer should not read other shops, got %d", code)
+	}
+
+	manager, managerCSRF := login("manager@example.com")
+	if code := do(manager, "", http.MethodPost, "/shops/1/orders", order); code != http.StatusForbidden {
+		t.Fatalf("missing csrf token should be rejected, got %d", code)
+	}
+	if code := do(manager, managerCSRF, http.MethodPost, "/shops/1/orders", order); code != http.StatusCreated {
+		t.Fatalf("manager should create orders, got %d", code)
+	}
+	if code := do(manager, managerCSRF, http.MethodGet, "/shops/1/members", ""); code != http.StatusForbidden {
+		t.Fatalf("manager should not manage members, got %d", code)
+	}
+
+	if code := do(manager, managerCSRF, http.MethodPost, "/auth/logout", ""); code != http.StatusNoContent {
+		t.Fatalf("logout failed, got %d", code)
+	}
+	if code := do(manager, "", http.MethodGet, "/shops/1/orders", ""); code != http.StatusUnauthorized {
+		t.Fatalf("session should be gone after logout, got %d", code)
+	}
+}
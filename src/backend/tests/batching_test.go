@@ -0,0 +1,263 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"growth-mvp/backend/adapters/egress"
+	"growth-mvp/backend/adapters/email"
+	"growth-mvp/backend/adapters/telegram"
+	"growth-mvp/backend/domain"
+)
+
+func newBatchingService(sender telegram.Sender, logs *MockNotificationLogRepo, windowSeconds int) *domain.Service {
+	integration := telegramIntegration(1, "123456:bot-token", "-1001234567890", true)
+	integration.BatchWindowSeconds = windowSeconds
+	return domain.NewService(&MockIntegrationRepo{items: []domain.Integration{integration}}, &MockOrderRepo{}, logs, telegramNotifiers(sender), 1)
+}
+
+func createOrders(t *testing.T, svc *domain.Service, numbers ...string) []domain.Order {
+	t.Helper()
+
+	var orders []domain.Order
+	for _, number := range numbers {
+		out, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{Number: number, Total: 100, CustomerName: "Anna"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		orders = append(orders, out.Order)
+	}
+	return orders
+}
+
+func messageIDOf(logs *MockNotificationLogRepo, k string) string {
+	logs.mu.Lock()
+	defer logs.mu.Unlock()
+
+	if id := logs.logs[k].MessageID; id != nil {
+		return *id
+	}
+	return ""
+}
+
+func deliverAfterOf(logs *MockNotificationLogRepo, k string) *time.Time {
+	logs.mu.Lock()
+	defer logs.mu.Unlock()
+
+	return logs.logs[k].DeliverAfter
+}
+
+// deliverDue waits for the batching window to end and runs the deferred job once.
+func deliverDue(t *testing.T, svc *domain.Service, window time.Duration) int {
+	t.Helper()
+
+	time.Sleep(window + 50*time.Millisecond)
+	started, err := svc.DeliverDue(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return started
+}
+
+func TestBatchingMergesOrdersWithinWindow(t *testing.T) {
+	sender := &messageRecorder{}
+	logs := NewMockNotificationLogRepo()
+	svc := newBatchingService(sender, logs, 1)
+
+	orders := createOrders(t, svc, "A-1", "A-2", "A-3")
+	if started, err := svc.DeliverDue(context.Background(), 100); err != nil || started != 0 {
+		t.Fatalf("nothing should be due before the window ends, got %d %v", started, err)
+	}
+	if got := sender.sent(); len(got) != 0 {
+		t.Fatalf("nothing should be sent before the window ends, got %+v", got)
+	}
+
+	// the orders join the window the first one opened
+	first := deliverAfterOf(logs, key(1, orders[0].ID))
+	for _, order := range orders {
+		if got := deliverAfterOf(logs, key(1, order.ID)); first == nil || got == nil || !got.Equal(*first) {
+			t.Fatalf("order %s should wait for %v, got %v", order.Number, first, got)
+		}
+	}
+
+	if started := deliverDue(t, svc, time.Second); started != 3 {
+		t.Fatalf("expected 3 notifications to start, got %d", started)
+	}
+	for _, order := range orders {
+		waitForLogStatus(t, logs, 1, order.ID, domain.NotificationStatusSent, time.Second)
+	}
+
+	got := sender.sent()
+	if len(got) != 1 {
+		t.Fatalf("expected one merged message, got %d", len(got))
+	}
+	if !strings.HasPrefix(got[0].text, domain.RenderBatchHeader(3)+"\n\n") {
+		t.Fatalf("expected the batch header, got %q", got[0].text)
+	}
+	for _, order := range orders {
+		if !strings.Contains(got[0].text, domain.RenderOrderMessage(order).Text) {
+			t.Errorf("order %s is missing from the message %q", order.Number, got[0].text)
+		}
+		if id := messageIDOf(logs, key(1, order.ID)); id != "1" {
+			t.Errorf("order %s should be linked to message 1, got %q", order.Number, id)
+		}
+	}
+
+	// a later order opens a new window
+	later := createOrders(t, svc, "A-4")[0]
+	if after := deliverAfterOf(logs, key(1, later.ID)); after == nil || !after.After(*first) {
+		t.Fatalf("expected the later order to open a new window, got %v", after)
+	}
+	deliverDue(t, svc, time.Second)
+
+	if got := sender.sent(); len(got) != 2 || got[1].text != domain.RenderOrderMessage(later).Text {
+		t.Fatalf("a lone order should be sent without the header, got %+v", got)
+	}
+	if id := messageIDOf(logs, key(1, later.ID)); id != "2" {
+		t.Fatalf("expected the later order to be linked to message 2, got %q", id)
+	}
+}
+
+func TestBatchingSendsWhenWindowEnds(t *testing.T) {
+	sender := &messageRecorder{}
+	logs := NewMockNotificationLogRepo()
+	integration := telegramIntegration(1, "123456:bot-token", "-1001234567890", true)
+	integration.BatchWindowSeconds = 1
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc := domain.NewService(&MockIntegrationRepo{items: []domain.Integration{integration}}, &MockOrderRepo{}, logs, telegramNotifiers(sender), 1,
+		domain.WithBatchTimers(ctx))
+
+	opened := time.Now()
+	orders := createOrders(t, svc, "A-1", "A-2")
+
+	time.Sleep(800 * time.Millisecond)
+	if got := sender.sent(); len(got) != 0 {
+		t.Fatalf("nothing should be sent before the window ends, got %+v", got)
+	}
+
+	// no DeliverDue here: the timer the first order set sends the batch
+	for _, order := range orders {
+		waitForLogStatus(t, logs, 1, order.ID, domain.NotificationStatusSent, time.Second)
+	}
+	if elapsed := time.Since(opened); elapsed < time.Second || elapsed > 1500*time.Millisecond {
+		t.Fatalf("expected the batch right after its 1s window, got it after %v", elapsed)
+	}
+	if got := sender.sent(); len(got) != 1 || !strings.HasPrefix(got[0].text, domain.RenderBatchHeader(2)) {
+		t.Fatalf("expected one merged message, got %+v", got)
+	}
+
+	// once the service stops, a batch waits for the poll
+	cancel()
+	later := createOrders(t, svc, "A-3")[0]
+	time.Sleep(1100 * time.Millisecond)
+	if got := sender.sent(); len(got) != 1 {
+		t.Fatalf("a stopped timer should not send, got %+v", got)
+	}
+	if started, err := svc.DeliverDue(context.Background(), 100); err != nil || started != 1 {
+		t.Fatalf("expected the poll to pick up the batch, got %d %v", started, err)
+	}
+	waitForLogStatus(t, logs, 1, later.ID, domain.NotificationStatusSent, time.Second)
+}
+
+func TestBatchingSurvivesRestart(t *testing.T) {
+	sender := &messageRecorder{}
+	logs := NewMockNotificationLogRepo()
+	integration := telegramIntegration(1, "123456:bot-token", "-1001234567890", true)
+	integration.BatchWindowSeconds = 1
+	integrations := &MockIntegrationRepo{items: []domain.Integration{integration}}
+	orderRepo := &MockOrderRepo{}
+	orders := createOrders(t, domain.NewService(integrations, orderRepo, logs, telegramNotifiers(sender), 1), "A-1", "A-2")
+
+	// another instance, or the same one after a restart, sends the batch
+	restarted := domain.NewService(integrations, orderRepo, logs, telegramNotifiers(sender), 1)
+	if started := deliverDue(t, restarted, time.Second); started != 2 {
+		t.Fatalf("expected 2 notifications to start, got %d", started)
+	}
+	for _, order := range orders {
+		waitForLogStatus(t, logs, 1, order.ID, domain.NotificationStatusSent, time.Second)
+	}
+	if got := sender.sent(); len(got) != 1 || !strings.HasPrefix(got[0].text, domain.RenderBatchHeader(2)) {
+		t.Fatalf("expected one merged message, got %+v", got)
+	}
+}
+
+func TestTelegramBatchSplitsAtMessageLimit(t *testing.T) {
+	sender := &messageRecorder{}
+	notifier := telegram.NewNotifier(sender)
+	integration := telegramIntegration(1, "123456:bot-token", "-1001234567890", true)
+
+	// Cyrillic runs two bytes per letter but one UTF-16 unit, which Telegram counts
+	notifications := []domain.Notification{
+		{ID: 1, Text: strings.Repeat("я", 1500), Silent: true},
+		{ID: 2, Text: strings.Repeat("я", 1500), Silent: true},
+		{ID: 3, Text: strings.Repeat("я", 1500)},
+		{ID: 4, Text: strings.Repeat("я", 5000)},
+	}
+
+	ids, err := notifier.SendBatch(context.Background(), integration, notifications)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"1", "1", "2", "3"}; fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Fatalf("expected message IDs %v, got %v", want, ids)
+	}
+
+	got := sender.sent()
+	if len(got) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(got))
+	}
+	for i, message := range got {
+		if n := len(utf16.Encode([]rune(message.text))); n > 4096 {
+			t.Errorf("message %d has %d UTF-16 units", i+1, n)
+		}
+	}
+	if !strings.HasPrefix(got[0].text, domain.RenderBatchHeader(2)) || !got[0].silent {
+		t.Fatalf("the first message should merge two silent orders, got %.40q silent=%v", got[0].text, got[0].silent)
+	}
+	if strings.HasPrefix(got[1].text, "📦") || got[1].silent {
+		t.Fatalf("a lone order should be sent as is, got %.40q silent=%v", got[1].text, got[1].silent)
+	}
+	if !strings.HasSuffix(got[2].text, "…") {
+		t.Fatalf("an overlong order should be truncated, got ...%q", got[2].text[len(got[2].text)-10:])
+	}
+}
+
+func TestBatchingValidatesWindow(t *testing.T) {
+	notifiers := telegramNotifiers(&messageRecorder{}).Register(domain.ChannelEmail, email.NewNotifier(email.Config{}, egress.Config{Timeout: time.Second}))
+	svc := domain.NewService(&MockIntegrationRepo{}, &MockOrderRepo{}, NewMockNotificationLogRepo(), notifiers, 1)
+
+	cases := []struct {
+		path, body, code string
+	}{
+		{"/v2/shops/1/integrations/telegram/connect", `{"settings":{"chatId":"-100"},"secret":"123:abc","batchWindowSeconds":301}`, "max"},
+		{"/v2/shops/1/integrations/email/connect", `{"settings":{"to":"owner@shop.example","host":"smtp.example","from":"shop@shop.example"},"batchWindowSeconds":30}`, "unsupported"},
+	}
+	for _, tc := range cases {
+		status, problem := doProblem(t, svc, http.MethodPost, tc.path, tc.body)
+		if status != http.StatusBadRequest || len(problem.Errors) != 1 || problem.Errors[0].Field != "batchWindowSeconds" || problem.Errors[0].Code != tc.code {
+			t.Errorf("%s: expected a %q error for batchWindowSeconds, got %d %+v", tc.path, tc.code, status, problem.Errors)
+		}
+	}
+
+	ctx := context.Background()
+	if _, err := svc.ConnectIntegration(ctx, 1, domain.ChannelTelegram, domain.ConnectIntegrationInput{
+		Settings: map[string]string{domain.TelegramSettingChatID: "-100"}, Secret: "123:abc", Enabled: true,
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	window := 30
+	integration, err := svc.UpdateIntegration(ctx, 1, domain.ChannelTelegram, domain.UpdateIntegrationInput{BatchWindowSeconds: &window})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if integration.BatchWindowSeconds != 30 {
+		t.Fatalf("expected the window to be stored, got %d", integration.BatchWindowSeconds)
+	}
+}
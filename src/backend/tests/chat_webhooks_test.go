@@ -0,0 +1,301 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/adapters/discord"
+	"growth-mvp/backend/adapters/egress"
+	"growth-mvp/backend/adapters/slack"
+	"growth-mvp/backend/domain"
+)
+
+type chatWebhookRequest struct {
+	at   time.Time
+	path string
+	body []byte
+}
+
+// chatWebhookStandIn plays Slack or Discord: it answers the queued responses
+// in order and success after they run out.
+type chatWebhookStandIn struct {
+	server *httptest.Server
+	status int
+
+	mu        sync.Mutex
+	responses []func(http.ResponseWriter)
+	requests  []chatWebhookRequest
+}
+
+func newChatWebhookStandIn(t *testing.T, status int, responses ...func(http.ResponseWriter)) *chatWebhookStandIn {
+	t.Helper()
+
+	s := &chatWebhookStandIn{status: status, responses: responses}
+	s.server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		s.mu.Lock()
+		s.requests = append(s.requests, chatWebhookRequest{at: time.Now(), path: r.URL.Path, body: body})
+		var respond func(http.ResponseWriter)
+		if len(s.responses) > 0 {
+			respond, s.responses = s.responses[0], s.responses[1:]
+		}
+		s.mu.Unlock()
+
+		if respond != nil {
+			respond(w)
+			return
+		}
+		w.WriteHeader(s.status)
+		if s.status == http.StatusOK {
+			_, _ = io.WriteString(w, "ok")
+		}
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *chatWebhookStandIn) wait(t *testing.T, want int) []chatWebhookRequest {
+	t.Helper()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		got := append([]chatWebhookRequest(nil), s.requests...)
+		s.mu.Unlock()
+		if len(got) >= want {
+			return got
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected %d webhook requests", want)
+	return nil
+}
+
+func newChatWebhookService(integrations *MockIntegrationRepo, logs *MockNotificationLogRepo, attempts int, outbound egress.Config) *domain.Service {
+	notifiers := domain.NewNotifierRegistry().
+		Register(domain.ChannelSlack, slack.NewNotifier(outbound)).
+		Register(domain.ChannelDiscord, discord.NewNotifier(outbound))
+	return domain.NewService(integrations, &MockOrderRepo{}, logs, notifiers, attempts)
+}
+
+func connectChatWebhook(t *testing.T, svc *domain.Service, channel domain.ChannelType, secret string, settings map[string]string) domain.Integration {
+	t.Helper()
+
+	integration, err := svc.ConnectIntegration(context.Background(), 1, channel, domain.ConnectIntegrationInput{
+		Settings: settings,
+		Secret:   secret,
+		Enabled:  true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return integration
+}
+
+func TestSlackPostsBlockKitMessage(t *testing.T) {
+	server := newChatWebhookStandIn(t, http.StatusOK)
+	logs := NewMockNotificationLogRepo()
+	svc := newChatWebhookService(&MockIntegrationRepo{}, logs, 1, trustServer(server.server))
+	integration := connectChatWebhook(t, svc, domain.ChannelSlack, server.server.URL+"/services/T0/B0/slack-token", nil)
+
+	out, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{Number: "A-1", Total: 1990.5, CustomerName: "<!channel> & Co"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForLogStatus(t, logs, integration.ID, out.Order.ID, domain.NotificationStatusSent, time.Second)
+
+	req := server.wait(t, 1)[0]
+	if req.path != "/services/T0/B0/slack-token" {
+		t.Fatalf("unexpected path %q", req.path)
+	}
+
+	var payload struct {
+		Text   string `json:"text"`
+		Blocks []struct {
+			Type string `json:"type"`
+			Text struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"text"`
+			Fields []struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"fields"`
+		} `json:"blocks"`
+	}
+	decodeJSON(t, req.body, &payload)
+
+	if payload.Text != domain.RenderOrderMessage(out.Order).Text {
+		t.Fatalf("fallback text should be the order message, got %q", payload.Text)
+	}
+	if len(payload.Blocks) != 2 || payload.Blocks[0].Type != "header" || payload.Blocks[0].Text.Text != "Новый заказ A-1" {
+		t.Fatalf("expected a header block, got %+v", payload.Blocks)
+	}
+
+	fields := payload.Blocks[1].Fields
+	if payload.Blocks[1].Type != "section" || len(fields) != 2 {
+		t.Fatalf("expected a section with two fields, got %+v", payload.Blocks[1])
+	}
+	if fields[0].Type != "mrkdwn" || fields[0].Text != "*Сумма*\n1990.50 ₽" {
+		t.Fatalf("unexpected total field %+v", fields[0])
+	}
+	if fields[1].Text != "*Клиент*\n&lt;!channel&gt; &amp; Co" {
+		t.Fatalf("customer name must be escaped, got %q", fields[1].Text)
+	}
+}
+
+func TestSlackWaitsOutRateLimit(t *testing.T) {
+	server := newChatWebhookStandIn(t, http.StatusOK, func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	logs := NewMockNotificationLogRepo()
+	svc := newChatWebhookService(&MockIntegrationRepo{}, logs, 2, trustServer(server.server))
+	integration := connectChatWebhook(t, svc, domain.ChannelSlack, server.server.URL+"/services/T0/B0/slack-token", nil)
+
+	out, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{Number: "A-1", Total: 10, CustomerName: "Anna"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForLogStatus(t, logs, integration.ID, out.Order.ID, domain.NotificationStatusSent, 3*time.Second)
+
+	reqs := server.wait(t, 2)
+	if gap := reqs[1].at.Sub(reqs[0].at); gap < time.Second {
+		t.Fatalf("expected the retry to wait for Retry-After, waited %s", gap)
+	}
+}
+
+func TestDiscordPostsEmbed(t *testing.T) {
+	server := newChatWebhookStandIn(t, http.StatusNoContent)
+	logs := NewMockNotificationLogRepo()
+	svc := newChatWebhookService(&MockIntegrationRepo{}, logs, 1, trustServer(server.server))
+	integration := connectChatWebhook(t, svc, domain.ChannelDiscord, server.server.URL+"/api/webhooks/42/discord-token",
+		map[string]string{"username": " Growth "})
+
+	if integration.Settings["username"] != "Growth" {
+		t.Fatalf("expected trimmed username, got %+v", integration.Settings)
+	}
+
+	out, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{Number: "A-1", Total: 1990.5, CustomerName: "@everyone"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForLogStatus(t, logs, integration.ID, out.Order.ID, domain.NotificationStatusSent, time.Second)
+
+	var payload struct {
+		Username string `json:"username"`
+		Embeds   []struct {
+			Title  string `json:"title"`
+			Fields []struct {
+				Name   string `json:"name"`
+				Value  string `json:"value"`
+				Inline bool   `json:"inline"`
+			} `json:"fields"`
+		} `json:"embeds"`
+		AllowedMentions struct {
+			Parse []string `json:"parse"`
+		} `json:"allowed_mentions"`
+	}
+	req := server.wait(t, 1)[0]
+	decodeJSON(t, req.body, &payload)
+
+	if payload.Username != "Growth" || len(payload.Embeds) != 1 || payload.Embeds[0].Title != "Новый заказ A-1" {
+		t.Fatalf("unexpected payload %s", req.body)
+	}
+	fields := payload.Embeds[0].Fields
+	if len(fields) != 2 || fields[0].Name != "Сумма" || fields[0].Value != "1990.50 ₽" || fields[1].Value != "@everyone" || !fields[1].Inline {
+		t.Fatalf("unexpected embed fields %+v", fields)
+	}
+	if payload.AllowedMentions.Parse == nil || len(payload.AllowedMentions.Parse) != 0 {
+		t.Fatalf("mentions must be disabled, got %s", req.body)
+	}
+}
+
+func TestDiscordWaitsOutRateLimit(t *testing.T) {
+	server := newChatWebhookStandIn(t, http.StatusNoContent, func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = io.WriteString(w, `{"message":"You are being rate limited.","retry_after":1.2,"global":false}`)
+	})
+	logs := NewMockNotificationLogRepo()
+	svc := newChatWebhookService(&MockIntegrationRepo{}, logs, 2, trustServer(server.server))
+	integration := connectChatWebhook(t, svc, domain.ChannelDiscord, server.server.URL+"/api/webhooks/42/discord-token", nil)
+
+	out, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{Number: "A-1", Total: 10, CustomerName: "Anna"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForLogStatus(t, logs, integration.ID, out.Order.ID, domain.NotificationStatusSent, 3*time.Second)
+
+	reqs := server.wait(t, 2)
+	if gap := reqs[1].at.Sub(reqs[0].at); gap < 1200*time.Millisecond {
+		t.Fatalf("expected the retry to wait for retry_after, waited %s", gap)
+	}
+}
+
+func TestChatWebhookFailuresKeepURLSecret(t *testing.T) {
+	slackServer := newChatWebhookStandIn(t, http.StatusNotFound)
+	closed := httptest.NewTLSServer(http.NotFoundHandler())
+	closed.Close()
+
+	logs := NewMockNotificationLogRepo()
+	svc := newChatWebhookService(&MockIntegrationRepo{}, logs, 1, trustServer(slackServer.server))
+	slackIntegration := connectChatWebhook(t, svc, domain.ChannelSlack, slackServer.server.URL+"/services/T0/B0/slack-token", nil)
+	discordIntegration := connectChatWebhook(t, svc, domain.ChannelDiscord, closed.URL+"/api/webhooks/42/discord-token", nil)
+
+	out, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{Number: "A-1", Total: 10, CustomerName: "Anna"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, integration := range []domain.Integration{slackIntegration, discordIntegration} {
+		errText := waitForLogError(t, logs, key(integration.ID, out.Order.ID), time.Second)
+
+		if strings.Contains(errText, "slack-token") || strings.Contains(errText, "discord-token") {
+			t.Fatalf("%s error leaks the webhook URL: %q", integration.Channel, errText)
+		}
+		if integration.Channel == domain.ChannelSlack && !strings.Contains(errText, "status=404") {
+			t.Fatalf("expected the Slack status in the error, got %q", errText)
+		}
+	}
+}
+
+func TestChatWebhooksValidateSettings(t *testing.T) {
+	svc := newChatWebhookService(&MockIntegrationRepo{}, NewMockNotificationLogRepo(), 1, egress.Config{})
+
+	cases := []struct {
+		channel string
+		body    string
+		want    []string
+	}{
+		{"slack", `{"settings":{"channel":"#ops"},"secret":"https://hooks.slack.com/workflows/1"}`, []string{"settings.channel", "secret"}},
+		{"discord", `{"settings":{"username":"` + strings.Repeat("x", 81) + `"},"secret":"https://discord.com/api/webhooks/42"}`, []string{"settings.username", "secret"}},
+		{"slack", `{"secret":"http://hooks.slack.com/services/T0/B0/x"}`, []string{"secret"}},
+		{"slack", `{"secret":"https://127.0.0.1/services/T0/B0/x"}`, []string{"secret"}},
+		{"discord", `{"secret":"https://169.254.169.254/api/webhooks/42/token"}`, []string{"secret"}},
+	}
+
+	for _, tc := range cases {
+		status, problem := doProblem(t, svc, http.MethodPost, "/v2/shops/1/integrations/"+tc.channel+"/connect", tc.body)
+		if status != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d %+v", tc.channel, status, problem)
+		}
+
+		fields := map[string]bool{}
+		for _, field := range problem.Errors {
+			fields[field.Field] = true
+		}
+		for _, want := range tc.want {
+			if !fields[want] {
+				t.Errorf("%s: expected a field error for %s, got %+v", tc.channel, want, problem.Errors)
+			}
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/domain"
+)
+
+type MockDestinationRepo struct {
+	mu     sync.Mutex
+	nextID int64
+	items  []domain.TelegramDestination
+}
+
+func (f *MockDestinationRepo) Create(_ context.Context, shopID, integrationID int64, input domain.TelegramDestinationInput) (domain.TelegramDestination, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, item := range f.items {
+		if item.IntegrationID == integrationID && item.ChatID == input.ChatID {
+			return domain.TelegramDestination{}, domain.ErrDestinationExists
+		}
+	}
+	f.nextID++
+	destination := domain.TelegramDestination{
+		ID:            f.nextID,
+		ShopID:        shopID,
+		IntegrationID: integrationID,
+		Name:          input.Name,
+		ChatID:        input.ChatID,
+		Rule:          input.Rule,
+		Enabled:       *input.Enabled,
+	}
+	f.items = append(f.items, destination)
+	return destination, nil
+}
+
+func (f *MockDestinationRepo) find(shopID, destinationID int64) int {
+	for i, item := range f.items {
+		if item.ShopID == shopID && item.ID == destinationID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *MockDestinationRepo) Get(_ context.Context, shopID, destinationID int64) (domain.TelegramDestination, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if i := f.find(shopID, destinationID); i >= 0 {
+		return f.items[i], nil
+	}
+	return domain.TelegramDestination{}, domain.ErrDestinationNotFound
+}
+
+func (f *MockDestinationRepo) ListByShop(_ context.Context, shopID int64) ([]domain.TelegramDestination, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := []domain.TelegramDestination{}
+	for _, item := range f.items {
+		if item.ShopID == shopID {
+			out = append(out, item)
+		}
+	}
+	return out, nil
+}
+
+func (f *MockDestinationRepo) Update(_ context.Context, shopID, destinationID int64, input domain.UpdateTelegramDestinationInput) (domain.TelegramDestination, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	i := f.find(shopID, destinationID)
+	if i < 0 {
+		return domain.TelegramDestination{}, domain.ErrDestinationNotFound
+	}
+	for j, item := range f.items {
+		if j != i && input.ChatID != nil && item.IntegrationID == f.items[i].IntegrationID && item.ChatID == *input.ChatID {
+			return domain.TelegramDestination{}, domain.ErrDestinationExists
+		}
+	}
+	if input.Name != nil {
+		f.items[i].Name = *input.Name
+	}
+	if input.ChatID != nil {
+		f.items[i].ChatID = *input.ChatID
+	}
+	if input.Rule != nil {
+		f.items[i].Rule = *input.Rule
+	}
+	if input.Enabled != nil {
+		f.items[i].Enabled = *input.Enabled
+	}
+	return f.items[i], nil
+}
+
+func (f *MockDestinationRepo) Delete(_ context.Context, shopID, destinationID int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	i := f.find(shopID, destinationID)
+	if i < 0 {
+		return domain.ErrDestinationNotFound
+	}
+	f.items = append(f.items[:i], f.items[i+1:]...)
+	return nil
+}
+
+// chatRecorder is a telegram.Sender that remembers the chats it sent to and
+// fails for the chats in failing.
+type chatRecorder struct {
+	mu      sync.Mutex
+	chats   []string
+	failing map[string]bool
+}
+
+func (r *chatRecorder) SendMessage(_ context.Context, _, chatID, _ string, _ bool) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.chats = append(r.chats, chatID)
+	if r.failing[chatID] {
+		return 0, errors.New("telegram sendMessage failed (status=403): Forbidden: bot was blocked by the user")
+	}
+	return int64(len(r.chats)), nil
+}
+
+func (r *chatRecorder) sent() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := slices.Clone(r.chats)
+	slices.Sort(out)
+	return out
+}
+
+func (r *chatRecorder) recover(chatID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.failing, chatID)
+}
+
+func newDestinationService(t *testing.T, sender *chatRecorder, logs *MockNotificationLogRepo) (*domain.Service, domain.Integration) {
+	t.Helper()
+
+	svc := domain.NewService(&MockIntegrationRepo{}, &MockOrderRepo{}, logs, telegramNotifiers(sender), 1,
+		domain.WithTelegramDestinations(&MockDestinationRepo{}))
+
+	integration, err := svc.ConnectIntegration(context.Background(), 1, domain.ChannelTelegram, domain.ConnectIntegrationInput{
+		Settings: map[string]string{domain.TelegramSettingChatID: "-1001234567890"},
+		Secret:   "123456:bot-token",
+		Enabled:  true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return svc, integration
+}
+
+func addDestination(t *testing.T, svc *domain.Service, input domain.TelegramDestinationInput) domain.TelegramDestination {
+	t.Helper()
+
+	destination, err := svc.CreateTelegramDestination(context.Background(), 1, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return destination
+}
+
+func TestDestinationsReceiveOnlyMatchingOrders(t *testing.T) {
+	sender := &chatRecorder{}
+	logs := NewMockNotificationLogRepo()
+	svc, integration := newDestinationService(t, sender, logs)
+
+	minTotal := 1000.0
+	owner := addDestination(t, svc, domain.TelegramDestinationInput{Name: "Владелец", ChatID: "555000111", Rule: domain.RoutingRule{MinTotal: &minTotal}})
+	addDestination(t, svc, domain.TelegramDestinationInput{Name: "Ночная смена", ChatID: "777000111", Rule: domain.RoutingRule{ActiveFrom: "22:00", ActiveTo: "6:00"}})
+	disabled := false
+	addDestination(t, svc, domain.TelegramDestinationInput{Name: "Архив", ChatID: "888000111", Enabled: &disabled})
+
+	if owner.ChatID != "*****0111" {
+		t.Fatalf("expected a masked chat ID, got %q", owner.ChatID)
+	}
+
+	day := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	night := time.Date(2024, 5, 1, 23, 30, 0, 0, time.UTC)
+	result, err := svc.ImportOrders(context.Background(), 1, []domain.ImportOrderRow{
+		{Line: 1, Order: domain.CreateOrderInput{Number: "A-1", Total: 500, CustomerName: "Anna"}, CreatedAt: &day},
+		{Line: 2, Order: domain.CreateOrderInput{Number: "A-2", Total: 1500, CustomerName: "Boris"}, CreatedAt: &night},
+	}, domain.ImportOrdersOptions{Notify: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	small, large := *result.Rows[0].OrderID, *result.Rows[1].OrderID
+	waitForLogStatus(t, logs, integration.ID, small, domain.NotificationStatusSent, time.Second)
+	waitForKeyStatus(t, logs, recipientKey(integration.ID, large, "555000111"), domain.NotificationStatusSent, time.Second)
+	waitForKeyStatus(t, logs, recipientKey(integration.ID, large, "777000111"), domain.NotificationStatusSent, time.Second)
+	waitForLogStatus(t, logs, integration.ID, large, domain.NotificationStatusSent, time.Second)
+
+	want := []string{"-1001234567890", "-1001234567890", "555000111", "777000111"}
+	if got := sender.sent(); !slices.Equal(got, want) {
+		t.Fatalf("expected sends to %v, got %v", want, got)
+	}
+}
+
+func TestDestinationsMatchOrderStatus(t *testing.T) {
+	sender := &chatRecorder{}
+	logs := NewMockNotificationLogRepo()
+	svc, integration := newDestinationService(t, sender, logs)
+
+	paid := addDestination(t, svc, domain.TelegramDestinationInput{Name: "Склад", ChatID: "555000111", Rule: domain.RoutingRule{Statuses: []string{" Paid", "paid"}}})
+	if want := []string{"paid"}; !slices.Equal(paid.Rule.Statuses, want) {
+		t.Fatalf("expected statuses %v to be stored, got %v", want, paid.Rule.Statuses)
+	}
+
+	result, err := svc.ImportOrders(context.Background(), 1, []domain.ImportOrderRow{
+		{Line: 1, Order: domain.CreateOrderInput{Number: "A-1", Total: 500, CustomerName: "Anna"}},
+		{Line: 2, Order: domain.CreateOrderInput{Number: "A-2", Total: 500, CustomerName: "Boris", Status: "PAID"}},
+	}, domain.ImportOrdersOptions{Notify: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	unpaid, ready := *result.Rows[0].OrderID, *result.Rows[1].OrderID
+	waitForLogStatus(t, logs, integration.ID, unpaid, domain.NotificationStatusSent, time.Second)
+	waitForKeyStatus(t, logs, recipientKey(integration.ID, ready, "555000111"), domain.NotificationStatusSent, time.Second)
+	waitForLogStatus(t, logs, integration.ID, ready, domain.NotificationStatusSent, time.Second)
+
+	want := []string{"-1001234567890", "-1001234567890", "555000111"}
+	if got := sender.sent(); !slices.Equal(got, want) {
+		t.Fatalf("only the paid order should reach the warehouse chat, got %v", got)
+	}
+}
+
+func TestDestinationWindowUsesShopTimezone(t *testing.T) {
+	sender := &chatRecorder{}
+	logs := NewMockNotificationLogRepo()
+	shops := NewMockShopRepo()
+	shops.shops[1] = domain.Shop{ID: 1, Name: "Demo Shop", Timezone: "Asia/Vladivostok", Locale: "ru-RU", Currency: "RUB"}
+	svc := domain.NewService(&MockIntegrationRepo{}, &MockOrderRepo{}, logs, telegramNotifiers(sender), 1,
+		domain.WithTelegramDestinations(&MockDestinationRepo{}), domain.WithShops(shops))
+	integration, err := svc.ConnectIntegration(context.Background(), 1, domain.ChannelTelegram, domain.ConnectIntegrationInput{
+		Settings: map[string]string{domain.TelegramSettingChatID: "-1001234567890"}, Secret: "123456:bot-token", Enabled: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	router := newTestRouter(t, svc, domain.NewAPIKeyService(NewMockAPIKeyRepo(), "admin-secret"), newAuthService())
+	rec := serveAdmin(router, http.MethodPost, "/v2/shops/1/telegram/destinations", `{"name":"Ночная смена","chatId":"777000111","rule":{"activeFrom":"22:00","activeTo":"06:00"}}`)
+	var night domain.TelegramDestination
+	decodeJSON(t, rec.Body.Bytes(), &night)
+	if rec.Code != http.StatusCreated || !night.Enabled {
+		t.Fatalf("a destination should be enabled unless asked otherwise, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	// 23:30 and 09:30 in Vladivostok, UTC+10
+	lateEvening := time.Date(2024, 5, 1, 13, 30, 0, 0, time.UTC)
+	morning := time.Date(2024, 5, 1, 23, 30, 0, 0, time.UTC)
+	result, err := svc.ImportOrders(context.Background(), 1, []domain.ImportOrderRow{
+		{Line: 1, Order: domain.CreateOrderInput{Number: "A-1", Total: 500, CustomerName: "Anna"}, CreatedAt: &lateEvening},
+		{Line: 2, Order: domain.CreateOrderInput{Number: "A-2", Total: 500, CustomerName: "Boris"}, CreatedAt: &morning},
+	}, domain.ImportOrdersOptions{Notify: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	evening, day := *result.Rows[0].OrderID, *result.Rows[1].OrderID
+	waitForKeyStatus(t, logs, recipientKey(integration.ID, evening, "777000111"), domain.NotificationStatusSent, time.Second)
+	waitForLogStatus(t, logs, integration.ID, day, domain.NotificationStatusSent, time.Second)
+
+	want := []string{"-1001234567890", "-1001234567890", "777000111"}
+	if got := sender.sent(); !slices.Equal(got, want) {
+		t.Fatalf("only the evening order should reach the night chat, got %v", got)
+	}
+}
+
+func TestDestinationStatusDoesNotCountPendingAsFailed(t *testing.T) {
+	logs := NewMockNotificationLogRepo()
+	svc, _ := newDestinationService(t, &chatRecorder{}, logs)
+	addDestination(t, svc, domain.TelegramDestinationInput{Name: "Владелец", ChatID: "555000111"})
+	ctx := context.Background()
+
+	// the rows stay reserved until the batch window ends
+	window := 300
+	if _, err := svc.UpdateIntegration(ctx, 1, domain.ChannelTelegram, domain.UpdateIntegrationInput{BatchWindowSeconds: &window}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.CreateOrder(ctx, 1, domain.CreateOrderInput{Number: "A-1", Total: 10, CustomerName: "Anna"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	status, err := svc.GetIntegrationStatus(ctx, 1, domain.ChannelTelegram)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, destination := range status.Destinations {
+		if destination.SentCount != 0 || destination.FailedCount != 0 {
+			t.Fatalf("a pending notification is neither sent nor failed, got %+v", destination)
+		}
+	}
+}
+
+func TestDestinationStatusAndResendArePerChat(t *testing.T) {
+	sender := &chatRecorder{failing: map[string]bool{"555000111": true}}
+	logs := NewMockNotificationLogRepo()
+	svc, integration := newDestinationService(t, sender, logs)
+	owner := addDestination(t, svc, domain.TelegramDestinationInput{Name: "Владелец", ChatID: "555000111"})
+	ctx := context.Background()
+
+	out, err := svc.CreateOrder(ctx, 1, domain.CreateOrderInput{Number: "A-1", Total: 10, CustomerName: "Anna"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForLogStatus(t, logs, integration.ID, out.Order.ID, domain.NotificationStatusSent, time.Second)
+	waitForLogError(t, logs, recipientKey(integration.ID, out.Order.ID, "555000111"), time.Second)
+
+	status, err := svc.GetIntegrationStatus(ctx, 1, domain.ChannelTelegram)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(status.Destinations) != 2 {
+		t.Fatalf("expected the own chat and one destination, got %+v", status.Destinations)
+	}
+	own, dest := status.Destinations[0], status.Destinations[1]
+	if own.ID != nil || own.ChatID != "-100******7890" || own.SentCount != 1 || own.FailedCount != 0 {
+		t.Fatalf("unexpected own chat stats %+v", own)
+	}
+	if dest.ID == nil || *dest.ID != owner.ID || dest.Name != "Владелец" || dest.SentCount != 0 || dest.FailedCount != 1 {
+		t.Fatalf("unexpected destination stats %+v", dest)
+	}
+
+	sender.recover("555000111")
+	if _, err := svc.ResendOrder(ctx, 1, out.Order.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForKeyStatus(t, logs, recipientKey(integration.ID, out.Order.ID, "555000111"), domain.NotificationStatusSent, time.Second)
+
+	if got := sender.sent(); !slices.Equal(got, []string{"-1001234567890", "555000111", "555000111"}) {
+		t.Fatalf("resend should only retry the failed chat, got %v", got)
+	}
+}
+
+func TestDestinationsValidateRules(t *testing.T) {
+	svc, _ := newDestinationService(t, &chatRecorder{}, NewMockNotificationLogRepo())
+
+	status, problem := doProblem(t, svc, http.MethodPost, "/v2/shops/1/telegram/destinations",
+		`{"name":" ","chatId":"555","rule":{"minTotal":-1,"statuses":["не оплачен"],"activeFrom":"25:00"}}`)
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d %+v", status, problem)
+	}
+
+	fields := map[string]bool{}
+	for _, field := range problem.Errors {
+		fields[field.Field] = true
+	}
+	for _, want := range []string{"name", "rule.minTotal", "rule.statuses", "rule.activeFrom", "rule.activeTo"} {
+		if !fields[want] {
+			t.Errorf("expected a field error for %s, got %+v", want, problem.Errors)
+		}
+	}
+
+	addDestination(t, svc, domain.TelegramDestinationInput{Name: "Владелец", ChatID: "555000111"})
+	status, problem = doProblem(t, svc, http.MethodPost, "/v2/shops/1/telegram/destinations", `{"name":"Ещё раз","chatId":"555000111"}`)
+	if status != http.StatusConflict || problem.Code != "destination_exists" {
+		t.Fatalf("expected 409 destination_exists, got %d %+v", status, problem)
+	}
+}
+
+func TestDestinationsRejectSharedChats(t *testing.T) {
+	svc, _ := newDestinationService(t, &chatRecorder{}, NewMockNotificationLogRepo())
+	ctx := context.Background()
+
+	// the stats are counted per chat, so a shared chat would report another chat's sends
+	if _, err := svc.CreateTelegramDestination(ctx, 1, domain.TelegramDestinationInput{Name: "Менеджеры", ChatID: " -1001234567890"}); !errors.Is(err, domain.ErrDestinationExists) {
+		t.Fatalf("expected ErrDestinationExists for the integration's own chat, got %v", err)
+	}
+
+	owner := addDestination(t, svc, domain.TelegramDestinationInput{Name: "Владелец", ChatID: "555000111"})
+	night := addDestination(t, svc, domain.TelegramDestinationInput{Name: "Ночная смена", ChatID: "777000111"})
+
+	for _, chatID := range []string{"-1001234567890", "555000111"} {
+		if _, err := svc.UpdateTelegramDestination(ctx, 1, night.ID, domain.UpdateTelegramDestinationInput{ChatID: &chatID}); !errors.Is(err, domain.ErrDestinationExists) {
+			t.Fatalf("expected ErrDestinationExists when moving to %s, got %v", chatID, err)
+		}
+	}
+
+	name := "Владелец магазина"
+	if _, err := svc.UpdateTelegramDestination(ctx, 1, owner.ID, domain.UpdateTelegramDestinationInput{Name: &name}); err != nil {
+		t.Fatalf("renaming should not trip the chat check, got %v", err)
+	}
+}
+
+func TestDestinationsRequireTelegramIntegration(t *testing.T) {
+	svc := domain.NewService(&MockIntegrationRepo{}, &MockOrderRepo{}, NewMockNotificationLogRepo(), telegramNotifiers(&chatRecorder{}), 1,
+		domain.WithTelegramDestinations(&MockDestinationRepo{}))
+
+	_, err := svc.CreateTelegramDestination(context.Background(), 1, domain.TelegramDestinationInput{Name: "Владелец", ChatID: "555000111"})
+	if !errors.Is(err, domain.ErrShopNotIntegrated) {
+		t.Fatalf("expected ErrShopNotIntegrated, got %v", err)
+	}
+}
@@ -0,0 +1,399 @@
+package tests
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/domain"
+)
+
+type MockDigestRepo struct {
+	mu        sync.Mutex
+	schedules map[int64]domain.DigestSchedule
+	orders    *MockOrderRepo
+	failed    int64
+	markErr   error
+}
+
+func NewMockDigestRepo(orders *MockOrderRepo) *MockDigestRepo {
+	return &MockDigestRepo{schedules: map[int64]domain.DigestSchedule{}, orders: orders}
+}
+
+func (f *MockDigestRepo) Get(_ context.Context, shopID int64) (domain.DigestSchedule, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	schedule, ok := f.schedules[shopID]
+	return schedule, ok, nil
+}
+
+func (f *MockDigestRepo) Upsert(_ context.Context, shopID int64, input domain.DigestScheduleInput) (domain.DigestSchedule, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	schedule := f.schedules[shopID]
+	schedule.ShopID = shopID
+	schedule.Enabled = input.Enabled
+	schedule.Frequency = input.Frequency
+	schedule.Time = input.Time
+	schedule.Weekday = input.Weekday
+	schedule.Sections = input.Sections
+	schedule.TopCustomers = input.TopCustomers
+	schedule.UpdatedAt = time.Now()
+	f.schedules[shopID] = schedule
+	return schedule, nil
+}
+
+func (f *MockDigestRepo) ListEnabled(context.Context) ([]domain.DigestSchedule, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := []domain.DigestSchedule{}
+	for _, schedule := range f.schedules {
+		if schedule.Enabled {
+			out = append(out, schedule)
+		}
+	}
+	return out, nil
+}
+
+func (f *MockDigestRepo) MarkSent(_ context.Context, shopID int64, periodEnd, sentAt time.Time, errText *string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.markErr != nil {
+		return f.markErr
+	}
+	schedule := f.schedules[shopID]
+	schedule.LastPeriodEnd = &periodEnd
+	if errText == nil {
+		schedule.LastSentAt = &sentAt
+	}
+	schedule.LastError = errText
+	f.schedules[shopID] = schedule
+	return nil
+}
+
+func (f *MockDigestRepo) Summary(_ context.Context, shopID int64, from, to time.Time, top int) (domain.Digest, error) {
+	out := domain.Digest{ShopID: shopID, From: from, To: to, TopCustomers: []domain.CustomerTotal{}, FailedNotifications: f.failed}
+	byName := map[string]*domain.CustomerTotal{}
+
+	for _, order := range f.orders.created {
+		if order.ShopID != shopID || order.CreatedAt.Before(from) || !order.CreatedAt.Before(to) {
+			continue
+		}
+		out.OrderCount++
+		out.Revenue += order.Total
+		if byName[order.CustomerName] == nil {
+			byName[order.CustomerName] = &domain.CustomerTotal{Name: order.CustomerName}
+		}
+		byName[order.CustomerName].Orders++
+		byName[order.CustomerName].Total += order.Total
+	}
+	if out.OrderCount > 0 {
+		out.AverageCheck = out.Revenue / float64(out.OrderCount)
+	}
+	for _, customer := range byName {
+		out.TopCustomers = append(out.TopCustomers, *customer)
+	}
+	slices.SortFunc(out.TopCustomers, func(a, b domain.CustomerTotal) int {
+		return cmp.Or(cmp.Compare(b.Total, a.Total), cmp.Compare(a.Name, b.Name))
+	})
+	if len(out.TopCustomers) > top {
+		out.TopCustomers = out.TopCustomers[:top]
+	}
+	return out, nil
+}
+
+// mockLocker is a single-process domain.Locker.
+type mockLocker struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+func (l *mockLocker) TryLock(_ context.Context, name string) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.held == nil {
+		l.held = map[string]bool{}
+	}
+	if l.held[name] {
+		return nil, false, nil
+	}
+	l.held[name] = true
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.held, name)
+	}, true, nil
+}
+
+func newDigestService(t *testing.T, sender *messageRecorder, integrations *MockIntegrationRepo) (*domain.Service, *MockDigestRepo, *MockOrderRepo) {
+	t.Helper()
+
+	shops := NewMockShopRepo()
+	if _, err := domain.NewShopService(shops).CreateShop(context.Background(), domain.ShopInput{Name: "Demo", Timezone: "Asia/Vladivostok"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	orders := &MockOrderRepo{}
+	digests := NewMockDigestRepo(orders)
+	svc := domain.NewService(integrations, orders, NewMockNotificationLogRepo(), telegramNotifiers(sender), 1,
+		domain.WithShops(shops), domain.WithDigests(digests))
+	return svc, digests, orders
+}
+
+func TestDigestLatestRun(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	daily := domain.DigestSchedule{Frequency: domain.DigestDaily, Time: "09:00"}
+	// 2024-05-01 is a Wednesday
+	weekly := domain.DigestSchedule{Frequency: domain.DigestWeekly, Time: "09:00", Weekday: 1}
+
+	for _, tc := range []struct {
+		name     string
+		schedule domain.DigestSchedule
+		now      time.Time
+		want     time.Time
+	}{
+		{"daily after the time", daily, time.Date(2024, 5, 1, 9, 30, 0, 0, moscow), time.Date(2024, 5, 1, 9, 0, 0, 0, moscow)},
+		{"daily at the time", daily, time.Date(2024, 5, 1, 9, 0, 0, 0, moscow), time.Date(2024, 5, 1, 9, 0, 0, 0, moscow)},
+		{"daily before the time", daily, time.Date(2024, 5, 1, 8, 59, 0, 0, moscow), time.Date(2024, 4, 30, 9, 0, 0, 0, moscow)},
+		{"daily in the shop's zone", daily, time.Date(2024, 5, 1, 5, 30, 0, 0, time.UTC), time.Date(2024, 4, 30, 9, 0, 0, 0, moscow)},
+		{"weekly midweek", weekly, time.Date(2024, 5, 1, 12, 0, 0, 0, moscow), time.Date(2024, 4, 29, 9, 0, 0, 0, moscow)},
+		{"weekly on the day before the time", weekly, time.Date(2024, 5, 6, 8, 0, 0, 0, moscow), time.Date(2024, 4, 29, 9, 0, 0, 0, moscow)},
+		{"weekly on the day after the time", weekly, time.Date(2024, 5, 6, 10, 0, 0, 0, moscow), time.Date(2024, 5, 6, 9, 0, 0, 0, moscow)},
+		{"weekly on sunday", domain.DigestSchedule{Frequency: domain.DigestWeekly, Time: "20:00", Weekday: 7}, time.Date(2024, 5, 5, 21, 0, 0, 0, moscow), time.Date(2024, 5, 5, 20, 0, 0, 0, moscow)},
+	} {
+		if got := tc.schedule.LatestRun(tc.now, moscow); !got.Equal(tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestSendDueDigestsSendsEachRunOnce(t *testing.T) {
+	sender := &messageRecorder{}
+	integrations := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "123456:bot-token", "-1001234567890", true)}}
+	svc, digests, orders := newDigestService(t, sender, integrations)
+	ctx := context.Background()
+
+	schedule, err := svc.UpdateDigestSchedule(ctx, 1, domain.DigestScheduleInput{Enabled: true, Frequency: domain.DigestDaily, Time: "9:00", TopCustomers: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	digests.failed = 1
+
+	loc, _ := time.LoadLocation("Asia/Vladivostok")
+	run := schedule.LatestRun(time.Now(), loc).AddDate(0, 0, 1)
+	orders.created = []domain.Order{
+		{ID: 1, ShopID: 1, Number: "A-1", Total: 100, CustomerName: "Анна", CreatedAt: run.Add(-2 * time.Hour)},
+		{ID: 2, ShopID: 1, Number: "A-2", Total: 300, CustomerName: "Борис", CreatedAt: run.Add(-3 * time.Hour)},
+		{ID: 3, ShopID: 1, Number: "A-3", Total: 50, CustomerName: "Анна", CreatedAt: run.Add(-4 * time.Hour)},
+		{ID: 4, ShopID: 1, Number: "A-4", Total: 900, CustomerName: "Вера", CreatedAt: run.Add(-25 * time.Hour)},
+		{ID: 5, ShopID: 1, Number: "A-5", Total: 700, CustomerName: "Глеб", CreatedAt: run.Add(time.Minute)},
+	}
+
+	if sent, err := svc.SendDueDigests(ctx, time.Now()); err != nil || sent != 0 {
+		t.Fatalf("a run before the schedule was saved must not be sent, got %d %v", sent, err)
+	}
+	if sent, err := svc.SendDueDigests(ctx, run.Add(5*time.Minute)); err != nil || sent != 1 {
+		t.Fatalf("expected one digest, got %d %v", sent, err)
+	}
+	if sent, _ := svc.SendDueDigests(ctx, run.Add(6*time.Minute)); sent != 0 {
+		t.Fatalf("a run must be sent once, got %d more", sent)
+	}
+
+	got := sender.sent()
+	if len(got) != 1 {
+		t.Fatalf("expected one message, got %+v", got)
+	}
+	for _, want := range []string{"Заказов: 3", "Выручка: 450.00 ₽", "Средний чек: 150.00 ₽", "1. Борис — 300.00 ₽ (заказов: 1)", "2. Анна — 150.00 ₽ (заказов: 2)", "Неотправленных уведомлений: 1"} {
+		if !strings.Contains(got[0].text, want) {
+			t.Errorf("expected %q in the digest:\n%s", want, got[0].text)
+		}
+	}
+	if strings.Contains(got[0].text, "Вера") || strings.Contains(got[0].text, "Глеб") {
+		t.Errorf("the digest must cover the day before the run only:\n%s", got[0].text)
+	}
+
+	schedule, err = svc.GetDigestSchedule(ctx, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if schedule.LastPeriodEnd == nil || !schedule.LastPeriodEnd.Equal(run) || schedule.LastSentAt == nil || schedule.LastError != nil {
+		t.Fatalf("expected the run to be recorded, got %+v", schedule)
+	}
+}
+
+func TestDigestSectionsAreConfigurable(t *testing.T) {
+	sender := &messageRecorder{}
+	integrations := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "123456:bot-token", "-1001234567890", true)}}
+	svc, _, _ := newDigestService(t, sender, integrations)
+	ctx := context.Background()
+
+	input := domain.DigestScheduleInput{
+		Enabled:   true,
+		Frequency: domain.DigestWeekly,
+		Weekday:   3,
+		Sections:  []domain.DigestSection{domain.DigestSectionRevenue, domain.DigestSectionOrders},
+	}
+	schedule, err := svc.UpdateDigestSchedule(ctx, 1, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if schedule.Time != "09:00" || schedule.TopCustomers != 3 || !slices.Equal(schedule.Sections, []domain.DigestSection{domain.DigestSectionOrders, domain.DigestSectionRevenue}) {
+		t.Fatalf("expected defaults and sections in digest order, got %+v", schedule)
+	}
+
+	preview, err := svc.PreviewDigest(ctx, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(preview.Text, "📊 Сводка за неделю") || !strings.Contains(preview.Text, "Заказов: 0") ||
+		strings.Contains(preview.Text, "Средний чек") || strings.Contains(preview.Text, "Неотправленных") {
+		t.Fatalf("unexpected preview:\n%s", preview.Text)
+	}
+	if preview.To.Sub(preview.From) < 6*24*time.Hour {
+		t.Fatalf("a weekly preview must cover a week, got %v - %v", preview.From, preview.To)
+	}
+	if len(sender.sent()) != 0 {
+		t.Fatalf("a preview must not be sent, got %+v", sender.sent())
+	}
+}
+
+func TestDigestFailureIsRecordedAndNotRetried(t *testing.T) {
+	sender := &messageRecorder{}
+	integrations := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "123456:bot-token", "-1001234567890", false)}}
+	svc, _, _ := newDigestService(t, sender, integrations)
+	ctx := context.Background()
+
+	if _, err := svc.UpdateDigestSchedule(ctx, 1, domain.DigestScheduleInput{Enabled: true, Frequency: domain.DigestDaily}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now().Add(25 * time.Hour)
+	if sent, err := svc.SendDueDigests(ctx, now); err != nil || sent != 0 {
+		t.Fatalf("expected no digest, got %d %v", sent, err)
+	}
+
+	schedule, _ := svc.GetDigestSchedule(ctx, 1)
+	if schedule.LastError == nil || !strings.Contains(*schedule.LastError, "telegram") || schedule.LastSentAt != nil {
+		t.Fatalf("expected the failure to be recorded, got %+v", schedule)
+	}
+
+	integrations.items[0].Enabled = true
+	if sent, _ := svc.SendDueDigests(ctx, now.Add(time.Minute)); sent != 0 {
+		t.Fatalf("a failed run must wait for the next one, got %d", sent)
+	}
+	if sent, _ := svc.SendDueDigests(ctx, now.Add(24*time.Hour)); sent != 1 {
+		t.Fatalf("expected the next run to be sent, got %d", sent)
+	}
+}
+
+func TestDigestMarkFailureDoesNotStopTheRun(t *testing.T) {
+	sender := &messageRecorder{}
+	integrations := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "123456:bot-token", "-1001234567890", true)}}
+	svc, digests, _ := newDigestService(t, sender, integrations)
+	ctx := context.Background()
+
+	if _, err := svc.UpdateDigestSchedule(ctx, 1, domain.DigestScheduleInput{Enabled: true, Frequency: domain.DigestDaily}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	digests.markErr = errors.New("db down")
+
+	if sent, err := svc.SendDueDigests(ctx, time.Now().Add(25*time.Hour)); err != nil || sent != 1 {
+		t.Fatalf("a sent digest must be counted when it cannot be recorded, got %d %v", sent, err)
+	}
+	if got := sender.sent(); len(got) != 1 {
+		t.Fatalf("expected the digest to be sent, got %+v", got)
+	}
+}
+
+func TestDigestRetriesStopWhenCancelled(t *testing.T) {
+	shops := NewMockShopRepo()
+	if _, err := domain.NewShopService(shops).CreateShop(context.Background(), domain.ShopInput{Name: "Demo"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sendErr := errors.New("telegram is down")
+	client := &MockTelegramClient{errs: []error{sendErr, sendErr, sendErr}}
+	integrations := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "123456:bot-token", "-1001234567890", true)}}
+	digests := NewMockDigestRepo(&MockOrderRepo{})
+	svc := domain.NewService(integrations, &MockOrderRepo{}, NewMockNotificationLogRepo(), telegramNotifiers(client), 3,
+		domain.WithShops(shops), domain.WithDigests(digests))
+
+	if _, err := svc.UpdateDigestSchedule(context.Background(), 1, domain.DigestScheduleInput{Enabled: true, Frequency: domain.DigestDaily}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := svc.SendDueDigests(ctx, time.Now().Add(25*time.Hour)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the run to stop with the context, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("the retry wait should end with the context, took %v", elapsed)
+	}
+	if client.Calls() != 1 {
+		t.Fatalf("expected one attempt before the cancel, got %d", client.Calls())
+	}
+
+	schedule, _ := svc.GetDigestSchedule(context.Background(), 1)
+	if schedule.LastError == nil || !strings.Contains(*schedule.LastError, "retries stopped") {
+		t.Fatalf("expected the interrupted run to be recorded, got %+v", schedule)
+	}
+}
+
+func TestDigestScheduleValidation(t *testing.T) {
+	svc, _, _ := newDigestService(t, &messageRecorder{}, &MockIntegrationRepo{})
+
+	status, problem := doProblem(t, svc, http.MethodPut, "/v2/shops/1/digest",
+		`{"frequency":"hourly","time":"25:00","weekday":8,"sections":["orders","weather"],"topCustomers":11}`)
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d %+v", status, problem)
+	}
+
+	fields := map[string]bool{}
+	for _, field := range problem.Errors {
+		fields[field.Field] = true
+	}
+	for _, want := range []string{"frequency", "time", "weekday", "sections", "topCustomers"} {
+		if !fields[want] {
+			t.Errorf("expected a field error for %s, got %+v", want, problem.Errors)
+		}
+	}
+}
+
+func TestSchedulerRunsJobUnderLock(t *testing.T) {
+	locker := &mockLocker{}
+	scheduler := domain.NewScheduler(locker, slog.New(slog.DiscardHandler))
+	runs := 0
+	job := domain.Job{Name: "digests", Every: time.Minute, Run: func(context.Context, time.Time) error {
+		runs++
+		return nil
+	}}
+
+	unlock, _, _ := locker.TryLock(context.Background(), "job:digests")
+	if scheduler.RunJob(context.Background(), job, time.Now()) || runs != 0 {
+		t.Fatalf("a job locked by another instance must not run")
+	}
+	unlock()
+
+	if !scheduler.RunJob(context.Background(), job, time.Now()) || runs != 1 {
+		t.Fatalf("expected the job to run, got %d runs", runs)
+	}
+	if !scheduler.RunJob(context.Background(), job, time.Now()) || runs != 2 {
+		t.Fatalf("the lock must be released after a run, got %d runs", runs)
+	}
+}
@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/adapters/egress"
+	"growth-mvp/backend/adapters/webhook"
+	"growth-mvp/backend/domain"
+)
+
+func TestEgressBlocksInternalAddresses(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1":       true,
+		"10.1.2.3":        true,
+		"172.16.0.1":      true,
+		"192.168.1.1":     true,
+		"169.254.169.254": true,
+		"100.64.0.1":      true,
+		"0.0.0.0":         true,
+		"::1":             true,
+		"fd00:ec2::254":   true,
+		"fe80::1":         true,
+		"::ffff:10.0.0.1": true,
+		"93.184.216.34":   false,
+		"2606:4700::1111": false,
+	} {
+		if got := egress.Blocked(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("Blocked(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestWebhookRefusesInternalTargetsAtDialTime(t *testing.T) {
+	var hits int
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	notifier := webhook.NewNotifier(egress.Config{Timeout: time.Second})
+	port := server.URL[strings.LastIndexByte(server.URL, ':'):]
+
+	// a host name is only checked once resolved, so it cannot rebind past the guard
+	for _, target := range []string{server.URL, "https://localhost" + port} {
+		integration := domain.Integration{Settings: map[string]string{domain.WebhookSettingURL: target}, Secret: webhookSecret}
+
+		err := notifier.Send(context.Background(), integration, domain.Notification{ID: 1, Event: domain.WebhookEventOrderCreated, Text: "{}"})
+		if !errors.Is(err, egress.ErrBlockedAddress) {
+			t.Fatalf("%s: expected the dial to be refused, got %v", target, err)
+		}
+	}
+	if hits != 0 {
+		t.Fatalf("the internal server should not be reached, got %d requests", hits)
+	}
+}
@@ -0,0 +1,386 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/adapters/egress"
+	"growth-mvp/backend/adapters/email"
+	"growth-mvp/backend/domain"
+)
+
+type smtpMessage struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// smtpStandIn is an in-process SMTP server that speaks just enough of the
+// protocol for net/smtp: EHLO, AUTH PLAIN, MAIL, RCPT, DATA and QUIT.
+// Recipients in reject are refused with 550.
+type smtpStandIn struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	reject   map[string]bool
+	messages []smtpMessage
+}
+
+func newSMTPStandIn(t *testing.T, reject ...string) *smtpStandIn {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	s := &smtpStandIn{listener: listener, reject: map[string]bool{}}
+	for _, address := range reject {
+		s.reject[address] = true
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpStandIn) port() string {
+	return strconv.Itoa(s.listener.Addr().(*net.TCPAddr).Port)
+}
+
+func (s *smtpStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+	var msg smtpMessage
+
+	reply("220 localhost ESMTP stand-in")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			fields := strings.Fields(line)
+			if len(fields) == 3 {
+				decoded, _ := base64.StdEncoding.DecodeString(fields[2])
+				msg.auth = string(decoded)
+			}
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			msg.from = strings.Trim(strings.TrimPrefix(line[len("MAIL FROM:"):], " "), "<>")
+			reply("250 OK")
+		case "RCPT":
+			to := strings.Trim(line[len("RCPT TO:"):], " <>")
+			s.mu.Lock()
+			rejected := s.reject[to]
+			s.mu.Unlock()
+			if rejected {
+				reply("550 5.1.1 mailbox unavailable")
+				continue
+			}
+			msg.to = append(msg.to, to)
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			msg.data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			msg = smtpMessage{auth: msg.auth}
+			reply("250 OK queued")
+		case "RSET", "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+func (s *smtpStandIn) accept(address string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.reject, address)
+}
+
+func (s *smtpStandIn) wait(t *testing.T, want int) []smtpMessage {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		got := append([]smtpMessage(nil), s.messages...)
+		s.mu.Unlock()
+		if len(got) >= want {
+			return got
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected %d emails", want)
+	return nil
+}
+
+// parseEmail returns the decoded subject and the text and HTML parts.
+func parseEmail(t *testing.T, data string) (subject, text, html string) {
+	t.Helper()
+
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("read message: %v", err)
+	}
+	subject, err = new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("decode subject: %v", err)
+	}
+
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("content type: %v", err)
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := parts.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read part: %v", err)
+		}
+		body, _ := io.ReadAll(quotedprintable.NewReader(part))
+		switch {
+		case strings.HasPrefix(part.Header.Get("Content-Type"), "text/plain"):
+			text = string(body)
+		case strings.HasPrefix(part.Header.Get("Content-Type"), "text/html"):
+			html = string(body)
+		}
+	}
+	return subject, text, html
+}
+
+func newEmailService(integrations *MockIntegrationRepo, logs *MockNotificationLogRepo, global email.Config) *domain.Service {
+	// the SMTP stand-in listens on loopback, which the operator has to allow
+	outbound := egress.Config{Timeout: time.Second, AllowedHosts: []string{"127.0.0.1"}}
+	notifiers := domain.NewNotifierRegistry().Register(domain.ChannelEmail, email.NewNotifier(global, outbound))
+	return domain.NewService(integrations, &MockOrderRepo{}, logs, notifiers, 1)
+}
+
+func TestEmailSendsTextAndHTMLThroughShopServer(t *testing.T) {
+	server := newSMTPStandIn(t)
+	integrations := &MockIntegrationRepo{}
+	logs := NewMockNotificationLogRepo()
+	svc := newEmailService(integrations, logs, email.Config{})
+	ctx := context.Background()
+
+	integration, err := svc.ConnectIntegration(ctx, 1, domain.ChannelEmail, domain.ConnectIntegrationInput{
+		Settings: map[string]string{
+			"to": "owner@shop.example", "from": "Shop <noreply@shop.example>",
+			"host": "127.0.0.1", "port": server.port(), "username": "mailer", "tls": email.TLSNone,
+		},
+		Secret:  "smtp-password",
+		Enabled: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if integration.Settings["to"] != "o***@shop.example" {
+		t.Fatalf("expected masked recipients, got %+v", integration.Settings)
+	}
+
+	out, err := svc.CreateOrder(ctx, 1, domain.CreateOrderInput{Number: "A-1", Total: 1990.5, CustomerName: "Анна <b>"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg := server.wait(t, 1)[0]
+	if msg.from != "noreply@shop.example" || len(msg.to) != 1 || msg.to[0] != "owner@shop.example" {
+		t.Fatalf("unexpected envelope %+v", msg)
+	}
+	if msg.auth != "\x00mailer\x00smtp-password" {
+		t.Fatalf("expected AUTH PLAIN with the integration secret, got %q", msg.auth)
+	}
+
+	subject, text, html := parseEmail(t, msg.data)
+	if subject != "Новый заказ A-1" {
+		t.Fatalf("unexpected subject %q", subject)
+	}
+	if text != domain.RenderOrderMessage(out.Order).Text {
+		t.Fatalf("text part should reuse the order message, got %q", text)
+	}
+	if !strings.Contains(html, "1990.50") || !strings.Contains(html, "Анна &lt;b&gt;") {
+		t.Fatalf("unexpected html part %q", html)
+	}
+
+	waitForKeyStatus(t, logs, recipientKey(integration.ID, out.Order.ID, "owner@shop.example"), domain.NotificationStatusSent, time.Second)
+}
+
+func TestEmailLogsEveryRecipientSeparately(t *testing.T) {
+	server := newSMTPStandIn(t, "gone@shop.example")
+	integrations := &MockIntegrationRepo{}
+	logs := NewMockNotificationLogRepo()
+	svc := newEmailService(integrations, logs, email.Config{Host: "127.0.0.1", Port: mustAtoi(t, server.port()), From: "noreply@growth.example", TLS: email.TLSNone})
+	ctx := context.Background()
+
+	integration, err := svc.ConnectIntegration(ctx, 1, domain.ChannelEmail, domain.ConnectIntegrationInput{
+		Settings: map[string]string{"to": "owner@shop.example, gone@shop.example"},
+		Enabled:  true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out, err := svc.CreateOrder(ctx, 1, domain.CreateOrderInput{Number: "A-1", Total: 10, CustomerName: "Anna"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sent := recipientKey(integration.ID, out.Order.ID, "owner@shop.example")
+	rejected := recipientKey(integration.ID, out.Order.ID, "gone@shop.example")
+	waitForKeyStatus(t, logs, sent, domain.NotificationStatusSent, time.Second)
+
+	if errText := waitForLogError(t, logs, rejected, time.Second); !strings.Contains(errText, "550") {
+		t.Fatalf("expected the SMTP rejection in the log, got %q", errText)
+	}
+
+	if got := server.wait(t, 1); len(got) != 1 || got[0].from != "noreply@growth.example" {
+		t.Fatalf("expected one email from the global sender, got %+v", got)
+	}
+
+	server.accept("gone@shop.example")
+	if _, err := svc.ResendOrder(ctx, 1, out.Order.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForKeyStatus(t, logs, rejected, domain.NotificationStatusSent, time.Second)
+
+	if got := server.wait(t, 2); len(got) != 2 || got[1].to[0] != "gone@shop.example" {
+		t.Fatalf("resend should only retry the failed recipient, got %+v", got)
+	}
+}
+
+func TestEmailValidatesSettings(t *testing.T) {
+	svc := newEmailService(&MockIntegrationRepo{}, NewMockNotificationLogRepo(), email.Config{})
+
+	status, problem := doProblem(t, svc, http.MethodPost, "/v2/shops/1/integrations/email/connect",
+		`{"settings":{"to":"not-an-address","from":"Shop <noreply@shop.example>","tls":"ssl3"}}`)
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d %+v", status, problem)
+	}
+
+	fields := map[string]bool{}
+	for _, field := range problem.Errors {
+		fields[field.Field] = true
+	}
+	for _, want := range []string{"settings.to", "settings.tls", "settings.host", "settings.from"} {
+		if !fields[want] {
+			t.Errorf("expected a field error for %s, got %+v", want, problem.Errors)
+		}
+	}
+}
+
+func mustAtoi(t *testing.T, s string) int {
+	t.Helper()
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		t.Fatalf("atoi %q: %v", s, err)
+	}
+	return n
+}
+
+func TestEmailRelayIgnoresShopSender(t *testing.T) {
+	server := newSMTPStandIn(t)
+	integrations := &MockIntegrationRepo{items: []domain.Integration{{
+		ID: 1, ShopID: 1, Channel: domain.ChannelEmail, Enabled: true,
+		// saved before "from" required "host"
+		Settings: map[string]string{"to": "owner@shop.example", "from": "ceo@bank.example"},
+	}}}
+	svc := newEmailService(integrations, NewMockNotificationLogRepo(), email.Config{Host: "127.0.0.1", Port: mustAtoi(t, server.port()), From: "noreply@growth.example", TLS: email.TLSNone})
+
+	if _, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{Number: "A-1", Total: 10, CustomerName: "Anna"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg := server.wait(t, 1)[0]
+	if msg.from != "noreply@growth.example" || strings.Contains(string(msg.data), "ceo@bank.example") {
+		t.Fatalf("the global relay should send as the operator, got %q", msg.from)
+	}
+}
+
+func TestEmailRefusesInternalShopServer(t *testing.T) {
+	server := newSMTPStandIn(t)
+	logs := NewMockNotificationLogRepo()
+	notifiers := domain.NewNotifierRegistry().Register(domain.ChannelEmail, email.NewNotifier(email.Config{}, egress.Config{Timeout: time.Second}))
+	svc := domain.NewService(&MockIntegrationRepo{}, &MockOrderRepo{}, logs, notifiers, 1)
+	ctx := context.Background()
+
+	integration, err := svc.ConnectIntegration(ctx, 1, domain.ChannelEmail, domain.ConnectIntegrationInput{
+		Settings: map[string]string{"to": "owner@shop.example", "from": "noreply@shop.example", "host": "127.0.0.1", "port": server.port(), "tls": email.TLSNone},
+		Enabled:  true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out, err := svc.CreateOrder(ctx, 1, domain.CreateOrderInput{Number: "A-1", Total: 10, CustomerName: "Anna"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	errText := waitForLogError(t, logs, recipientKey(integration.ID, out.Order.ID, "owner@shop.example"), time.Second)
+	if !strings.Contains(errText, egress.ErrBlockedAddress.Error()) {
+		t.Fatalf("expected the dial to be refused, got %q", errText)
+	}
+}
+
+func TestEmailRejectsCredentialsInClearText(t *testing.T) {
+	svc := newEmailService(&MockIntegrationRepo{}, NewMockNotificationLogRepo(), email.Config{})
+
+	status, problem := doProblem(t, svc, http.MethodPost, "/v2/shops/1/integrations/email/connect",
+		`{"settings":{"to":"owner@shop.example","from":"noreply@shop.example","host":"smtp.shop.example","username":"mailer","tls":"none"},"secret":"pw"}`)
+	if status != http.StatusBadRequest || len(problem.Errors) != 1 || problem.Errors[0].Field != "settings.tls" || problem.Errors[0].Code != "conflict" {
+		t.Fatalf("expected a settings.tls conflict, got %d %+v", status, problem.Errors)
+	}
+}
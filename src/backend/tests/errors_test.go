@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"growth-mvp/backend/api"
+	"growth-mvp/backend/domain"
+)
+
+type failingIntegrationRepo struct {
+	MockIntegrationRepo
+}
+
+func (f *failingIntegrationRepo) Get(_ context.Context, _ int64, _ domain.ChannelType) (domain.Integration, bool, error) {
+	return domain.Integration{}, false, errors.New("pgx: connection refused for user growth")
+}
+
+func doProblem(t *testing.T, svc *domain.Service, method, path, body string) (int, api.Problem) {
+	t.Helper()
+
+	router := newTestRouter(t, svc, domain.NewAPIKeyService(NewMockAPIKeyRepo(), "admin-secret"), newAuthService())
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	req.Header.Set("X-Request-ID", "req-7")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Type"); got != "application/problem+json" {
+		t.Fatalf("expected problem+json, got %q: %s", got, rec.Body.String())
+	}
+
+	var problem api.Problem
+	decodeJSON(t, rec.Body.Bytes(), &problem)
+
+	if problem.Status != rec.Code || problem.RequestID != "req-7" || problem.Instance != path[:strings.IndexByte(path+"?", '?')] {
+		t.Fatalf("inconsistent problem %+v for status %d", problem, rec.Code)
+	}
+
+	return rec.Code, problem
+}
+
+func TestValidationErrorsArePerField(t *testing.T) {
+	code, problem := doProblem(t, nil, http.MethodPost, "/shops/1/orders", `{"number":"","total":-5}`)
+
+	if code != http.StatusBadRequest || problem.Code != "invalid_input" {
+		t.Fatalf("unexpected problem %d %+v", code, problem)
+	}
+
+	got := map[string]string{}
+	for _, field := range problem.Errors {
+		got[field.Field] = field.Code
+	}
+
+	want := map[string]string{"number": "required", "total": "gt", "customerName": "required"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected field errors %v, got %v", want, got)
+	}
+}
+
+func TestMalformedJSONAndTypeErrors(t *testing.T) {
+	if code, problem := doProblem(t, nil, http.MethodPost, "/shops/1/orders", `{"number":`); code != http.StatusBadRequest || problem.Detail != "invalid input: malformed JSON body" {
+		t.Fatalf("unexpected problem %d %+v", code, problem)
+	}
+
+	code, problem := doProblem(t, nil, http.MethodPost, "/shops/1/orders", `{"number":"A-1","total":"ten","customerName":"Anna"}`)
+	if code != http.StatusBadRequest || len(problem.Errors) != 1 || problem.Errors[0].Field != "total" {
+		t.Fatalf("unexpected problem %d %+v", code, problem)
+	}
+	// orders.total is NUMERIC(12,2)
+	code, problem = doProblem(t, nil, http.MethodPost, "/shops/1/orders", `{"number":"A-1","total":12345678901,"customerName":"Anna"}`)
+	if code != http.StatusBadRequest || len(problem.Errors) != 1 || problem.Errors[0].Field != "total" || problem.Errors[0].Code != "lte" {
+		t.Fatalf("unexpected problem %d %+v", code, problem)
+	}
+}
+
+func TestDomainErrorsKeepTheirCodes(t *testing.T) {
+	if code, problem := doProblem(t, nil, http.MethodGet, "/shops/999", ""); code != http.StatusNotFound || problem.Code != "shop_not_found" {
+		t.Fatalf("unexpected problem %d %+v", code, problem)
+	}
+
+	if code, problem := doProblem(t, nil, http.MethodGet, "/shops/abc/orders", ""); code != http.StatusBadRequest || problem.Errors[0].Field != "shopId" {
+		t.Fatalf("unexpected problem %d %+v", code, problem)
+	}
+
+	wrapped := fmt.Errorf("update: %w", domain.InvalidField("chatId", "required", "chatId must be non-empty"))
+	if !errors.Is(wrapped, domain.ErrInvalidInput) || errors.Is(wrapped, domain.ErrShopNotFound) {
+		t.Fatalf("field errors must match ErrInvalidInput only")
+	}
+}
+
+func TestInternalErrorsAreNotLeaked(t *testing.T) {
+	svc := domain.NewService(&failingIntegrationRepo{}, &MockOrderRepo{}, NewMockNotificationLogRepo(), telegramNotifiers(&MockTelegramClient{}), 3)
+	code, problem := doProblem(t, svc, http.MethodGet, "/shops/1/telegram/status", "")
+
+	if code != http.StatusInternalServerError || problem.Code != "internal" || strings.Contains(problem.Detail, "pgx") {
+		t.Fatalf("unexpected problem %d %+v", code, problem)
+	}
+}
@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"growth-mvp/backend/adapters/telegram"
+	"growth-mvp/backend/api"
+	"growth-mvp/backend/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+type healthResponse struct {
+	Status string `json:"status"`
+	Checks map[string]struct {
+		Status string `json:"status"`
+	} `json:"checks"`
+}
+
+func healthRouter(t *testing.T, health *api.Health) *gin.Engine {
+	t.Helper()
+	return newTestRouter(t, nil, domain.NewAPIKeyService(NewMockAPIKeyRepo(), ""), newAuthService(), api.WithHealth(health))
+}
+
+func probe(t *testing.T, router *gin.Engine, path string) (int, healthResponse) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var out healthResponse
+	decodeJSON(t, rec.Body.Bytes(), &out)
+	return rec.Code, out
+}
+
+func staticCheck(name string, critical bool, detail string, err error) api.HealthCheck {
+	return api.HealthCheck{Name: name, Critical: critical, Check: func(context.Context) (string, error) {
+		return detail, err
+	}}
+}
+
+func TestReadyzReportsEveryDependency(t *testing.T) {
+	health := api.NewHealth(0,
+		staticCheck("database", true, "", nil),
+		staticCheck("migrations", true, "version 8", nil),
+	)
+	router := healthRouter(t, health)
+
+	code, out := probe(t, router, "/readyz")
+
+	if code != http.StatusOK || out.Status != "ok" {
+		t.Fatalf("unexpected readiness %d %+v", code, out)
+	}
+	if len(out.Checks) != 2 || out.Checks["migrations"].Status != "ok" || out.Checks["database"].Status != "ok" {
+		t.Fatalf("unexpected checks %+v", out.Checks)
+	}
+
+	if code, out := probe(t, router, "/livez"); code != http.StatusOK || out.Status != "ok" {
+		t.Fatalf("unexpected liveness %d %+v", code, out)
+	}
+}
+
+func TestReadyzFailsOnlyOnCriticalChecks(t *testing.T) {
+	degraded := healthRouter(t, api.NewHealth(0,
+		staticCheck("database", true, "", nil),
+		staticCheck("telegram", false, "", errors.New("telegram unreachable")),
+	))
+
+	code, out := probe(t, degraded, "/readyz")
+	if code != http.StatusOK || out.Status != "degraded" || out.Checks["telegram"].Status != "fail" {
+		t.Fatalf("expected degraded readiness, got %d %+v", code, out)
+	}
+
+	unavailable := healthRouter(t, api.NewHealth(0,
+		staticCheck("database", true, "", errors.New("connection refused")),
+		staticCheck("telegram", false, "", errors.New("telegram unreachable")),
+	))
+
+	code, out = probe(t, unavailable, "/readyz")
+	if code != http.StatusServiceUnavailable || out.Status != "unavailable" {
+		t.Fatalf("expected unavailable readiness, got %d %+v", code, out)
+	}
+	if out.Checks["database"].Status != "fail" {
+		t.Fatalf("expected the database check to fail, got %+v", out.Checks)
+	}
+}
+
+func TestReadyzLogsFailuresWithoutExposingThem(t *testing.T) {
+	logs := &logBuffer{}
+	gin.SetMode(gin.TestMode)
+	health := api.NewHealth(0,
+		staticCheck("database", true, "", errors.New("dial tcp 10.0.3.7:5432: connection refused")),
+		staticCheck("telegram", false, "https://api.telegram.org", nil),
+	)
+	handler := api.NewHandler(domain.NewService(&MockIntegrationRepo{}, &MockOrderRepo{}, NewMockNotificationLogRepo(), telegramNotifiers(&MockTelegramClient{}), 1),
+		domain.NewShopService(NewMockShopRepo()), domain.NewAPIKeyService(NewMockAPIKeyRepo(), ""), newAuthService(), api.WithHealth(health))
+	router := gin.New()
+	router.Use(api.RequestID(), api.RequestLogger(slog.New(slog.NewJSONHandler(logs, nil))))
+	handler.RegisterRoutes(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if body := rec.Body.String(); rec.Code != http.StatusServiceUnavailable || strings.Contains(body, "10.0.3.7") || strings.Contains(body, "telegram.org") {
+		t.Fatalf("expected a bare 503, got %d %s", rec.Code, body)
+	}
+
+	entry := logs.find(t, "health check failed")
+	if entry["check"] != "database" || entry["critical"] != true || !strings.Contains(fmt.Sprint(entry["error"]), "10.0.3.7:5432") {
+		t.Fatalf("expected the database failure to be logged, got %v", entry)
+	}
+}
+
+func TestReadyzFailsWhileDraining(t *testing.T) {
+	health := api.NewHealth(0, staticCheck("database", true, "", nil))
+	router := healthRouter(t, health)
+
+	health.SetDraining()
+
+	if code, out := probe(t, router, "/readyz"); code != http.StatusServiceUnavailable || out.Status != "draining" {
+		t.Fatalf("expected draining readiness, got %d %+v", code, out)
+	}
+	if code, _ := probe(t, router, "/livez"); code != http.StatusOK {
+		t.Fatalf("liveness should not fail while draining, got %d", code)
+	}
+}
+
+func TestTelegramClientUsesConfiguredBaseURL(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		_, _ = io.WriteString(w, `{"ok":true}`)
+	}))
+	defer server.Close()
+
+	client := telegram.NewClient(server.URL+"/", 0)
+
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("unexpected ping error: %v", err)
+	}
+	if _, err := client.SendMessage(context.Background(), "123:abc", "chat", "hello", false); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	if len(paths) != 2 || paths[0] != "HEAD /" || paths[1] != "POST /bot123:abc/sendMessage" {
+		t.Fatalf("unexpected requests %v", paths)
+	}
+}
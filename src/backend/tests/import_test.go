@@ -0,0 +1,300 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/api"
+	"growth-mvp/backend/domain"
+)
+
+func importRows(n int) []domain.ImportOrderRow {
+	rows := make([]domain.ImportOrderRow, 0, n)
+	for i := 0; i < n; i++ {
+		rows = append(rows, domain.ImportOrderRow{
+			Line: i + 2,
+			Order: domain.CreateOrderInput{
+				Number:       "A-" + string(rune('a'+i)),
+				Total:        100,
+				CustomerName: "Anna",
+			},
+		})
+	}
+	return rows
+}
+
+func TestImportOrdersReportsPerRow(t *testing.T) {
+	orderRepo := &MockOrderRepo{}
+	svc := domain.NewService(&MockIntegrationRepo{}, orderRepo, NewMockNotificationLogRepo(), telegramNotifiers(&MockTelegramClient{}), 3)
+
+	rows := importRows(3)
+	rows[1].Err = errors.New("invalid total")
+
+	out, err := svc.ImportOrders(context.Background(), 1, rows, domain.ImportOrdersOptions{Notify: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if out.Total != 3 || out.Imported != 2 || out.Invalid != 1 || out.Failed != 0 {
+		t.Fatalf("unexpected counters: %+v", out)
+	}
+	if out.Rows[1].Status != domain.ImportRowStatusInvalid || out.Rows[1].Error == nil {
+		t.Fatalf("expected row 2 to be invalid, got %+v", out.Rows[1])
+	}
+	for _, i := range []int{0, 2} {
+		if out.Rows[i].Status != domain.ImportRowStatusImported || out.Rows[i].OrderID == nil {
+			t.Fatalf("expected row %d to be imported, got %+v", i, out.Rows[i])
+		}
+		if out.Rows[i].Line != rows[i].Line {
+			t.Fatalf("expected line %d, got %d", rows[i].Line, out.Rows[i].Line)
+		}
+	}
+}
+
+func TestImportOrdersMarksFailedBatch(t *testing.T) {
+	orderRepo := &MockOrderRepo{batchErrs: []error{nil, errors.New("insert failed")}}
+	svc := domain.NewService(&MockIntegrationRepo{}, orderRepo, NewMockNotificationLogRepo(), telegramNotifiers(&MockTelegramClient{}), 3)
+
+	out, err := svc.ImportOrders(context.Background(), 1, importRows(5), domain.ImportOrdersOptions{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if orderRepo.batchCalls != 3 {
+		t.Fatalf("expected 3 batches, got %d", orderRepo.batchCalls)
+	}
+	if out.Imported != 3 || out.Failed != 2 {
+		t.Fatalf("unexpected counters: %+v", out)
+	}
+	if out.Rows[2].Status != domain.ImportRowStatusFailed || out.Rows[3].Status != domain.ImportRowStatusFailed {
+		t.Fatalf("expected second batch to fail, got %+v", out.Rows)
+	}
+}
+
+func TestImportOrdersWithoutNotifySkipsTelegram(t *testing.T) {
+	integrationRepo := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "token", "chat", true)}}
+	sendLogRepo := NewMockNotificationLogRepo()
+	telegramClient := &MockTelegramClient{}
+	svc := domain.NewService(integrationRepo, &MockOrderRepo{}, sendLogRepo, telegramNotifiers(telegramClient), 3)
+
+	out, err := svc.ImportOrders(context.Background(), 1, importRows(2), domain.ImportOrdersOptions{Notify: false})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, row := range out.Rows {
+		if row.SendStatus != domain.SendStatusSkipped {
+			t.Fatalf("expected skipped send status, got %s", row.SendStatus)
+		}
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if telegramClient.Calls() != 0 {
+		t.Fatalf("expected no send calls, got %d", telegramClient.Calls())
+	}
+	if len(sendLogRepo.logs) != 0 {
+		t.Fatalf("expected no send log rows, got %d", len(sendLogRepo.logs))
+	}
+}
+
+func TestImportOrdersWithNotifyQueuesNotifications(t *testing.T) {
+	integrationRepo := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "token", "chat", true)}}
+	telegramClient := &MockTelegramClient{}
+	svc := domain.NewService(integrationRepo, &MockOrderRepo{}, NewMockNotificationLogRepo(), telegramNotifiers(telegramClient), 3)
+
+	out, err := svc.ImportOrders(context.Background(), 1, importRows(2), domain.ImportOrdersOptions{Notify: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, row := range out.Rows {
+		if row.SendStatus != domain.SendStatusPending {
+			t.Fatalf("expected pending send status, got %s", row.SendStatus)
+		}
+	}
+
+	waitForCalls(t, telegramClient, 2, time.Second)
+}
+
+// slowSender counts the messages being sent at once.
+type slowSender struct {
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+	calls    int
+}
+
+func (s *slowSender) SendMessage(context.Context, string, string, string, bool) (int64, error) {
+	s.mu.Lock()
+	s.inFlight++
+	s.peak = max(s.peak, s.inFlight)
+	s.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight--
+	s.calls++
+	return int64(s.calls), nil
+}
+
+func (s *slowSender) stats() (calls, peak int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls, s.peak
+}
+
+func TestImportOrdersLimitsConcurrentSends(t *testing.T) {
+	integrationRepo := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "token", "chat", true)}}
+	sender := &slowSender{}
+	svc := domain.NewService(integrationRepo, &MockOrderRepo{}, NewMockNotificationLogRepo(), telegramNotifiers(sender), 1)
+
+	if _, err := svc.ImportOrders(context.Background(), 1, importRows(12), domain.ImportOrdersOptions{Notify: true, Concurrency: 2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for calls, _ := sender.stats(); calls < 12 && time.Now().Before(deadline); calls, _ = sender.stats() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if calls, peak := sender.stats(); calls != 12 || peak > 2 {
+		t.Fatalf("expected 12 sends at most 2 at a time, got %d sends, %d at once", calls, peak)
+	}
+}
+
+func TestImportOrdersReturnsPartialReportWhenNotifyFails(t *testing.T) {
+	integrationRepo := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "token", "chat", true)}}
+	logs := NewMockNotificationLogRepo()
+	logs.reserveErrs = []error{nil, errors.New("db down")}
+	orderRepo := &MockOrderRepo{}
+	svc := domain.NewService(integrationRepo, orderRepo, logs, telegramNotifiers(&MockTelegramClient{}), 3)
+
+	out, err := svc.ImportOrders(context.Background(), 1, importRows(5), domain.ImportOrdersOptions{Notify: true, BatchSize: 3})
+	if err == nil || !strings.Contains(err.Error(), "db down") {
+		t.Fatalf("expected the notify error, got %v", err)
+	}
+	if out.Total != 5 || out.Imported != 3 || out.Failed != 2 || len(orderRepo.created) != 3 {
+		t.Fatalf("expected the first batch to be saved and the rest failed, got %+v", out)
+	}
+
+	want := []struct{ status, sendStatus string }{
+		{domain.ImportRowStatusImported, domain.SendStatusPending},
+		{domain.ImportRowStatusImported, domain.SendStatusFailed},
+		{domain.ImportRowStatusImported, domain.SendStatusFailed},
+		{domain.ImportRowStatusFailed, ""},
+		{domain.ImportRowStatusFailed, ""},
+	}
+	for i, w := range want {
+		row := out.Rows[i]
+		if row.Status != w.status || row.SendStatus != w.sendStatus {
+			t.Errorf("row %d: expected %s/%q, got %s/%q", i, w.status, w.sendStatus, row.Status, row.SendStatus)
+		}
+	}
+	if out.Rows[1].Error == nil || out.Rows[3].Error == nil || !strings.HasPrefix(*out.Rows[3].Error, "import stopped") {
+		t.Fatalf("expected the failed rows to carry the error, got %+v", out.Rows)
+	}
+}
+
+func importCSV(t *testing.T, orderRepo *MockOrderRepo, logs *MockNotificationLogRepo, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	integrationRepo := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "token", "chat", true)}}
+	svc := domain.NewService(integrationRepo, orderRepo, logs, telegramNotifiers(&MockTelegramClient{}), 1)
+	router := newTestRouter(t, svc, domain.NewAPIKeyService(NewMockAPIKeyRepo(), "admin-secret"), newAuthService())
+	return serveAdmin(router, http.MethodPost, "/v2/shops/1/orders/import", body)
+}
+
+func TestImportEndpointParsesCSV(t *testing.T) {
+	orderRepo := &MockOrderRepo{}
+	body := "\ufeffNumber, total ,customer_name,created_at,status,comment\n" +
+		`"A-1","1590,50","Doe, Anna",2024-03-01T10:00:00Z,Paid,x` + "\n" +
+		`A-2,100,"He said ""hi""",,,` + "\n" +
+		`A-3,1"0,Anna` + "\n" +
+		"A-4,abc,Anna\n" +
+		"A-5,10,Anna,yesterday\n" +
+		"A-6,10,Anna,,paid!\n" +
+		",10,Anna\n"
+
+	rec := importCSV(t, orderRepo, NewMockNotificationLogRepo(), body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var out domain.ImportOrdersResult
+	decodeJSON(t, rec.Body.Bytes(), &out)
+	if out.Total != 7 || out.Imported != 2 || out.Invalid != 5 {
+		t.Fatalf("unexpected counters: %+v", out)
+	}
+	for i, line := range []int{2, 3, 4, 5, 6, 7, 8} {
+		if out.Rows[i].Line != line {
+			t.Errorf("row %d: expected line %d, got %d", i, line, out.Rows[i].Line)
+		}
+	}
+	for i, want := range []string{"bare \"", "invalid total", "invalid createdAt", "status must be", "number"} {
+		row := out.Rows[i+2]
+		if row.Status != domain.ImportRowStatusInvalid || row.Error == nil || !strings.Contains(*row.Error, want) {
+			t.Errorf("line %d: expected an invalid row mentioning %q, got %+v", row.Line, want, row)
+		}
+	}
+
+	if len(orderRepo.created) != 2 {
+		t.Fatalf("expected 2 saved orders, got %+v", orderRepo.created)
+	}
+	first, second := orderRepo.created[0], orderRepo.created[1]
+	if first.Number != "A-1" || first.Total != 1590.5 || first.CustomerName != "Doe, Anna" || first.Status != "paid" {
+		t.Errorf("unexpected first order: %+v", first)
+	}
+	if second.CustomerName != `He said "hi"` || second.Status != domain.OrderStatusNew {
+		t.Errorf("unexpected second order: %+v", second)
+	}
+}
+
+func TestImportEndpointRejectsBadCSV(t *testing.T) {
+	cases := []struct {
+		name, body string
+		status     int
+		detail     string
+	}{
+		{"empty", "", http.StatusBadRequest, "csv is empty"},
+		{"missing column", "number,total\nA-1,10\n", http.StatusBadRequest, `"customerName"`},
+		{"broken header", "number,\"total\ncustomerName\n", http.StatusBadRequest, "csv header"},
+		{"too large", "number,total,customerName\n" + strings.Repeat("A-1,10,Anna\n", 1<<20), http.StatusRequestEntityTooLarge, "must not exceed"},
+	}
+	for _, tc := range cases {
+		orderRepo := &MockOrderRepo{}
+		rec := importCSV(t, orderRepo, NewMockNotificationLogRepo(), tc.body)
+
+		var problem api.Problem
+		decodeJSON(t, rec.Body.Bytes(), &problem)
+		if rec.Code != tc.status || !strings.Contains(problem.Detail, tc.detail) {
+			t.Errorf("%s: expected %d mentioning %q, got %d %+v", tc.name, tc.status, tc.detail, rec.Code, problem)
+		}
+		if len(orderRepo.created) != 0 {
+			t.Errorf("%s: nothing should be saved, got %d orders", tc.name, len(orderRepo.created))
+		}
+	}
+}
+
+func TestImportEndpointReturnsPartialReport(t *testing.T) {
+	logs := NewMockNotificationLogRepo()
+	logs.reserveErrs = []error{errors.New("db down")}
+	body := "number,total,customerName\nA-1,10,Anna\nA-2,20,Anna\n"
+
+	rec := importCSV(t, &MockOrderRepo{}, logs, body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("saved orders must be reported, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var out domain.ImportOrdersResult
+	decodeJSON(t, rec.Body.Bytes(), &out)
+	if out.Imported != 2 || out.Rows[0].SendStatus != domain.SendStatusFailed || out.Rows[0].Error == nil {
+		t.Fatalf("expected both orders imported and the failure reported, got %+v", out)
+	}
+}
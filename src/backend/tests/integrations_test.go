@@ -0,0 +1,221 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/domain"
+)
+
+const channelFake domain.ChannelType = "fake"
+
+// fakeNotifier accepts a single "target" setting and records what it sends.
+type fakeNotifier struct {
+	mu   sync.Mutex
+	sent []domain.Notification
+	errs []error
+}
+
+func (n *fakeNotifier) Normalize(settings map[string]string, secret string) (map[string]string, string, error) {
+	if strings.TrimSpace(settings["target"]) == "" {
+		return nil, "", domain.InvalidField("settings.target", "required", "target must be non-empty")
+	}
+	return map[string]string{"target": strings.TrimSpace(settings["target"])}, secret, nil
+}
+
+func (n *fakeNotifier) Mask(settings map[string]string) map[string]string {
+	return map[string]string{"target": "***"}
+}
+
+func (n *fakeNotifier) Send(_ context.Context, _ domain.Integration, notification domain.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.sent = append(n.sent, notification)
+	if len(n.errs) == 0 {
+		return nil
+	}
+	err := n.errs[0]
+	n.errs = n.errs[1:]
+	return err
+}
+
+func (n *fakeNotifier) Sent() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.sent)
+}
+
+func newMultiChannelService(integrations *MockIntegrationRepo, logs *MockNotificationLogRepo, client *MockTelegramClient, fake *fakeNotifier) *domain.Service {
+	notifiers := telegramNotifiers(client).Register(channelFake, fake)
+	return domain.NewService(integrations, &MockOrderRepo{}, logs, notifiers, 1)
+}
+
+func TestListChannelsReturnsRegisteredChannels(t *testing.T) {
+	svc := newMultiChannelService(&MockIntegrationRepo{}, NewMockNotificationLogRepo(), &MockTelegramClient{}, &fakeNotifier{})
+	router := newTestRouter(t, svc, domain.NewAPIKeyService(NewMockAPIKeyRepo(), "admin-secret"), newAuthService())
+
+	rec := serveAdmin(router, http.MethodGet, "/v2/channels", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var out domain.ListChannelsResult
+	decodeJSON(t, rec.Body.Bytes(), &out)
+	if len(out.Items) != 2 || out.Items[0] != channelFake || out.Items[1] != domain.ChannelTelegram {
+		t.Fatalf("unexpected channels %v", out.Items)
+	}
+}
+
+func TestUnknownChannelIsNotFound(t *testing.T) {
+	svc := newMultiChannelService(&MockIntegrationRepo{}, NewMockNotificationLogRepo(), &MockTelegramClient{}, &fakeNotifier{})
+
+	status, problem := doProblem(t, svc, http.MethodPost, "/v2/shops/1/integrations/pigeon/connect", `{"settings":{"target":"x"}}`)
+	if status != http.StatusNotFound || problem.Code != "channel_not_found" {
+		t.Fatalf("expected channel_not_found, got %d %+v", status, problem)
+	}
+}
+
+func TestConnectIntegrationValidatesAndMasksSettings(t *testing.T) {
+	integrations := &MockIntegrationRepo{}
+	svc := newMultiChannelService(integrations, NewMockNotificationLogRepo(), &MockTelegramClient{}, &fakeNotifier{})
+	router := newTestRouter(t, svc, domain.NewAPIKeyService(NewMockAPIKeyRepo(), "admin-secret"), newAuthService())
+
+	rec := serveAdmin(router, http.MethodPost, "/v2/shops/1/integrations/telegram/connect", `{"settings":{"chatId":"-1001234567890","extra":"x"},"secret":"123:abc"}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "settings.extra") {
+		t.Fatalf("expected unknown setting to be rejected, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = serveAdmin(router, http.MethodPost, "/v2/shops/1/integrations/telegram/connect", `{"settings":{"chatId":" -1001234567890 "},"secret":"123:SECRET","enabled":true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "SECRET") || strings.Contains(rec.Body.String(), "1234567890") {
+		t.Fatalf("response leaks the secret or chat ID: %s", rec.Body.String())
+	}
+
+	var out domain.Integration
+	decodeJSON(t, rec.Body.Bytes(), &out)
+	if out.Channel != domain.ChannelTelegram || out.Settings["chatId"] != "-100******7890" || out.SecretFingerprint == "" {
+		t.Fatalf("unexpected integration %+v", out)
+	}
+	if integrations.items[0].Settings["chatId"] != "-1001234567890" {
+		t.Fatalf("expected trimmed chat ID to be stored, got %+v", integrations.items[0].Settings)
+	}
+
+	rec = serveAdmin(router, http.MethodGet, "/v2/shops/1/integrations", "")
+	var list domain.ListIntegrationsResult
+	decodeJSON(t, rec.Body.Bytes(), &list)
+	if rec.Code != http.StatusOK || len(list.Items) != 1 || list.Items[0].Settings["chatId"] != "-100******7890" {
+		t.Fatalf("unexpected list %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestOrderIsSentToEveryEnabledChannel(t *testing.T) {
+	integrations := &MockIntegrationRepo{}
+	logs := NewMockNotificationLogRepo()
+	client := &MockTelegramClient{}
+	fake := &fakeNotifier{}
+	svc := newMultiChannelService(integrations, logs, client, fake)
+	ctx := context.Background()
+
+	if _, err := svc.ConnectIntegration(ctx, 1, domain.ChannelTelegram, domain.ConnectIntegrationInput{Settings: map[string]string{"chatId": "chat"}, Secret: "token", Enabled: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fakeIntegration, err := svc.ConnectIntegration(ctx, 1, channelFake, domain.ConnectIntegrationInput{Settings: map[string]string{"target": "ops"}, Enabled: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out, err := svc.CreateOrder(ctx, 1, domain.CreateOrderInput{Number: "A-1", Total: 10, CustomerName: "Anna"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.SendStatus != domain.SendStatusPending {
+		t.Fatalf("expected pending, got %s", out.SendStatus)
+	}
+
+	waitForLogStatus(t, logs, 1, out.Order.ID, domain.NotificationStatusSent, time.Second)
+	waitForLogStatus(t, logs, fakeIntegration.ID, out.Order.ID, domain.NotificationStatusSent, time.Second)
+	if client.Calls() != 1 || fake.Sent() != 1 {
+		t.Fatalf("expected one send per channel, got telegram=%d fake=%d", client.Calls(), fake.Sent())
+	}
+
+	disabled := false
+	if _, err := svc.UpdateIntegration(ctx, 1, channelFake, domain.UpdateIntegrationInput{Enabled: &disabled}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.CreateOrder(ctx, 1, domain.CreateOrderInput{Number: "A-2", Total: 10, CustomerName: "Anna"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForCalls(t, client, 2, time.Second)
+	if fake.Sent() != 1 {
+		t.Fatalf("disabled channel should not be notified, got %d sends", fake.Sent())
+	}
+}
+
+func TestResendRetriesOnlyFailedChannels(t *testing.T) {
+	integrations := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "token", "chat", true)}}
+	logs := NewMockNotificationLogRepo()
+	client := &MockTelegramClient{}
+	fake := &fakeNotifier{errs: []error{errors.New("target unreachable")}}
+	svc := newMultiChannelService(integrations, logs, client, fake)
+	ctx := context.Background()
+
+	fakeIntegration, err := svc.ConnectIntegration(ctx, 1, channelFake, domain.ConnectIntegrationInput{Settings: map[string]string{"target": "ops"}, Enabled: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	created, err := svc.CreateOrder(ctx, 1, domain.CreateOrderInput{Number: "A-1", Total: 10, CustomerName: "Anna"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForLogStatus(t, logs, 1, created.Order.ID, domain.NotificationStatusSent, time.Second)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		logs.mu.Lock()
+		failed := logs.logs[key(fakeIntegration.ID, created.Order.ID)].Error != nil
+		logs.mu.Unlock()
+		if failed {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := svc.ResendOrder(ctx, 1, created.Order.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForLogStatus(t, logs, fakeIntegration.ID, created.Order.ID, domain.NotificationStatusSent, time.Second)
+
+	if client.Calls() != 1 || fake.Sent() != 2 {
+		t.Fatalf("expected only the failed channel to be retried, got telegram=%d fake=%d", client.Calls(), fake.Sent())
+	}
+}
+
+func TestIntegrationStatusIsPerChannel(t *testing.T) {
+	integrations := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "token", "-1001234567890", true)}}
+	svc := newMultiChannelService(integrations, NewMockNotificationLogRepo(), &MockTelegramClient{}, &fakeNotifier{})
+
+	telegramStatus, err := svc.GetIntegrationStatus(context.Background(), 1, domain.ChannelTelegram)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !telegramStatus.Enabled || telegramStatus.Settings["chatId"] != "-100******7890" {
+		t.Fatalf("unexpected telegram status %+v", telegramStatus)
+	}
+
+	fakeStatus, err := svc.GetIntegrationStatus(context.Background(), 1, channelFake)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fakeStatus.Enabled || fakeStatus.Channel != channelFake {
+		t.Fatalf("unconnected channel should be reported disabled, got %+v", fakeStatus)
+	}
+}
@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/api"
+	"growth-mvp/backend/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) entries(t *testing.T) []map[string]any {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line is not JSON: %s", line)
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+func (b *logBuffer) find(t *testing.T, msg string) map[string]any {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		for _, entry := range b.entries(t) {
+			if entry["msg"] == msg {
+				return entry
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no %q log entry in %v", msg, b.entries(t))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+type failingFinalizeRepo struct {
+	*MockNotificationLogRepo
+}
+
+func (r failingFinalizeRepo) Finalize(context.Context, int64, domain.NotificationStatus, *string, time.Time) error {
+	return errors.New("connection reset")
+}
+
+func loggedRouter(svc *domain.Service, logger *slog.Logger) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	handler := api.NewHandler(svc, domain.NewShopService(NewMockShopRepo()), domain.NewAPIKeyService(NewMockAPIKeyRepo(), "admin-secret"), newAuthService())
+
+	router := gin.New()
+	router.Use(api.RequestID(), api.RequestLogger(logger))
+	handler.RegisterRoutes(router)
+	return router
+}
+
+func TestRequestLoggerCarriesRequestIDIntoSendLogs(t *testing.T) {
+	logs := &logBuffer{}
+	logger := slog.New(slog.NewJSONHandler(logs, nil))
+
+	integrationRepo := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "123456:very-secret", "chat", true)}}
+	telegramClient := &MockTelegramClient{errs: []error{errors.New("bot123456:very-secret timed out")}}
+	svc := domain.NewService(integrationRepo, &MockOrderRepo{}, failingFinalizeRepo{NewMockNotificationLogRepo()}, telegramNotifiers(telegramClient), 3,
+		domain.WithLogger(slog.New(slog.DiscardHandler)))
+
+	router := loggedRouter(svc, logger)
+
+	req := httptest.NewRequest(http.MethodPost, "/shops/1/orders?utm=secret", strings.NewReader(`{"number":"A-1","total":10,"customerName":"Anna"}`))
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	req.Header.Set(api.RequestIDHeader, "req-42")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	access := logs.find(t, "http request")
+	if access["requestId"] != "req-42" || access["route"] != "/shops/:shopId/orders" || access["status"] != float64(201) {
+		t.Fatalf("unexpected access log %v", access)
+	}
+	if access["path"] != "/shops/1/orders" {
+		t.Fatalf("query string should not be logged, got %v", access["path"])
+	}
+
+	attempt := logs.find(t, "notification send attempt failed")
+	if attempt["requestId"] != "req-42" || attempt["shopId"] != float64(1) || attempt["orderId"] != float64(1) || attempt["channel"] != "telegram" {
+		t.Fatalf("send attempt log should carry request, shop, order and channel, got %v", attempt)
+	}
+	if strings.Contains(attempt["error"].(string), "very-secret") {
+		t.Fatalf("send attempt log leaks the bot token: %v", attempt["error"])
+	}
+
+	finalize := logs.find(t, "failed to finalize notification log")
+	if finalize["error"] != "connection reset" || finalize["status"] != string(domain.NotificationStatusSent) {
+		t.Fatalf("unexpected finalize log %v", finalize)
+	}
+}
+
+func TestRequestLoggerLogsInternalErrorsAtErrorLevel(t *testing.T) {
+	logs := &logBuffer{}
+	logger := slog.New(slog.NewJSONHandler(logs, nil))
+
+	svc := domain.NewService(&failingIntegrationRepo{}, &MockOrderRepo{}, NewMockNotificationLogRepo(), telegramNotifiers(&MockTelegramClient{}), 3)
+
+	rec := serveAdmin(loggedRouter(svc, logger), http.MethodGet, "/shops/1/telegram/status", "")
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	access := logs.find(t, "http request")
+	if access["level"] != "ERROR" || access["error"] == nil {
+		t.Fatalf("expected error-level access log with the cause, got %v", access)
+	}
+}
+
+func TestRecoveryLogsPanicsAsProblems(t *testing.T) {
+	logs := &logBuffer{}
+	logger := slog.New(slog.NewJSONHandler(logs, nil))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(api.Recovery(logger), api.RequestID())
+	router.GET("/boom", func(*gin.Context) { panic("boom") })
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/boom", nil))
+
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("unexpected response %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	entry := logs.find(t, "panic recovered")
+	if entry["panic"] != "boom" || entry["requestId"] == "" {
+		t.Fatalf("unexpected panic log %v", entry)
+	}
+}
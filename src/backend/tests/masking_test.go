@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/adapters/telegram"
+	"growth-mvp/backend/domain"
+)
+
+func TestMaskChatID(t *testing.T) {
+	cases := map[string]string{
+		"":               "",
+		"-1001234567890": "-100******7890",
+		"123456789":      "*****6789",
+		"-4512":          "-**12",
+		"@shop_orders":   "@*******ders",
+		"42":             "**",
+	}
+
+	for in, want := range cases {
+		if got := domain.MaskChatID(in); got != want {
+			t.Errorf("MaskChatID(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMaskTokenAndCustomerName(t *testing.T) {
+	if got := domain.MaskToken("123456:ABC-secret"); got != "123456:****" {
+		t.Fatalf("unexpected masked token %q", got)
+	}
+	if got := domain.MaskToken("garbage"); got != "****" {
+		t.Fatalf("unexpected masked token %q", got)
+	}
+	if got := domain.MaskCustomerName("Анна  Иванова"); got != "А*** И******" {
+		t.Fatalf("unexpected masked name %q", got)
+	}
+}
+
+func TestTelegramStatusMasksChatID(t *testing.T) {
+	integrationRepo := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "123456:secret", "-1001234567890", true)}}
+	svc := domain.NewService(integrationRepo, &MockOrderRepo{}, NewMockNotificationLogRepo(), telegramNotifiers(&MockTelegramClient{}), 3)
+
+	status, err := svc.GetTelegramStatus(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.MaskedChatID != "-100******7890" {
+		t.Fatalf("expected masked chat id, got %q", status.MaskedChatID)
+	}
+}
+
+func TestTelegramClientErrorDoesNotLeakToken(t *testing.T) {
+	client := telegram.NewClient("", 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.SendMessage(ctx, "123456:very-secret", "-1001234567890", "hello", false)
+	if err == nil {
+		t.Fatal("expected error for a cancelled request")
+	}
+	if strings.Contains(err.Error(), "very-secret") {
+		t.Fatalf("error leaks the bot token: %v", err)
+	}
+	if !strings.Contains(err.Error(), "123456:****") {
+		t.Fatalf("expected masked token in error, got %v", err)
+	}
+}
+
+func TestFailedSendLogDoesNotLeakToken(t *testing.T) {
+	integrationRepo := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "123456:very-secret", "chat", true)}}
+	sendLogRepo := NewMockNotificationLogRepo()
+	sendErr := errors.New("Post \"https://api.telegram.org/bot123456:very-secret/sendMessage\": timeout")
+	telegramClient := &MockTelegramClient{errs: []error{sendErr}}
+	svc := domain.NewService(integrationRepo, &MockOrderRepo{}, sendLogRepo, telegramNotifiers(telegramClient), 1)
+
+	out, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{Number: "A-1", Total: 10, CustomerName: "Anna"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var errText *string
+	deadline := time.Now().Add(time.Second)
+	for errText == nil && time.Now().Before(deadline) {
+		sendLogRepo.mu.Lock()
+		errText = sendLogRepo.logs[key(1, out.Order.ID)].Error
+		sendLogRepo.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if errText == nil || strings.Contains(*errText, "very-secret") {
+		t.Fatalf("expected redacted error text, got %v", errText)
+	}
+}
@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/adapters/metrics"
+	"growth-mvp/backend/adapters/telegram"
+	"growth-mvp/backend/api"
+	"growth-mvp/backend/domain"
+)
+
+type MockMetrics struct {
+	mu             sync.Mutex
+	telegramErrors []string
+	channels       []string
+	deliveries     []string
+	attempts       []int
+	pending        int
+}
+
+func (m *MockMetrics) ObserveTelegramRequest(errorClass string, _ time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.telegramErrors = append(m.telegramErrors, errorClass)
+}
+
+func (m *MockMetrics) ObserveDelivery(channel, outcome string, attempts int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.channels = append(m.channels, channel)
+	m.deliveries = append(m.deliveries, outcome)
+	m.attempts = append(m.attempts, attempts)
+}
+
+func (m *MockMetrics) AddPendingNotifications(delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending += delta
+}
+
+func (m *MockMetrics) snapshot() (deliveries []string, attempts []int, pending int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.deliveries...), append([]int(nil), m.attempts...), m.pending
+}
+
+func TestServiceRecordsDeliveryMetrics(t *testing.T) {
+	integrationRepo := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "token", "chat", true)}}
+	recorder := &MockMetrics{}
+	telegramClient := &MockTelegramClient{errs: []error{errors.New("timeout")}}
+	svc := domain.NewService(integrationRepo, &MockOrderRepo{}, NewMockNotificationLogRepo(), telegramNotifiers(telegramClient), 3,
+		domain.WithMetrics(recorder))
+
+	if _, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{Number: "A-1", Total: 10, CustomerName: "Anna"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		deliveries, attempts, pending := recorder.snapshot()
+
+		if len(deliveries) == 1 && pending == 0 {
+			if deliveries[0] != domain.DeliveryOutcomeSent || attempts[0] != 2 {
+				t.Fatalf("expected one sent delivery after 2 attempts, got %v %v", deliveries, attempts)
+			}
+			recorder.mu.Lock()
+			channel := recorder.channels[0]
+			recorder.mu.Unlock()
+			if channel != string(domain.ChannelTelegram) {
+				t.Fatalf("expected the delivery to be labelled telegram, got %s", channel)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("delivery not recorded: %v %v pending=%d", deliveries, attempts, pending)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTelegramClientClassifiesErrors(t *testing.T) {
+	statuses := []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusBadRequest, http.StatusOK}
+	var mu sync.Mutex
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		status := statuses[0]
+		statuses = statuses[1:]
+		mu.Unlock()
+
+		body := `{"ok":false,"description":"nope"}`
+		if status == http.StatusOK {
+			body = `{"ok":true}`
+		}
+
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, body)
+	}))
+	defer server.Close()
+
+	recorder := &MockMetrics{}
+	client := telegram.NewClient(server.URL, time.Second, telegram.WithMetrics(recorder))
+
+	for range 4 {
+		_, _ = client.SendMessage(context.Background(), "123:abc", "chat", "hello", false)
+	}
+	_, _ = client.SendMessage(context.Background(), "", "chat", "hello", false)
+
+	want := []string{
+		telegram.ErrorClassRateLimited,
+		telegram.ErrorClassServer,
+		telegram.ErrorClassClient,
+		telegram.ErrorClassNone,
+		telegram.ErrorClassInvalid,
+	}
+	if strings.Join(recorder.telegramErrors, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected classes %v, got %v", want, recorder.telegramErrors)
+	}
+}
+
+func TestMetricsEndpointLabelsRouteTemplates(t *testing.T) {
+	prom := metrics.NewPrometheus()
+	router := newTestRouter(t, nil, domain.NewAPIKeyService(NewMockAPIKeyRepo(), "admin-secret"), newAuthService(),
+		api.WithMetrics(prom))
+
+	if rec := serveAdmin(router, http.MethodGet, "/v2/shops/1", ""); rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	prom.ObserveTelegramRequest(telegram.ErrorClassTimeout, time.Second)
+	prom.ObserveDelivery(string(domain.ChannelTelegram), domain.DeliveryOutcomeFailed, 3)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		`http_request_duration_seconds_count{method="GET",route="/v2/shops/:shopId",status="200"} 1`,
+		`telegram_send_duration_seconds_count{error_class="timeout"} 1`,
+		`notification_deliveries_total{channel="telegram",outcome="failed"} 1`,
+		`notification_retries_total{channel="telegram"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output is missing %q", want)
+		}
+	}
+}
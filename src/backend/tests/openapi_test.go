@@ -0,0 +1,214 @@
+package tests
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"growth-mvp/backend/api"
+	"growth-mvp/backend/domain"
+)
+
+type openAPIDoc struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]openAPISchema `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPISchema struct {
+	Ref        string                     `json:"$ref"`
+	Properties map[string]json.RawMessage `json:"properties"`
+	AllOf      []openAPISchema            `json:"allOf"`
+}
+
+var undocumentedRoutes = map[string]bool{
+	"GET /openapi.json":         true,
+	"GET /docs":                 true,
+	"GET /swagger-ui/*filepath": true,
+	"GET /livez":                true,
+	"GET /readyz":               true,
+}
+
+func loadOpenAPI(t *testing.T) openAPIDoc {
+	t.Helper()
+
+	var doc openAPIDoc
+	if err := json.Unmarshal(api.OpenAPISpec(), &doc); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	return doc
+}
+
+func TestOpenAPICoversRegisteredRoutes(t *testing.T) {
+	doc := loadOpenAPI(t)
+	router := newTestRouter(t, nil, domain.NewAPIKeyService(NewMockAPIKeyRepo(), ""), newAuthService())
+	param := regexp.MustCompile(`:(\w+)`)
+	registered := map[string]bool{}
+
+	for _, route := range router.Routes() {
+		if undocumentedRoutes[route.Method+" "+route.Path] {
+			continue
+		}
+
+		path := param.ReplaceAllString(route.Path, "{$1}")
+		if !strings.HasPrefix(path, "/v1/") && !strings.HasPrefix(path, "/v2/") {
+			// unversioned paths are aliases of v1
+			path = "/v1" + path
+		}
+		method := strings.ToLower(route.Method)
+		registered[method+" "+path] = true
+
+		if _, ok := doc.Paths[path][method]; !ok {
+			t.Errorf("route %s %s is missing from openapi.json", route.Method, path)
+		}
+	}
+
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			if !registered[method+" "+path] {
+				t.Errorf("openapi.json documents %s %s, which is not registered", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestOpenAPICoversDTOFields(t *testing.T) {
+	doc := loadOpenAPI(t)
+
+	for _, path := range []string{"../domain/dto.go", "../api/dto_v2.go"} {
+		checkDTOFile(t, doc, path)
+	}
+}
+
+func checkDTOFile(t *testing.T, doc openAPIDoc, path string) {
+	t.Helper()
+
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+	if err != nil {
+		t.Fatalf("parse %s: %v", path, err)
+	}
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			structType, ok := typeSpec.Type.(*ast.StructType)
+			if !ok || !hasJSONTags(structType) {
+				continue
+			}
+
+			name := typeSpec.Name.Name
+			schema, ok := doc.Components.Schemas[name]
+			if !ok {
+				t.Errorf("DTO %s has no schema in openapi.json", name)
+				continue
+			}
+
+			properties, refs := schemaFields(schema)
+
+			for _, field := range structType.Fields.List {
+				if len(field.Names) == 0 {
+					embedded := exprName(field.Type)
+					if !refs["#/components/schemas/"+embedded] {
+						t.Errorf("schema %s must include embedded %s via allOf", name, embedded)
+					}
+					continue
+				}
+
+				jsonName := jsonTagName(field)
+				if jsonName == "" {
+					continue
+				}
+				if !properties[jsonName] {
+					t.Errorf("schema %s is missing property %q", name, jsonName)
+				}
+			}
+		}
+	}
+}
+
+func TestOpenAPIAndDocsAreServed(t *testing.T) {
+	router := newTestRouter(t, nil, domain.NewAPIKeyService(NewMockAPIKeyRepo(), ""), newAuthService())
+
+	for path, contentType := range map[string]string{
+		"/openapi.json":                    "application/json",
+		"/docs":                            "text/html",
+		"/swagger-ui/swagger-ui-bundle.js": "text/javascript",
+		"/swagger-ui/swagger-ui.css":       "text/css",
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), contentType) {
+			t.Fatalf("%s: unexpected response %d %q", path, rec.Code, rec.Header().Get("Content-Type"))
+		}
+		if path == "/docs" && strings.Contains(rec.Body.String(), "https://") {
+			t.Fatalf("the docs page must not load assets from a CDN:\n%s", rec.Body.String())
+		}
+	}
+}
+
+func hasJSONTags(structType *ast.StructType) bool {
+	for _, field := range structType.Fields.List {
+		if field.Tag != nil && strings.Contains(field.Tag.Value, "json:") {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonTagName(field *ast.Field) string {
+	if field.Tag == nil {
+		return ""
+	}
+	tag := reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
+	name, _, _ := strings.Cut(tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func exprName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.StarExpr:
+		return exprName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	}
+	return ""
+}
+
+func schemaFields(schema openAPISchema) (map[string]bool, map[string]bool) {
+	properties := map[string]bool{}
+	refs := map[string]bool{}
+
+	for name := range schema.Properties {
+		properties[name] = true
+	}
+
+	for _, part := range schema.AllOf {
+		if part.Ref != "" {
+			refs[part.Ref] = true
+		}
+		for name := range part.Properties {
+			properties[name] = true
+		}
+	}
+
+	return properties, refs
+}
@@ -0,0 +1,213 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/domain"
+)
+
+// makeDeferredDue moves the deferred rows of the mock into the past, as if the
+// quiet hours were over.
+func (f *MockNotificationLogRepo) makeDeferredDue() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	past := time.Now().Add(-time.Minute)
+	for k, log := range f.logs {
+		if log.DeliverAfter != nil {
+			log.DeliverAfter = &past
+			f.logs[k] = log
+		}
+	}
+}
+
+func (f *MockNotificationLogRepo) get(k string) domain.NotificationLog {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.logs[k]
+}
+
+// quietNow returns quiet hours of the shop's time zone that contain the
+// current time.
+func quietNow(t *testing.T, timezone string, mode domain.QuietHoursMode) *domain.QuietHours {
+	t.Helper()
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Now().In(loc)
+	return &domain.QuietHours{From: now.Add(-time.Hour).Format("15:04"), To: now.Add(time.Hour).Format("15:04"), Mode: mode}
+}
+
+func newQuietService(t *testing.T, sender *messageRecorder, logs *MockNotificationLogRepo, integrations *MockIntegrationRepo, quiet *domain.QuietHours) *domain.Service {
+	t.Helper()
+
+	shops := NewMockShopRepo()
+	if _, err := domain.NewShopService(shops).CreateShop(context.Background(), domain.ShopInput{Name: "Demo", Timezone: "Asia/Vladivostok", QuietHours: quiet}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return domain.NewService(integrations, &MockOrderRepo{}, logs, telegramNotifiers(sender), 1, domain.WithShops(shops))
+}
+
+func TestQuietHoursEnd(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	night := domain.QuietHours{From: "23:00", To: "08:00"}
+	lunch := domain.QuietHours{From: "13:00", To: "14:00"}
+
+	for _, tc := range []struct {
+		name  string
+		quiet domain.QuietHours
+		now   time.Time
+		want  time.Time
+	}{
+		{"after midnight", night, time.Date(2024, 5, 2, 2, 30, 0, 0, moscow), time.Date(2024, 5, 2, 8, 0, 0, 0, moscow)},
+		{"before midnight", night, time.Date(2024, 5, 1, 23, 30, 0, 0, moscow), time.Date(2024, 5, 2, 8, 0, 0, 0, moscow)},
+		{"daytime", night, time.Date(2024, 5, 1, 12, 0, 0, 0, moscow), time.Time{}},
+		{"window end is outside", night, time.Date(2024, 5, 2, 8, 0, 0, 0, moscow), time.Time{}},
+		{"same day window", lunch, time.Date(2024, 5, 1, 13, 59, 0, 0, moscow), time.Date(2024, 5, 1, 14, 0, 0, 0, moscow)},
+		{"compared in the shop's zone", night, time.Date(2024, 5, 1, 21, 0, 0, 0, time.UTC), time.Date(2024, 5, 2, 8, 0, 0, 0, moscow)},
+	} {
+		end, ok := tc.quiet.End(tc.now, moscow)
+		if ok != !tc.want.IsZero() || !end.Equal(tc.want) {
+			t.Errorf("%s: expected %v, got %v (%v)", tc.name, tc.want, end, ok)
+		}
+	}
+}
+
+func TestQuietHoursDeferSendsUntilWindowEnds(t *testing.T) {
+	sender := &messageRecorder{}
+	logs := NewMockNotificationLogRepo()
+	integrations := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "123456:bot-token", "-1001234567890", true)}}
+	svc := newQuietService(t, sender, logs, integrations, quietNow(t, "Asia/Vladivostok", domain.QuietHoursDefer))
+	ctx := context.Background()
+
+	out, err := svc.CreateOrder(ctx, 1, domain.CreateOrderInput{Number: "A-1", Total: 100, CustomerName: "Anna"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.SendStatus != domain.SendStatusPending {
+		t.Fatalf("expected a deferred order to be pending, got %q", out.SendStatus)
+	}
+
+	row := logs.get(key(1, out.Order.ID))
+	if row.DeliverAfter == nil || !row.DeliverAfter.After(time.Now()) || row.DeliverAfter.After(time.Now().Add(time.Hour)) {
+		t.Fatalf("expected the row to wait for the window end, got %v", row.DeliverAfter)
+	}
+
+	if started, err := svc.DeliverDue(ctx, 10); err != nil || started != 0 {
+		t.Fatalf("nothing should be due yet, got %d %v", started, err)
+	}
+	if _, err := svc.ResendOrder(ctx, 1, out.Order.ID); !errors.Is(err, domain.ErrNotResendable) {
+		t.Fatalf("a deferred notification is in progress, got %v", err)
+	}
+
+	logs.makeDeferredDue()
+	if started, err := svc.DeliverDue(ctx, 10); err != nil || started != 1 {
+		t.Fatalf("expected one deferred send, got %d %v", started, err)
+	}
+	waitForLogStatus(t, logs, 1, out.Order.ID, domain.NotificationStatusSent, time.Second)
+
+	if got := sender.sent(); len(got) != 1 || got[0].silent {
+		t.Fatalf("expected one audible message after the quiet hours, got %+v", got)
+	}
+	if started, _ := svc.DeliverDue(ctx, 10); started != 0 {
+		t.Fatalf("a row must be claimed once, got %d more", started)
+	}
+}
+
+func TestQuietHoursDeferredSendFailsForDisabledIntegration(t *testing.T) {
+	sender := &messageRecorder{}
+	logs := NewMockNotificationLogRepo()
+	integrations := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "123456:bot-token", "-1001234567890", true)}}
+	svc := newQuietService(t, sender, logs, integrations, quietNow(t, "Asia/Vladivostok", domain.QuietHoursDefer))
+	ctx := context.Background()
+
+	out, err := svc.CreateOrder(ctx, 1, domain.CreateOrderInput{Number: "A-1", Total: 100, CustomerName: "Anna"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	disabled := false
+	if _, err := svc.UpdateIntegration(ctx, 1, domain.ChannelTelegram, domain.UpdateIntegrationInput{Enabled: &disabled}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	logs.makeDeferredDue()
+	if started, err := svc.DeliverDue(ctx, 10); err != nil || started != 0 {
+		t.Fatalf("expected no sends, got %d %v", started, err)
+	}
+
+	row := logs.get(key(1, out.Order.ID))
+	if row.Status != domain.NotificationStatusFailed || row.Error == nil || !strings.Contains(*row.Error, "disabled") {
+		t.Fatalf("expected a failed row that explains why, got %+v", row)
+	}
+	if len(sender.sent()) != 0 {
+		t.Fatalf("nothing should be sent, got %+v", sender.sent())
+	}
+}
+
+func TestQuietHoursSilentModeSendsAtOnce(t *testing.T) {
+	sender := &messageRecorder{}
+	logs := NewMockNotificationLogRepo()
+	integrations := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "123456:bot-token", "-1001234567890", true)}}
+	svc := newQuietService(t, sender, logs, integrations, quietNow(t, "Asia/Vladivostok", domain.QuietHoursSilent))
+
+	out, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{Number: "A-1", Total: 100, CustomerName: "Anna"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForLogStatus(t, logs, 1, out.Order.ID, domain.NotificationStatusSent, time.Second)
+
+	if got := sender.sent(); len(got) != 1 || !got[0].silent {
+		t.Fatalf("expected one silent message, got %+v", got)
+	}
+}
+
+func TestStatusStatsUseShopCalendarDays(t *testing.T) {
+	logs := NewMockNotificationLogRepo()
+	integrations := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "123456:bot-token", "-1001234567890", true)}}
+	svc := newQuietService(t, &messageRecorder{}, logs, integrations, nil)
+
+	if _, err := svc.GetIntegrationStatus(context.Background(), 1, domain.ChannelTelegram); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loc, _ := time.LoadLocation("Asia/Vladivostok")
+	today := time.Now().In(loc)
+	want := time.Date(today.Year(), today.Month(), today.Day()-6, 0, 0, 0, 0, loc)
+	if !logs.statsSince.Equal(want) {
+		t.Fatalf("expected the window to start at %v, got %v", want, logs.statsSince.In(loc))
+	}
+}
+
+func TestShopQuietHoursAreValidated(t *testing.T) {
+	svc := domain.NewShopService(NewMockShopRepo())
+
+	for _, quiet := range []domain.QuietHours{
+		{From: "25:00", To: "08:00"},
+		{From: "23:00", To: "23:00"},
+		{From: "23:00", To: "08:00", Mode: "loud"},
+	} {
+		_, err := svc.CreateShop(context.Background(), domain.ShopInput{Name: "Demo", QuietHours: &quiet})
+		if !errors.Is(err, domain.ErrInvalidInput) {
+			t.Fatalf("%+v: expected ErrInvalidInput, got %v", quiet, err)
+		}
+	}
+
+	shop, err := svc.CreateShop(context.Background(), domain.ShopInput{Name: "Demo", QuietHours: &domain.QuietHours{From: "23:00", To: "8:00"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *shop.QuietHours != (domain.QuietHours{From: "23:00", To: "08:00", Mode: domain.QuietHoursDefer}) {
+		t.Fatalf("expected normalized quiet hours, got %+v", *shop.QuietHours)
+	}
+}
@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/adapters/memory"
+	"growth-mvp/backend/api"
+	"growth-mvp/backend/domain"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	limit, err := domain.ParseRateLimit("100/1m")
+	if err != nil || limit.Requests != 100 || limit.Window != time.Minute {
+		t.Fatalf("unexpected limit %+v, err %v", limit, err)
+	}
+
+	for _, spec := range []string{"", "0", "off"} {
+		if limit, err := domain.ParseRateLimit(spec); err != nil || limit.Enabled() {
+			t.Fatalf("%q should disable limiting, got %+v, err %v", spec, limit, err)
+		}
+	}
+
+	for _, spec := range []string{"100", "x/1m", "10/soon", "10/0s"} {
+		if _, err := domain.ParseRateLimit(spec); err == nil {
+			t.Fatalf("%q should be rejected", spec)
+		}
+	}
+}
+
+func TestMemoryRateLimitStoreResetsPerWindow(t *testing.T) {
+	store := memory.NewRateLimitStore()
+	limit := domain.RateLimit{Requests: 2, Window: time.Minute}
+	now := time.Date(2024, 3, 1, 10, 0, 10, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		if out, _ := store.Hit(context.Background(), "key:1", limit, now); !out.Allowed {
+			t.Fatalf("hit %d should be allowed", i+1)
+		}
+	}
+
+	out, _ := store.Hit(context.Background(), "key:1", limit, now)
+	if out.Allowed || !out.ResetAt.Equal(now.Truncate(time.Minute).Add(time.Minute)) {
+		t.Fatalf("third hit should be rejected until the next window, got %+v", out)
+	}
+
+	if out, _ := store.Hit(context.Background(), "key:2", limit, now); !out.Allowed {
+		t.Fatalf("other keys must have their own counter")
+	}
+
+	if out, _ := store.Hit(context.Background(), "key:1", limit, now.Add(time.Minute)); !out.Allowed || out.Remaining != 1 {
+		t.Fatalf("next window should start over, got %+v", out)
+	}
+}
+
+func TestRateLimitPerKeyAndPerShop(t *testing.T) {
+	keys := domain.NewAPIKeyService(NewMockAPIKeyRepo(), "admin-secret")
+	limiter := api.NewRateLimiter(memory.NewRateLimitStore(),
+		domain.RateLimit{Requests: 2, Window: time.Hour},
+		domain.RateLimit{Requests: 3, Window: time.Hour},
+		domain.RateLimit{})
+	router := newTestRouter(t, nil, keys, newAuthService(), api.WithRateLimiter(limiter))
+
+	issue := func(name string) string {
+		created, err := keys.CreateKey(context.Background(), 1, domain.CreateAPIKeyInput{Name: name, Scopes: []domain.Scope{domain.ScopeOrdersRead}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return created.Key
+	}
+
+	list := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/shops/1/orders", nil)
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first, second := issue("first"), issue("second")
+
+	for i := 0; i < 2; i++ {
+		if rec := list(first); rec.Code != http.StatusOK {
+			t.Fatalf("request %d should pass, got %d", i+1, rec.Code)
+		}
+	}
+
+	rec := list(first)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d %v", rec.Code, rec.Header())
+	}
+
+	if rec := list(second); rec.Code != http.StatusOK {
+		t.Fatalf("another key should still pass, got %d", rec.Code)
+	}
+
+	if rec := list(second); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("shop limit should reject the fourth request to the shop, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitChargesShopOnlyAfterAuthorization(t *testing.T) {
+	keys := domain.NewAPIKeyService(NewMockAPIKeyRepo(), "admin-secret")
+	limiter := api.NewRateLimiter(memory.NewRateLimitStore(),
+		domain.RateLimit{},
+		domain.RateLimit{Requests: 1, Window: time.Hour},
+		domain.RateLimit{})
+	router := newTestRouter(t, nil, keys, newAuthService(), api.WithRateLimiter(limiter))
+
+	outsider, err := keys.CreateKey(context.Background(), 2, domain.CreateAPIKeyInput{Name: "other shop", Scopes: []domain.Scope{domain.ScopeOrdersRead}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	owner, err := keys.CreateKey(context.Background(), 1, domain.CreateAPIKeyInput{Name: "own shop", Scopes: []domain.Scope{domain.ScopeOrdersRead}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	list := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/shops/1/orders", nil)
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 3; i++ {
+		if code := list(outsider.Key); code != http.StatusForbidden {
+			t.Fatalf("a key of another shop should get 403, got %d", code)
+		}
+	}
+	if code := list(owner.Key); code != http.StatusOK {
+		t.Fatalf("forbidden requests must not use up the shop quota, got %d", code)
+	}
+	if code := list(owner.Key); code != http.StatusTooManyRequests {
+		t.Fatalf("the shop limit should still apply to its own key, got %d", code)
+	}
+}
+
+func TestRateLimitLogin(t *testing.T) {
+	limiter := api.NewRateLimiter(memory.NewRateLimitStore(),
+		domain.RateLimit{},
+		domain.RateLimit{},
+		domain.RateLimit{Requests: 2, Window: time.Hour})
+	router := newTestRouter(t, nil, domain.NewAPIKeyService(NewMockAPIKeyRepo(), "admin-secret"), newAuthService(), api.WithRateLimiter(limiter))
+	// as with TRUSTED_PROXIES unset, a forged X-Forwarded-For must not change the client
+	if err := router.SetTrustedProxies(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	login := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"owner@shop.example","password":"wrong password"}`))
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", "203.0.113.9")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := login("198.51.100.1:5000"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d should reach the password check, got %d", i+1, rec.Code)
+		}
+	}
+
+	rec := login("198.51.100.1:5001")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d %v", rec.Code, rec.Header())
+	}
+
+	if rec := login("198.51.100.2:5000"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("another address should have its own limit, got %d", rec.Code)
+	}
+}
+
+func TestJSONBodySizeLimit(t *testing.T) {
+	router := newTestRouter(t, nil, domain.NewAPIKeyService(NewMockAPIKeyRepo(), "admin-secret"), newAuthService(), api.WithMaxBodyBytes(64))
+
+	body := `{"number":"A-1","total":10,"customerName":"` + strings.Repeat("x", 100) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/shops/1/orders", strings.NewReader(body))
+	req.Header.Set("X-API-Key", "admin-secret")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,412 @@
+package tests
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/adapters/telegram"
+	"growth-mvp/backend/domain"
+)
+
+type MockRuleRepo struct {
+	mu          sync.Mutex
+	nextID      int64
+	items       []domain.NotificationRule
+	evaluations map[int64]domain.RuleEvaluation
+	recordErr   error
+}
+
+func (f *MockRuleRepo) Create(_ context.Context, shopID int64, input domain.NotificationRuleInput) (domain.NotificationRule, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextID++
+	rule := domain.NotificationRule{
+		ID:         f.nextID,
+		ShopID:     shopID,
+		Position:   input.Position,
+		Name:       input.Name,
+		Conditions: input.Conditions,
+		Action:     input.Action,
+		Template:   input.Template,
+		Enabled:    input.Enabled,
+	}
+	f.items = append(f.items, rule)
+	return rule, nil
+}
+
+func (f *MockRuleRepo) find(shopID, ruleID int64) int {
+	for i, item := range f.items {
+		if item.ShopID == shopID && item.ID == ruleID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *MockRuleRepo) Get(_ context.Context, shopID, ruleID int64) (domain.NotificationRule, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if i := f.find(shopID, ruleID); i >= 0 {
+		return f.items[i], nil
+	}
+	return domain.NotificationRule{}, domain.ErrRuleNotFound
+}
+
+func (f *MockRuleRepo) ListByShop(_ context.Context, shopID int64) ([]domain.NotificationRule, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := []domain.NotificationRule{}
+	for _, item := range f.items {
+		if item.ShopID == shopID {
+			out = append(out, item)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b domain.NotificationRule) int {
+		return cmp.Or(cmp.Compare(a.Position, b.Position), cmp.Compare(a.ID, b.ID))
+	})
+	return out, nil
+}
+
+func (f *MockRuleRepo) Update(_ context.Context, shopID, ruleID int64, input domain.UpdateNotificationRuleInput) (domain.NotificationRule, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	i := f.find(shopID, ruleID)
+	if i < 0 {
+		return domain.NotificationRule{}, domain.ErrRuleNotFound
+	}
+	if input.Position != nil {
+		f.items[i].Position = *input.Position
+	}
+	if input.Name != nil {
+		f.items[i].Name = *input.Name
+	}
+	if input.Conditions != nil {
+		f.items[i].Conditions = *input.Conditions
+	}
+	if input.Action != nil {
+		f.items[i].Action = *input.Action
+	}
+	if input.Template != nil {
+		f.items[i].Template = *input.Template
+	}
+	if input.Enabled != nil {
+		f.items[i].Enabled = *input.Enabled
+	}
+	return f.items[i], nil
+}
+
+func (f *MockRuleRepo) Delete(_ context.Context, shopID, ruleID int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	i := f.find(shopID, ruleID)
+	if i < 0 {
+		return domain.ErrRuleNotFound
+	}
+	f.items = append(f.items[:i], f.items[i+1:]...)
+	return nil
+}
+
+func (f *MockRuleRepo) RecordEvaluation(_ context.Context, evaluation domain.RuleEvaluation) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.recordErr != nil {
+		return f.recordErr
+	}
+	if f.evaluations == nil {
+		f.evaluations = map[int64]domain.RuleEvaluation{}
+	}
+	f.evaluations[evaluation.OrderID] = evaluation
+	return nil
+}
+
+func (f *MockRuleRepo) GetEvaluation(_ context.Context, shopID, orderID int64) (domain.RuleEvaluation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	evaluation, ok := f.evaluations[orderID]
+	if !ok || evaluation.ShopID != shopID {
+		return domain.RuleEvaluation{}, domain.ErrEvaluationNotFound
+	}
+	return evaluation, nil
+}
+
+type sentMessage struct {
+	text   string
+	silent bool
+}
+
+// messageRecorder is a telegram.Sender that remembers what it sent; message
+// IDs count up from 1.
+type messageRecorder struct {
+	mu       sync.Mutex
+	messages []sentMessage
+}
+
+func (r *messageRecorder) SendMessage(_ context.Context, _, _, text string, silent bool) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.messages = append(r.messages, sentMessage{text: text, silent: silent})
+	return int64(len(r.messages)), nil
+}
+
+func (r *messageRecorder) sent() []sentMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.messages)
+}
+
+func newRuleService(t *testing.T, sender telegram.Sender, logs *MockNotificationLogRepo) (*domain.Service, *MockRuleRepo) {
+	t.Helper()
+
+	rules := &MockRuleRepo{}
+	integrationRepo := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "123456:bot-token", "-1001234567890", true)}}
+	svc := domain.NewService(integrationRepo, &MockOrderRepo{}, logs, telegramNotifiers(sender), 1,
+		domain.WithNotificationRules(rules))
+	return svc, rules
+}
+
+func addRule(t *testing.T, svc *domain.Service, input domain.NotificationRuleInput) domain.NotificationRule {
+	t.Helper()
+
+	input.Enabled = true
+	rule, err := svc.CreateNotificationRule(context.Background(), 1, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return rule
+}
+
+func TestRulesSkipOrdersAndExplainWhy(t *testing.T) {
+	sender := &messageRecorder{}
+	logs := NewMockNotificationLogRepo()
+	svc, _ := newRuleService(t, sender, logs)
+	ctx := context.Background()
+
+	minTotal := 5000.0
+	addRule(t, svc, domain.NotificationRuleInput{Position: 1, Name: "Остальные", Action: domain.RuleActionSkip})
+	large := addRule(t, svc, domain.NotificationRuleInput{Name: "Крупные", Conditions: domain.RuleConditions{MinTotal: &minTotal}, Action: domain.RuleActionSend})
+
+	small, err := svc.CreateOrder(ctx, 1, domain.CreateOrderInput{Number: "A-1", Total: 1500, CustomerName: "Anna"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if small.SendStatus != domain.SendStatusSkipped {
+		t.Fatalf("expected the small order to be skipped, got %q", small.SendStatus)
+	}
+
+	big, err := svc.CreateOrder(ctx, 1, domain.CreateOrderInput{Number: "A-2", Total: 6200, CustomerName: "Boris"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForLogStatus(t, logs, 1, big.Order.ID, domain.NotificationStatusSent, time.Second)
+
+	if got := sender.sent(); len(got) != 1 || !strings.Contains(got[0].text, "A-2") {
+		t.Fatalf("expected only the large order to be sent, got %+v", got)
+	}
+
+	router := newTestRouter(t, svc, domain.NewAPIKeyService(NewMockAPIKeyRepo(), "admin-secret"), newAuthService())
+
+	rec := serveAdmin(router, http.MethodGet, fmt.Sprintf("/v2/shops/1/orders/%d/rule-evaluation", small.Order.ID), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	var skipped domain.RuleEvaluation
+	decodeJSON(t, rec.Body.Bytes(), &skipped)
+	if skipped.Action != domain.RuleActionSkip || skipped.RuleName != "Остальные" || skipped.Reason != "rule has no conditions" {
+		t.Fatalf("unexpected evaluation of the skipped order %+v", skipped)
+	}
+
+	rec = serveAdmin(router, http.MethodGet, fmt.Sprintf("/v2/shops/1/orders/%d/rule-evaluation", big.Order.ID), "")
+	var sent domain.RuleEvaluation
+	decodeJSON(t, rec.Body.Bytes(), &sent)
+	if sent.RuleID == nil || *sent.RuleID != large.ID || sent.Action != domain.RuleActionSend || sent.Reason != "total 6200.00 ≥ 5000.00" {
+		t.Fatalf("unexpected evaluation of the sent order %+v", sent)
+	}
+}
+
+func TestRulesPickTemplateAndSilentSend(t *testing.T) {
+	sender := &messageRecorder{}
+	logs := NewMockNotificationLogRepo()
+	svc, _ := newRuleService(t, sender, logs)
+	ctx := context.Background()
+
+	addRule(t, svc, domain.NotificationRuleInput{Name: "VIP", Conditions: domain.RuleConditions{CustomerPattern: "vip|иванов"}, Action: domain.RuleActionTemplate, Template: domain.TemplateVIP})
+	addRule(t, svc, domain.NotificationRuleInput{Name: "Опт", Conditions: domain.RuleConditions{NumberPrefix: "B2B-"}, Action: domain.RuleActionSilent})
+
+	for _, input := range []domain.CreateOrderInput{
+		{Number: "A-1", Total: 100, CustomerName: "Пётр Иванов"},
+		{Number: "B2B-7", Total: 100, CustomerName: "ООО Ромашка"},
+		{Number: "b2b-8", Total: 100, CustomerName: "Anna"},
+	} {
+		out, err := svc.CreateOrder(ctx, 1, input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		waitForLogStatus(t, logs, 1, out.Order.ID, domain.NotificationStatusSent, time.Second)
+	}
+
+	got := sender.sent()
+	slices.SortFunc(got, func(a, b sentMessage) int { return strings.Compare(a.text, b.text) })
+	want := []sentMessage{
+		{text: "Новый заказ B2B-7 на сумму 100.00 ₽, клиент ООО Ромашка", silent: true},
+		{text: "Новый заказ b2b-8 на сумму 100.00 ₽, клиент Anna"},
+		{text: "🔥 VIP-заказ A-1 на сумму 100.00 ₽, клиент Пётр Иванов. Свяжитесь с клиентом как можно скорее!"},
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestRulesMatchOrderStatus(t *testing.T) {
+	sender := &messageRecorder{}
+	logs := NewMockNotificationLogRepo()
+	svc, rules := newRuleService(t, sender, logs)
+	ctx := context.Background()
+
+	rule := addRule(t, svc, domain.NotificationRuleInput{Name: "Неоплаченные", Conditions: domain.RuleConditions{Statuses: []string{" New", "pending"}}, Action: domain.RuleActionSkip})
+	if want := []string{"new", "pending"}; !slices.Equal(rule.Conditions.Statuses, want) {
+		t.Fatalf("expected statuses %v to be stored, got %v", want, rule.Conditions.Statuses)
+	}
+
+	unpaid, err := svc.CreateOrder(ctx, 1, domain.CreateOrderInput{Number: "A-1", Total: 100, CustomerName: "Anna"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if unpaid.Order.Status != domain.OrderStatusNew || unpaid.SendStatus != domain.SendStatusSkipped {
+		t.Fatalf("an order without a status should be new and skipped, got %q %q", unpaid.Order.Status, unpaid.SendStatus)
+	}
+	if reason := rules.evaluations[unpaid.Order.ID].Reason; reason != `status is "new"` {
+		t.Fatalf("unexpected reason %q", reason)
+	}
+
+	paid, err := svc.CreateOrder(ctx, 1, domain.CreateOrderInput{Number: "A-2", Total: 100, CustomerName: "Boris", Status: "PAID"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if paid.Order.Status != "paid" {
+		t.Fatalf("expected the status to be lowercased, got %q", paid.Order.Status)
+	}
+	waitForLogStatus(t, logs, 1, paid.Order.ID, domain.NotificationStatusSent, time.Second)
+
+	imported, err := svc.ImportOrders(ctx, 1, []domain.ImportOrderRow{
+		{Line: 2, Order: domain.CreateOrderInput{Number: "A-3", Total: 100, CustomerName: "Vera", Status: "pending"}},
+		{Line: 3, Order: domain.CreateOrderInput{Number: "A-4", Total: 100, CustomerName: "Gleb", Status: "оплачен"}},
+	}, domain.ImportOrdersOptions{Notify: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if imported.Rows[0].SendStatus != domain.SendStatusSkipped || imported.Rows[1].Status != domain.ImportRowStatusInvalid {
+		t.Fatalf("expected the pending row skipped and the bad status rejected, got %+v", imported.Rows)
+	}
+
+	status, problem := doProblem(t, svc, http.MethodPost, "/v2/shops/1/orders", `{"number":"A-5","total":{"amount":"10"},"customerName":"Anna","status":"paid now"}`)
+	if status != http.StatusBadRequest || len(problem.Errors) != 1 || problem.Errors[0].Field != "status" {
+		t.Fatalf("expected a status field error, got %d %+v", status, problem)
+	}
+}
+
+func TestRuleEvaluationFailureDoesNotFailOrder(t *testing.T) {
+	logs := NewMockNotificationLogRepo()
+	svc, rules := newRuleService(t, &messageRecorder{}, logs)
+	rules.recordErr = errors.New("connection reset")
+	addRule(t, svc, domain.NotificationRuleInput{Name: "Все", Action: domain.RuleActionSilent})
+
+	// the order is saved before the evaluation, so a 500 would make the client create it twice
+	out, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{Number: "A-1", Total: 100, CustomerName: "Anna"})
+	if err != nil {
+		t.Fatalf("expected the order despite the lost evaluation, got %v", err)
+	}
+	waitForLogStatus(t, logs, 1, out.Order.ID, domain.NotificationStatusSent, time.Second)
+}
+
+func TestTelegramClientSendsSilentMessages(t *testing.T) {
+	var payloads []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		payloads = append(payloads, payload)
+		_, _ = io.WriteString(w, `{"ok":true}`)
+	}))
+	defer server.Close()
+
+	client := telegram.NewClient(server.URL, 0)
+
+	for _, silent := range []bool{false, true} {
+		if _, err := client.SendMessage(context.Background(), "123:abc", "chat", "hello", silent); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	if _, ok := payloads[0]["disable_notification"]; ok {
+		t.Fatalf("a usual message should not disable notifications: %v", payloads[0])
+	}
+	if payloads[1]["disable_notification"] != true {
+		t.Fatalf("expected disable_notification in %v", payloads[1])
+	}
+}
+
+func TestRulesValidateInput(t *testing.T) {
+	svc, _ := newRuleService(t, &messageRecorder{}, NewMockNotificationLogRepo())
+
+	status, problem := doProblem(t, svc, http.MethodPost, "/v2/shops/1/notification-rules",
+		`{"name":" ","action":"loud","conditions":{"minTotal":10,"maxTotal":5,"customerPattern":"(vip","statuses":["paid","не оплачен"]}}`)
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d %+v", status, problem)
+	}
+
+	fields := map[string]bool{}
+	for _, field := range problem.Errors {
+		fields[field.Field] = true
+	}
+	for _, want := range []string{"name", "action", "conditions.maxTotal", "conditions.customerPattern", "conditions.statuses"} {
+		if !fields[want] {
+			t.Errorf("expected a field error for %s, got %+v", want, problem.Errors)
+		}
+	}
+
+	for _, body := range []string{
+		`{"name":"VIP","action":"template"}`,
+		`{"name":"VIP","action":"template","template":"loud"}`,
+		`{"name":"Тихо","action":"silent","template":"vip"}`,
+	} {
+		status, problem = doProblem(t, svc, http.MethodPost, "/v2/shops/1/notification-rules", body)
+		if status != http.StatusBadRequest || len(problem.Errors) != 1 || problem.Errors[0].Field != "template" {
+			t.Fatalf("%s: expected a template field error, got %d %+v", body, status, problem)
+		}
+	}
+}
+
+func TestOrdersAreNotEvaluatedWithoutRules(t *testing.T) {
+	logs := NewMockNotificationLogRepo()
+	svc, _ := newRuleService(t, &messageRecorder{}, logs)
+
+	out, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{Number: "A-1", Total: 100, CustomerName: "Anna"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForLogStatus(t, logs, 1, out.Order.ID, domain.NotificationStatusSent, time.Second)
+
+	status, problem := doProblem(t, svc, http.MethodGet, fmt.Sprintf("/v2/shops/1/orders/%d/rule-evaluation", out.Order.ID), "")
+	if status != http.StatusNotFound || problem.Code != "evaluation_not_found" {
+		t.Fatalf("expected 404 evaluation_not_found, got %d %+v", status, problem)
+	}
+}
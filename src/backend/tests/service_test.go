@@ -2,50 +2,177 @@ package tests
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"maps"
+	"sort"
 	"sync"
 	"testing"
 	"time"
 
+	"growth-mvp/backend/adapters/telegram"
 	"growth-mvp/backend/domain"
 )
 
 type MockIntegrationRepo struct {
-	integration domain.TelegramIntegration
-	found       bool
+	mu    sync.Mutex
+	items []domain.Integration
 }
 
-func (f *MockIntegrationRepo) Upsert(_ context.Context, shopID int64, input domain.ConnectTelegramInput) (domain.TelegramIntegration, error) {
-	f.integration = domain.TelegramIntegration{
+func telegramIntegration(shopID int64, botToken, chatID string, enabled bool) domain.Integration {
+	return domain.Integration{
 		ID:       1,
 		ShopID:   shopID,
-		BotToken: input.BotToken,
-		ChatID:   input.ChatID,
-		Enabled:  input.Enabled,
+		Channel:  domain.ChannelTelegram,
+		Settings: map[string]string{domain.TelegramSettingChatID: chatID},
+		Secret:   botToken,
+		Enabled:  enabled,
+	}
+}
+
+func (f *MockIntegrationRepo) find(shopID int64, channel domain.ChannelType) int {
+	for i, item := range f.items {
+		if item.ShopID == shopID && item.Channel == channel {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *MockIntegrationRepo) Upsert(_ context.Context, shopID int64, channel domain.ChannelType, input domain.ConnectIntegrationInput) (domain.Integration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	integration := domain.Integration{
+		ShopID:             shopID,
+		Channel:            channel,
+		Settings:           maps.Clone(input.Settings),
+		Secret:             input.Secret,
+		Enabled:            input.Enabled,
+		BatchWindowSeconds: input.BatchWindowSeconds,
+	}
+	if i := f.find(shopID, channel); i >= 0 {
+		integration.ID = f.items[i].ID
+		f.items[i] = integration
+		return integration, nil
+	}
+	for _, item := range f.items {
+		integration.ID = max(integration.ID, item.ID)
+	}
+	integration.ID++
+	f.items = append(f.items, integration)
+	return integration, nil
+}
+
+func (f *MockIntegrationRepo) Get(_ context.Context, shopID int64, channel domain.ChannelType) (domain.Integration, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if i := f.find(shopID, channel); i >= 0 {
+		return f.items[i], true, nil
+	}
+	return domain.Integration{}, false, nil
+}
+
+func (f *MockIntegrationRepo) ListByShop(_ context.Context, shopID int64) ([]domain.Integration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := []domain.Integration{}
+	for _, item := range f.items {
+		if item.ShopID == shopID {
+			out = append(out, item)
+		}
 	}
-	f.found = true
-	return f.integration, nil
+	return out, nil
 }
 
-func (f *MockIntegrationRepo) GetByShopID(_ context.Context, _ int64) (domain.TelegramIntegration, bool, error) {
-	return f.integration, f.found, nil
+func (f *MockIntegrationRepo) Update(_ context.Context, shopID int64, channel domain.ChannelType, input domain.UpdateIntegrationInput) (domain.Integration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	i := f.find(shopID, channel)
+	if i < 0 {
+		return domain.Integration{}, domain.ErrShopNotIntegrated
+	}
+	if input.Settings != nil {
+		f.items[i].Settings = maps.Clone(input.Settings)
+	}
+	if input.Secret != nil {
+		f.items[i].Secret = *input.Secret
+	}
+	if input.Enabled != nil {
+		f.items[i].Enabled = *input.Enabled
+	}
+	if input.BatchWindowSeconds != nil {
+		f.items[i].BatchWindowSeconds = *input.BatchWindowSeconds
+	}
+	return f.items[i], nil
+}
+
+func (f *MockIntegrationRepo) Delete(_ context.Context, shopID int64, channel domain.ChannelType) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	i := f.find(shopID, channel)
+	if i < 0 {
+		return domain.ErrShopNotIntegrated
+	}
+	f.items = append(f.items[:i], f.items[i+1:]...)
+	return nil
 }
 
 type MockOrderRepo struct {
-	nextID    int64
-	listItems []domain.OrderListItem
+	nextID     int64
+	listItems  []domain.OrderListItem
+	batchCalls int
+	batchErrs  []error
+	created    []domain.Order
 }
 
 func (f *MockOrderRepo) Create(_ context.Context, shopID int64, input domain.CreateOrderInput) (domain.Order, error) {
 	f.nextID++
-	return domain.Order{
+	order := domain.Order{
 		ID:           f.nextID,
 		ShopID:       shopID,
 		Number:       input.Number,
 		Total:        input.Total,
 		CustomerName: input.CustomerName,
+		Status:       input.Status,
 		CreatedAt:    time.Now(),
-	}, nil
+	}
+	f.created = append(f.created, order)
+	return order, nil
+}
+
+func (f *MockOrderRepo) GetByID(_ context.Context, shopID, orderID int64) (domain.Order, error) {
+	for _, order := range f.created {
+		if order.ShopID == shopID && order.ID == orderID {
+			return order, nil
+		}
+	}
+	return domain.Order{}, domain.ErrOrderNotFound
+}
+
+func (f *MockOrderRepo) CreateBatch(ctx context.Context, shopID int64, rows []domain.ImportOrderRow) ([]domain.Order, error) {
+	f.batchCalls++
+	if len(f.batchErrs) > 0 {
+		err := f.batchErrs[0]
+		f.batchErrs = f.batchErrs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([]domain.Order, 0, len(rows))
+	for _, row := range rows {
+		order, _ := f.Create(ctx, shopID, row.Order)
+		if row.CreatedAt != nil {
+			order.CreatedAt = *row.CreatedAt
+		}
+		out = append(out, order)
+	}
+	return out, nil
 }
 
 func (f *MockOrderRepo) List(_ context.Context, _ int64, limit, offset int) ([]domain.OrderListItem, error) {
@@ -59,47 +186,95 @@ func (f *MockOrderRepo) List(_ context.Context, _ int64, limit, offset int) ([]d
 	return append([]domain.OrderListItem(nil), f.listItems[offset:end]...), nil
 }
 
-type MockSendLogRepo struct {
-	mu       sync.Mutex
-	reserved map[string]bool
-	logs     map[string]domain.TelegramSendLog
+type MockNotificationLogRepo struct {
+	mu         sync.Mutex
+	nextID     int64
+	reserved   map[string]bool
+	logs       map[string]domain.NotificationLog
+	keys       map[int64]string
+	statsSince time.Time
+	// reserveErrs are returned by the next Reserve calls, nil ones reserve.
+	reserveErrs []error
 }
 
-func NewMockSendLogRepo() *MockSendLogRepo {
-	return &MockSendLogRepo{
+func NewMockNotificationLogRepo() *MockNotificationLogRepo {
+	return &MockNotificationLogRepo{
 		reserved: map[string]bool{},
-		logs:     map[string]domain.TelegramSendLog{},
+		logs:     map[string]domain.NotificationLog{},
+		keys:     map[int64]string{},
 	}
 }
 
-func key(shopID, orderID int64) string {
-	return fmt.Sprintf("%d:%d", shopID, orderID)
+func key(integrationID, orderID int64) string {
+	return fmt.Sprintf("%d:%d", integrationID, orderID)
+}
+
+// entryKey matches the unique indexes: one row per integration, order and
+// recipient, and one per webhook subscription, order and event.
+func entryKey(entry domain.NotificationLog) string {
+	if entry.SubscriptionID != 0 {
+		return fmt.Sprintf("webhook:%d:%d:%s", entry.SubscriptionID, entry.OrderID, entry.Event)
+	}
+	if entry.Recipient != "" {
+		return recipientKey(entry.IntegrationID, entry.OrderID, entry.Recipient)
+	}
+	return key(entry.IntegrationID, entry.OrderID)
+}
+
+func recipientKey(integrationID, orderID int64, recipient string) string {
+	return fmt.Sprintf("%d:%d:%s", integrationID, orderID, recipient)
+}
+
+func (f *MockNotificationLogRepo) store(k string, entry domain.NotificationLog, reservedAt time.Time) int64 {
+	if log, ok := f.logs[k]; ok {
+		entry.ID = log.ID
+	} else {
+		f.nextID++
+		entry.ID = f.nextID
+	}
+	entry.Status = domain.NotificationStatusFailed
+	entry.SentAt = reservedAt
+	f.reserved[k] = true
+	f.logs[k] = entry
+	f.keys[entry.ID] = k
+	return entry.ID
 }
 
-func (f *MockSendLogRepo) Reserve(_ context.Context, shopID, orderID int64, message string, reservedAt time.Time) (bool, error) {
+func (f *MockNotificationLogRepo) Reserve(_ context.Context, entry domain.NotificationLog, reservedAt time.Time) (int64, bool, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	k := key(shopID, orderID)
+	if len(f.reserveErrs) > 0 {
+		err := f.reserveErrs[0]
+		f.reserveErrs = f.reserveErrs[1:]
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	k := entryKey(entry)
 	if f.reserved[k] {
-		return false, nil
+		return 0, false, nil
 	}
-	f.reserved[k] = true
-	f.logs[k] = domain.TelegramSendLog{
-		ShopID:  shopID,
-		OrderID: orderID,
-		Message: message,
-		Status:  domain.TelegramSendStatusFailed,
-		SentAt:  reservedAt,
+	return f.store(k, entry, reservedAt), true, nil
+}
+
+func (f *MockNotificationLogRepo) Retry(_ context.Context, entry domain.NotificationLog, reservedAt time.Time) (int64, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	k := entryKey(entry)
+	if log, ok := f.logs[k]; ok && (log.Status != domain.NotificationStatusFailed || log.Error == nil) {
+		return 0, false, nil
 	}
-	return true, nil
+	return f.store(k, entry, reservedAt), true, nil
 }
 
-func (f *MockSendLogRepo) Finalize(_ context.Context, shopID, orderID int64, status domain.TelegramSendStatus, errText *string, sentAt time.Time) error {
+func (f *MockNotificationLogRepo) Finalize(_ context.Context, logID int64, status domain.NotificationStatus, errText *string, sentAt time.Time) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	k := key(shopID, orderID)
+	k := f.keys[logID]
 	log := f.logs[k]
 	log.Status = status
 	log.Error = errText
@@ -108,28 +283,158 @@ func (f *MockSendLogRepo) Finalize(_ context.Context, shopID, orderID int64, sta
 	return nil
 }
 
-func (f *MockSendLogRepo) GetStatusStats(_ context.Context, _ int64, _ time.Time) (*time.Time, int64, int64, error) {
+func (f *MockNotificationLogRepo) LinkMessage(_ context.Context, messageID string, logIDs []int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, logID := range logIDs {
+		k := f.keys[logID]
+		log := f.logs[k]
+		log.MessageID = &messageID
+		f.logs[k] = log
+	}
+	return nil
+}
+
+func (f *MockNotificationLogRepo) GetStatusStats(_ context.Context, _ int64, _ domain.ChannelType, since time.Time) (*time.Time, int64, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.statsSince = since
 	return nil, 0, 0, nil
 }
 
+func (f *MockNotificationLogRepo) ClaimDue(_ context.Context, now time.Time, limit int) ([]domain.NotificationLog, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := []domain.NotificationLog{}
+	for id := int64(1); id <= f.nextID && len(out) < limit; id++ {
+		k, ok := f.keys[id]
+		if !ok {
+			continue
+		}
+		log := f.logs[k]
+		if log.DeliverAfter == nil || log.DeliverAfter.After(now) || log.Status != domain.NotificationStatusFailed || log.Error != nil {
+			continue
+		}
+		log.DeliverAfter = nil
+		f.logs[k] = log
+		out = append(out, log)
+	}
+	return out, nil
+}
+
+func (f *MockNotificationLogRepo) OpenBatch(_ context.Context, integrationID int64, recipient string, now time.Time) (*time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var open *time.Time
+	for _, log := range f.logs {
+		if log.IntegrationID != integrationID || log.Recipient != recipient || log.DeliverAfter == nil || !log.DeliverAfter.After(now) {
+			continue
+		}
+		if log.Status != domain.NotificationStatusFailed || log.Error != nil {
+			continue
+		}
+		if open == nil || log.DeliverAfter.Before(*open) {
+			deliverAfter := *log.DeliverAfter
+			open = &deliverAfter
+		}
+	}
+	return open, nil
+}
+
+func (f *MockNotificationLogRepo) GetRecipientStats(_ context.Context, integrationID int64, since time.Time) (map[string]domain.RecipientStats, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := map[string]domain.RecipientStats{}
+	for _, log := range f.logs {
+		if log.IntegrationID != integrationID || log.SubscriptionID != 0 {
+			continue
+		}
+		stats := out[log.Recipient]
+		if log.Status == domain.NotificationStatusSent && (stats.LastSentAt == nil || log.SentAt.After(*stats.LastSentAt)) {
+			sentAt := log.SentAt
+			stats.LastSentAt = &sentAt
+		}
+		if !log.SentAt.Before(since) {
+			switch log.Status {
+			case domain.NotificationStatusSent:
+				stats.SentCount++
+			case domain.NotificationStatusFailed:
+				if log.Error != nil {
+					stats.FailedCount++
+				}
+			}
+		}
+		out[log.Recipient] = stats
+	}
+	return out, nil
+}
+
+func (f *MockNotificationLogRepo) ListWebhookDeliveries(_ context.Context, webhookID int64, limit, offset int) ([]domain.WebhookDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var out []domain.WebhookDelivery
+	for _, log := range f.logs {
+		if log.SubscriptionID != webhookID {
+			continue
+		}
+		status := log.Status
+		if status == domain.NotificationStatusFailed && log.Error == nil {
+			status = domain.DeliveryStatusPending
+		}
+		out = append(out, domain.WebhookDelivery{
+			ID: log.ID, WebhookID: webhookID, OrderID: log.OrderID, Event: log.Event,
+			Status: status, Error: log.Error, Payload: json.RawMessage(log.Message), SentAt: log.SentAt,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+
+	if offset >= len(out) {
+		return []domain.WebhookDelivery{}, nil
+	}
+	return out[offset:min(offset+limit, len(out))], nil
+}
+
+func (f *MockNotificationLogRepo) Redeliver(_ context.Context, webhookID, deliveryID int64, reservedAt time.Time) (domain.NotificationLog, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	k, ok := f.keys[deliveryID]
+	log := f.logs[k]
+	if !ok || log.SubscriptionID != webhookID {
+		return domain.NotificationLog{}, false, domain.ErrDeliveryNotFound
+	}
+	if log.Status == domain.NotificationStatusFailed && log.Error == nil {
+		return domain.NotificationLog{}, false, nil
+	}
+	log.Error = nil
+	f.store(k, log, reservedAt)
+	return f.logs[k], true, nil
+}
+
 type MockTelegramClient struct {
 	mu    sync.Mutex
 	calls int
 	errs  []error
 }
 
-func (f *MockTelegramClient) SendMessage(_ context.Context, _, _, _ string) error {
+func (f *MockTelegramClient) SendMessage(_ context.Context, _, _, _ string, _ bool) (int64, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
 	f.calls++
 	if len(f.errs) == 0 {
-		return nil
+		return int64(f.calls), nil
 	}
 
 	err := f.errs[0]
 	f.errs = f.errs[1:]
-	return err
+	return 0, err
 }
 
 func (f *MockTelegramClient) Calls() int {
@@ -138,11 +443,27 @@ func (f *MockTelegramClient) Calls() int {
 	return f.calls
 }
 
-func waitForLogStatus(t *testing.T, repo *MockSendLogRepo, shopID, orderID int64, want domain.TelegramSendStatus, timeout time.Duration) {
+func telegramNotifiers(client telegram.Sender) *domain.NotifierRegistry {
+	return domain.NewNotifierRegistry().Register(domain.ChannelTelegram, telegram.NewNotifier(client))
+}
+
+func decodeJSON(t *testing.T, body []byte, out any) {
+	t.Helper()
+
+	if err := json.Unmarshal(body, out); err != nil {
+		t.Fatalf("decode response %s: %v", body, err)
+	}
+}
+
+func waitForLogStatus(t *testing.T, repo *MockNotificationLogRepo, integrationID, orderID int64, want domain.NotificationStatus, timeout time.Duration) {
+	t.Helper()
+	waitForKeyStatus(t, repo, key(integrationID, orderID), want, timeout)
+}
+
+func waitForKeyStatus(t *testing.T, repo *MockNotificationLogRepo, k string, want domain.NotificationStatus, timeout time.Duration) {
 	t.Helper()
 
 	deadline := time.Now().Add(timeout)
-	k := key(shopID, orderID)
 	for time.Now().Before(deadline) {
 		repo.mu.Lock()
 		log := repo.logs[k]
@@ -158,6 +479,26 @@ func waitForLogStatus(t *testing.T, repo *MockSendLogRepo, shopID, orderID int64
 	t.Fatalf("expected log status %s, got %s", want, repo.logs[k].Status)
 }
 
+// waitForLogError waits for the send to finish failing: reserved rows are
+// FAILED already, but without an error.
+func waitForLogError(t *testing.T, repo *MockNotificationLogRepo, k string, timeout time.Duration) string {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		repo.mu.Lock()
+		log := repo.logs[k]
+		repo.mu.Unlock()
+		if log.Status == domain.NotificationStatusFailed && log.Error != nil {
+			return *log.Error
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("expected the send to fail")
+	return ""
+}
+
 func waitForCalls(t *testing.T, client *MockTelegramClient, want int, timeout time.Duration) {
 	t.Helper()
 
@@ -173,21 +514,13 @@ func waitForCalls(t *testing.T, client *MockTelegramClient, want int, timeout ti
 }
 
 func TestCreateOrderEnabledIntegrationQueuesNotification(t *testing.T) {
-	integrationRepo := &MockIntegrationRepo{
-		found: true,
-		integration: domain.TelegramIntegration{
-			ShopID:   1,
-			BotToken: "token",
-			ChatID:   "chat",
-			Enabled:  true,
-		},
-	}
+	integrationRepo := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "token", "chat", true)}}
 
 	orderRepo := &MockOrderRepo{}
-	sendLogRepo := NewMockSendLogRepo()
+	sendLogRepo := NewMockNotificationLogRepo()
 	telegramClient := &MockTelegramClient{}
 
-	svc := domain.NewService(integrationRepo, orderRepo, sendLogRepo, telegramClient, 3)
+	svc := domain.NewService(integrationRepo, orderRepo, sendLogRepo, telegramNotifiers(telegramClient), 3)
 	out, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{
 		Number:       "A-0001",
 		Total:        100,
@@ -203,28 +536,20 @@ func TestCreateOrderEnabledIntegrationQueuesNotification(t *testing.T) {
 	}
 
 	waitForCalls(t, telegramClient, 1, time.Second)
-	waitForLogStatus(t, sendLogRepo, 1, out.Order.ID, domain.TelegramSendStatusSent, time.Second)
+	waitForLogStatus(t, sendLogRepo, 1, out.Order.ID, domain.NotificationStatusSent, time.Second)
 	if telegramClient.Calls() != 1 {
 		t.Fatalf("expected one send call, got %d", telegramClient.Calls())
 	}
 }
 
 func TestDuplicateSendDoesNotSendAgain(t *testing.T) {
-	integrationRepo := &MockIntegrationRepo{
-		found: true,
-		integration: domain.TelegramIntegration{
-			ShopID:   1,
-			BotToken: "token",
-			ChatID:   "chat",
-			Enabled:  true,
-		},
-	}
+	integrationRepo := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "token", "chat", true)}}
 	orderRepo := &MockOrderRepo{}
-	sendLogRepo := NewMockSendLogRepo()
+	sendLogRepo := NewMockNotificationLogRepo()
 	telegramClient := &MockTelegramClient{}
-	svc := domain.NewService(integrationRepo, orderRepo, sendLogRepo, telegramClient, 3)
+	svc := domain.NewService(integrationRepo, orderRepo, sendLogRepo, telegramNotifiers(telegramClient), 3)
 
-	sendLogRepo.Reserve(context.Background(), 1, 1, "msg", time.Now())
+	sendLogRepo.Reserve(context.Background(), domain.NotificationLog{ShopID: 1, OrderID: 1, IntegrationID: 1, Message: "msg"}, time.Now())
 
 	out, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{
 		Number:       "A-0001",
@@ -246,23 +571,15 @@ func TestDuplicateSendDoesNotSendAgain(t *testing.T) {
 }
 
 func TestTelegramFailureDoesNotBreakOrderCreation(t *testing.T) {
-	integrationRepo := &MockIntegrationRepo{
-		found: true,
-		integration: domain.TelegramIntegration{
-			ShopID:   1,
-			BotToken: "token",
-			ChatID:   "chat",
-			Enabled:  true,
-		},
-	}
+	integrationRepo := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "token", "chat", true)}}
 
 	orderRepo := &MockOrderRepo{}
-	sendLogRepo := NewMockSendLogRepo()
+	sendLogRepo := NewMockNotificationLogRepo()
 	sendErr := fmt.Errorf("telegram timeout")
 	telegramClient := &MockTelegramClient{
 		errs: []error{sendErr, sendErr, sendErr},
 	}
-	svc := domain.NewService(integrationRepo, orderRepo, sendLogRepo, telegramClient, 3)
+	svc := domain.NewService(integrationRepo, orderRepo, sendLogRepo, telegramNotifiers(telegramClient), 3)
 
 	out, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{
 		Number:       "A-0002",
@@ -283,7 +600,7 @@ func TestTelegramFailureDoesNotBreakOrderCreation(t *testing.T) {
 	}
 
 	waitForCalls(t, telegramClient, 3, 2*time.Second)
-	waitForLogStatus(t, sendLogRepo, 1, out.Order.ID, domain.TelegramSendStatusFailed, 2*time.Second)
+	waitForLogStatus(t, sendLogRepo, 1, out.Order.ID, domain.NotificationStatusFailed, 2*time.Second)
 	if telegramClient.Calls() != 3 {
 		t.Fatalf("expected 3 send attempts, got %d", telegramClient.Calls())
 	}
@@ -298,7 +615,7 @@ func TestListOrdersPagination(t *testing.T) {
 			{ID: 3, ShopID: 1, Number: "A-3", CreatedAt: now.Add(-2 * time.Minute), SendStatus: domain.SendStatusFailed},
 		},
 	}
-	svc := domain.NewService(&MockIntegrationRepo{}, orderRepo, NewMockSendLogRepo(), &MockTelegramClient{}, 3)
+	svc := domain.NewService(&MockIntegrationRepo{}, orderRepo, NewMockNotificationLogRepo(), telegramNotifiers(&MockTelegramClient{}), 3)
 
 	out, err := svc.ListOrders(context.Background(), 1, 2, 0)
 	if err != nil {
@@ -325,7 +642,7 @@ func TestListOrdersNormalizesInvalidPagination(t *testing.T) {
 			{ID: 1, ShopID: 1, Number: "A-1", CreatedAt: now, SendStatus: domain.SendStatusPending},
 		},
 	}
-	svc := domain.NewService(&MockIntegrationRepo{}, orderRepo, NewMockSendLogRepo(), &MockTelegramClient{}, 3)
+	svc := domain.NewService(&MockIntegrationRepo{}, orderRepo, NewMockNotificationLogRepo(), telegramNotifiers(&MockTelegramClient{}), 3)
 
 	out, err := svc.ListOrders(context.Background(), 1, -10, -5)
 	if err != nil {
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/domain"
+)
+
+type MockShopRepo struct {
+	nextID int64
+	shops  map[int64]domain.Shop
+}
+
+func NewMockShopRepo() *MockShopRepo {
+	return &MockShopRepo{shops: map[int64]domain.Shop{}}
+}
+
+func (f *MockShopRepo) Create(_ context.Context, input domain.ShopInput) (domain.Shop, error) {
+	f.nextID++
+	shop := domain.Shop{
+		ID:         f.nextID,
+		Name:       input.Name,
+		Timezone:   input.Timezone,
+		Locale:     input.Locale,
+		Currency:   input.Currency,
+		QuietHours: input.QuietHours,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	f.shops[shop.ID] = shop
+	return shop, nil
+}
+
+func (f *MockShopRepo) GetByID(_ context.Context, shopID int64) (domain.Shop, error) {
+	shop, ok := f.shops[shopID]
+	if !ok {
+		return domain.Shop{}, domain.ErrShopNotFound
+	}
+	return shop, nil
+}
+
+func (f *MockShopRepo) List(_ context.Context, limit, offset int) ([]domain.Shop, error) {
+	out := []domain.Shop{}
+	for id := int64(1); id <= f.nextID; id++ {
+		if shop, ok := f.shops[id]; ok {
+			out = append(out, shop)
+		}
+	}
+	if offset >= len(out) {
+		return []domain.Shop{}, nil
+	}
+	return out[offset:min(offset+limit, len(out))], nil
+}
+
+func (f *MockShopRepo) Update(_ context.Context, shopID int64, input domain.ShopInput) (domain.Shop, error) {
+	shop, ok := f.shops[shopID]
+	if !ok {
+		return domain.Shop{}, domain.ErrShopNotFound
+	}
+	shop.Name = input.Name
+	shop.Timezone = input.Timezone
+	shop.Locale = input.Locale
+	shop.Currency = input.Currency
+	shop.QuietHours = input.QuietHours
+	shop.UpdatedAt = time.Now()
+	f.shops[shopID] = shop
+	return shop, nil
+}
+
+func (f *MockShopRepo) Delete(_ context.Context, shopID int64) error {
+	if _, ok := f.shops[shopID]; !ok {
+		return domain.ErrShopNotFound
+	}
+	delete(f.shops, shopID)
+	return nil
+}
+
+func TestCreateShopAppliesDefaults(t *testing.T) {
+	svc := domain.NewShopService(NewMockShopRepo())
+
+	shop, err := svc.CreateShop(context.Background(), domain.ShopInput{Name: "  Demo  ", Currency: "usd"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if shop.Name != "Demo" {
+		t.Fatalf("expected trimmed name, got %q", shop.Name)
+	}
+	if shop.Timezone != domain.DefaultShopTimezone || shop.Locale != domain.DefaultShopLocale {
+		t.Fatalf("expected default timezone and locale, got %q %q", shop.Timezone, shop.Locale)
+	}
+	if shop.Currency != "USD" {
+		t.Fatalf("expected upper-cased currency, got %q", shop.Currency)
+	}
+}
+
+func TestCreateShopRejectsUnknownTimezone(t *testing.T) {
+	svc := domain.NewShopService(NewMockShopRepo())
+
+	_, err := svc.CreateShop(context.Background(), domain.ShopInput{Name: "Demo", Timezone: "Mars/Olympus"})
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput, got %v", err)
+	}
+}
+
+func TestUpdateMissingShopReturnsNotFound(t *testing.T) {
+	svc := domain.NewShopService(NewMockShopRepo())
+
+	_, err := svc.UpdateShop(context.Background(), 42, domain.ShopInput{Name: "Demo"})
+	if !errors.Is(err, domain.ErrShopNotFound) {
+		t.Fatalf("expected ErrShopNotFound, got %v", err)
+	}
+
+	if err := svc.DeleteShop(context.Background(), 42); !errors.Is(err, domain.ErrShopNotFound) {
+		t.Fatalf("expected ErrShopNotFound, got %v", err)
+	}
+}
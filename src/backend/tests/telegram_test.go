@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"growth-mvp/backend/domain"
+)
+
+func TestUpdateTelegramTogglesWithoutToken(t *testing.T) {
+	integrationRepo := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "token", "chat", true)}}
+	svc := domain.NewService(integrationRepo, &MockOrderRepo{}, NewMockNotificationLogRepo(), telegramNotifiers(&MockTelegramClient{}), 3)
+
+	disabled := false
+	out, err := svc.UpdateTelegram(context.Background(), 1, domain.UpdateTelegramInput{Enabled: &disabled})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if out.Enabled {
+		t.Fatal("expected integration to be disabled")
+	}
+	stored := integrationRepo.items[0]
+	if stored.Secret != "token" || stored.Settings[domain.TelegramSettingChatID] != "chat" {
+		t.Fatalf("expected credentials to be kept, got %+v", stored)
+	}
+}
+
+func TestUpdateTelegramValidatesInput(t *testing.T) {
+	integrationRepo := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "", "", false)}}
+	svc := domain.NewService(integrationRepo, &MockOrderRepo{}, NewMockNotificationLogRepo(), telegramNotifiers(&MockTelegramClient{}), 3)
+
+	if _, err := svc.UpdateTelegram(context.Background(), 1, domain.UpdateTelegramInput{}); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput for empty patch, got %v", err)
+	}
+
+	blank := "  "
+	if _, err := svc.UpdateTelegram(context.Background(), 1, domain.UpdateTelegramInput{ChatID: &blank}); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput for blank chatId, got %v", err)
+	}
+}
+
+func TestDisconnectTelegramSkipsFurtherNotifications(t *testing.T) {
+	integrationRepo := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "token", "chat", true)}}
+	telegramClient := &MockTelegramClient{}
+	svc := domain.NewService(integrationRepo, &MockOrderRepo{}, NewMockNotificationLogRepo(), telegramNotifiers(telegramClient), 3)
+
+	if err := svc.DisconnectTelegram(context.Background(), 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.DisconnectTelegram(context.Background(), 1); !errors.Is(err, domain.ErrShopNotIntegrated) {
+		t.Fatalf("expected ErrShopNotIntegrated on second disconnect, got %v", err)
+	}
+
+	out, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{
+		Number:       "A-0001",
+		Total:        100,
+		CustomerName: "Anna",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.SendStatus != domain.SendStatusSkipped {
+		t.Fatalf("expected skipped status, got %s", out.SendStatus)
+	}
+}
+
+func TestConnectTelegramResponseHidesToken(t *testing.T) {
+	svc := domain.NewService(&MockIntegrationRepo{}, &MockOrderRepo{}, NewMockNotificationLogRepo(), telegramNotifiers(&MockTelegramClient{}), 3)
+
+	out, err := svc.ConnectTelegram(context.Background(), 1, domain.ConnectTelegramInput{
+		BotToken: "123456:secret-token",
+		ChatID:   "-1001234567890",
+		Enabled:  true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	body, err := json.Marshal(out)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if strings.Contains(string(body), "secret-token") {
+		t.Fatalf("response leaks the bot token: %s", body)
+	}
+	if out.BotTokenFingerprint != domain.TokenFingerprint("123456:secret-token") {
+		t.Fatalf("unexpected fingerprint %q", out.BotTokenFingerprint)
+	}
+}
@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"growth-mvp/backend/adapters/postgres"
+)
+
+func randomKey(t *testing.T) []byte {
+	t.Helper()
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return key
+}
+
+func TestTokenCipherRoundTrip(t *testing.T) {
+	c, err := postgres.NewTokenCipher(map[string][]byte{"k1": randomKey(t)}, "k1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	encrypted, err := c.Encrypt("123456:secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bytes.Contains(encrypted.Ciphertext, []byte("secret")) {
+		t.Fatal("ciphertext contains the plaintext token")
+	}
+	if encrypted.KeyID != "k1" {
+		t.Fatalf("expected key id k1, got %s", encrypted.KeyID)
+	}
+
+	plaintext, err := c.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plaintext != "123456:secret" {
+		t.Fatalf("unexpected plaintext %q", plaintext)
+	}
+}
+
+func TestTokenCipherRotation(t *testing.T) {
+	oldKey, newKey := randomKey(t), randomKey(t)
+
+	before, _ := postgres.NewTokenCipher(map[string][]byte{"k1": oldKey}, "k1")
+	encrypted, err := before.Encrypt("123456:secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	after, err := postgres.NewTokenCipher(map[string][]byte{"k1": oldKey, "k2": newKey}, "k2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rewrapped, err := after.Rewrap(encrypted)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rewrapped.KeyID != "k2" {
+		t.Fatalf("expected key id k2, got %s", rewrapped.KeyID)
+	}
+
+	onlyNew, _ := postgres.NewTokenCipher(map[string][]byte{"k2": newKey}, "k2")
+	plaintext, err := onlyNew.Decrypt(rewrapped)
+	if err != nil {
+		t.Fatalf("unexpected error after retiring old key: %v", err)
+	}
+	if plaintext != "123456:secret" {
+		t.Fatalf("unexpected plaintext %q", plaintext)
+	}
+
+	if _, err := onlyNew.Decrypt(encrypted); err == nil {
+		t.Fatal("expected decrypt with a retired key to fail")
+	}
+}
+
+func TestLoadKeyringFromEnvAndFile(t *testing.T) {
+	k1 := base64.StdEncoding.EncodeToString(randomKey(t))
+	k2 := base64.StdEncoding.EncodeToString(randomKey(t))
+
+	file := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(file, []byte("# rotated 2024-05\nk2:"+k2+"\n"), 0o600); err != nil {
+		t.Fatalf("write keyring: %v", err)
+	}
+
+	keys, active, err := postgres.LoadKeyring("k1:"+k1, file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(keys) != 2 || active != "k2" {
+		t.Fatalf("expected two keys with k2 active, got %d keys, active %q", len(keys), active)
+	}
+
+	if _, _, err := postgres.LoadKeyring("k1:"+base64.StdEncoding.EncodeToString([]byte("short")), ""); err == nil {
+		t.Fatal("expected error for a short key")
+	}
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/adapters/telegram"
+	"growth-mvp/backend/adapters/tracing"
+	"growth-mvp/backend/api"
+	"growth-mvp/backend/domain"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func useSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestOrderTraceCoversAsyncNotificationSend(t *testing.T) {
+	recorder := useSpanRecorder(t)
+
+	integrationRepo := &MockIntegrationRepo{items: []domain.Integration{telegramIntegration(1, "token", "-1001234567890", true)}}
+	logRepo := NewMockNotificationLogRepo()
+	telegramClient := &MockTelegramClient{errs: []error{errors.New("timeout")}}
+	notifiers := domain.NewNotifierRegistry().
+		Register(domain.ChannelTelegram, tracing.NewNotifier(domain.ChannelTelegram, telegram.NewNotifier(telegramClient)))
+	svc := domain.NewService(integrationRepo,
+		tracing.NewOrderRepository(&MockOrderRepo{}),
+		tracing.NewNotificationLogRepository(logRepo),
+		notifiers,
+		3,
+	)
+
+	gin.SetMode(gin.TestMode)
+	shops := NewMockShopRepo()
+	shops.shops[1] = domain.Shop{ID: 1, Name: "Demo Shop", Currency: "RUB"}
+	handler := api.NewHandler(svc, domain.NewShopService(shops), domain.NewAPIKeyService(NewMockAPIKeyRepo(), "admin-secret"), newAuthService())
+
+	router := gin.New()
+	router.Use(otelgin.Middleware("test"), api.RequestID())
+	handler.RegisterRoutes(router)
+
+	rec := serveAdmin(router, http.MethodPost, "/v2/shops/1/orders", `{"number":"A-1","total":{"amount":"10.00"},"customerName":"Anna"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var out api.OrderSendResultV2
+	decodeJSON(t, rec.Body.Bytes(), &out)
+	waitForLogStatus(t, logRepo, 1, out.Order.ID, domain.NotificationStatusSent, 2*time.Second)
+
+	deadline := time.Now().Add(time.Second)
+	for len(recorder.Ended()) < 6 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	counts := map[string]int{}
+	spans := recorder.Ended()
+	root := spans[0].SpanContext().TraceID()
+
+	for _, span := range spans {
+		counts[span.Name()]++
+
+		if span.SpanContext().TraceID() != root {
+			t.Errorf("span %s belongs to another trace", span.Name())
+		}
+	}
+
+	want := map[string]int{
+		"POST /v2/shops/:shopId/orders":      1,
+		"OrderRepository.Create":             1,
+		"NotificationLogRepository.Reserve":  1,
+		"Notifier.Send":                      2,
+		"NotificationLogRepository.Finalize": 1,
+	}
+	for name, n := range want {
+		if counts[name] != n {
+			t.Errorf("expected %d %q spans, got %d (all: %v)", n, name, counts[name], counts)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/api"
+	"growth-mvp/backend/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+func versionRouter(t *testing.T, orders *MockOrderRepo) *gin.Engine {
+	t.Helper()
+
+	svc := domain.NewService(&MockIntegrationRepo{}, orders, NewMockNotificationLogRepo(), telegramNotifiers(&MockTelegramClient{}), 3)
+	return newTestRouter(t, svc, domain.NewAPIKeyService(NewMockAPIKeyRepo(), "admin-secret"), newAuthService())
+}
+
+func serveAdmin(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestV1AndUnversionedRoutesAreDeprecatedAliases(t *testing.T) {
+	router := versionRouter(t, &MockOrderRepo{})
+	body := `{"number":"A-1","total":1990.5,"customerName":"Anna"}`
+
+	for _, prefix := range []string{"", "/v1"} {
+		rec := serveAdmin(router, http.MethodPost, prefix+"/shops/1/orders", body)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("%q: unexpected status %d: %s", prefix, rec.Code, rec.Body.String())
+		}
+
+		var out domain.OrderSendResult
+		decodeJSON(t, rec.Body.Bytes(), &out)
+
+		if out.Order.Total != 1990.5 {
+			t.Fatalf("%q: v1 total should stay a number, got %+v", prefix, out.Order)
+		}
+		if !strings.HasPrefix(rec.Header().Get("Deprecation"), "@") {
+			t.Fatalf("%q: expected Deprecation header, got %v", prefix, rec.Header())
+		}
+		if rec.Header().Get("Link") != `</v2/shops/1/orders>; rel="successor-version"` {
+			t.Fatalf("%q: unexpected Link header %q", prefix, rec.Header().Get("Link"))
+		}
+	}
+}
+
+func TestV2UsesMoneyAndLowercaseStatuses(t *testing.T) {
+	orders := &MockOrderRepo{listItems: []domain.OrderListItem{
+		{ID: 2, ShopID: 1, Number: "A-2", Total: 10, CustomerName: "Boris", CreatedAt: time.Now(), SendStatus: "SENT"},
+		{ID: 1, ShopID: 1, Number: "A-1", Total: 5.5, CustomerName: "Anna", CreatedAt: time.Now()},
+	}}
+	router := versionRouter(t, orders)
+
+	rec := serveAdmin(router, http.MethodPost, "/v2/shops/1/orders", `{"number":"A-3","total":{"amount":"1990.50"},"customerName":"Anna"}`)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Deprecation") != "" {
+		t.Fatalf("unexpected response %d %v: %s", rec.Code, rec.Header(), rec.Body.String())
+	}
+
+	var created api.OrderSendResultV2
+	decodeJSON(t, rec.Body.Bytes(), &created)
+	if created.Order.Total != (api.Money{Amount: "1990.50", Currency: "RUB"}) {
+		t.Fatalf("unexpected money %+v", created.Order.Total)
+	}
+
+	rec = serveAdmin(router, http.MethodGet, "/v2/shops/1/orders", "")
+	var list api.ListOrdersResultV2
+	decodeJSON(t, rec.Body.Bytes(), &list)
+	if len(list.Items) != 2 || list.Items[0].SendStatus != "sent" || list.Items[1].SendStatus != "none" || list.Items[1].Total.Amount != "5.50" {
+		t.Fatalf("unexpected list %+v", list.Items)
+	}
+}
+
+func TestV2RejectsInvalidMoney(t *testing.T) {
+	router := versionRouter(t, &MockOrderRepo{})
+
+	cases := map[string]string{
+		`{"amount":"1e3"}`:                   "total.amount",
+		`{"amount":"10.999"}`:                "total.amount",
+		`{"amount":"0"}`:                     "total.amount",
+		`{"amount":"12345678901"}`:           "total.amount",
+		`{"amount":"9999999999.99"}`:         "",
+		`{"amount":"10","currency":"USD"}`:   "total.currency",
+		`{"amount":"10.5","currency":"rub"}`: "",
+	}
+
+	for total, field := range cases {
+		rec := serveAdmin(router, http.MethodPost, "/v2/shops/1/orders", `{"number":"A-1","total":`+total+`,"customerName":"Anna"}`)
+
+		if field == "" {
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("%s: expected 201, got %d: %s", total, rec.Code, rec.Body.String())
+			}
+			continue
+		}
+
+		var problem api.Problem
+		decodeJSON(t, rec.Body.Bytes(), &problem)
+		if rec.Code != http.StatusBadRequest || len(problem.Errors) != 1 || problem.Errors[0].Field != field {
+			t.Fatalf("%s: expected error on %s, got %d %+v", total, field, rec.Code, problem)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package tests
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"growth-mvp/backend/adapters/egress"
+	"growth-mvp/backend/adapters/webhook"
+	"growth-mvp/backend/domain"
+)
+
+const webhookSecret = "whsec-0123456789abcdef"
+
+type MockWebhookRepo struct {
+	mu      sync.Mutex
+	nextID  int64
+	items   []domain.WebhookSubscription
+	listErr error
+}
+
+func (f *MockWebhookRepo) Create(_ context.Context, shopID int64, input domain.WebhookInput) (domain.WebhookSubscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextID++
+	subscription := domain.WebhookSubscription{
+		ID:        f.nextID,
+		ShopID:    shopID,
+		URL:       input.URL,
+		Secret:    input.Secret,
+		Events:    input.Events,
+		Enabled:   input.Enabled,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	f.items = append(f.items, subscription)
+	return subscription, nil
+}
+
+func (f *MockWebhookRepo) Get(_ context.Context, shopID, webhookID int64) (domain.WebhookSubscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, item := range f.items {
+		if item.ShopID == shopID && item.ID == webhookID {
+			return item, nil
+		}
+	}
+	return domain.WebhookSubscription{}, domain.ErrWebhookNotFound
+}
+
+func (f *MockWebhookRepo) ListByShop(_ context.Context, shopID int64) ([]domain.WebhookSubscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+
+	out := []domain.WebhookSubscription{}
+	for _, item := range f.items {
+		if item.ShopID == shopID {
+			out = append(out, item)
+		}
+	}
+	return out, nil
+}
+
+func (f *MockWebhookRepo) Update(_ context.Context, shopID, webhookID int64, input domain.UpdateWebhookInput) (domain.WebhookSubscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, item := range f.items {
+		if item.ShopID != shopID || item.ID != webhookID {
+			continue
+		}
+		if input.URL != nil {
+			item.URL = *input.URL
+		}
+		if input.Secret != nil {
+			item.Secret = *input.Secret
+		}
+		if input.Events != nil {
+			item.Events = input.Events
+		}
+		if input.Enabled != nil {
+			item.Enabled = *input.Enabled
+		}
+		item.UpdatedAt = time.Now()
+		f.items[i] = item
+		return item, nil
+	}
+	return domain.WebhookSubscription{}, domain.ErrWebhookNotFound
+}
+
+func (f *MockWebhookRepo) Delete(_ context.Context, shopID, webhookID int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, item := range f.items {
+		if item.ShopID == shopID && item.ID == webhookID {
+			f.items = slices.Delete(f.items, i, i+1)
+			return nil
+		}
+	}
+	return domain.ErrWebhookNotFound
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookReceiver is a local subscriber endpoint. It answers with the queued
+// statuses first and 204 afterwards.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	received []receivedWebhook
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	r.received = append(r.received, receivedWebhook{header: req.Header.Clone(), body: body})
+	status := http.StatusNoContent
+	if len(r.statuses) > 0 {
+		status = r.statuses[0]
+		r.statuses = r.statuses[1:]
+	}
+	r.mu.Unlock()
+
+	w.WriteHeader(status)
+}
+
+func (r *webhookReceiver) wait(t *testing.T, want int) []receivedWebhook {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		got := slices.Clone(r.received)
+		r.mu.Unlock()
+		if len(got) >= want {
+			return got
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected %d webhook requests", want)
+	return nil
+}
+
+func newWebhookService(webhooks *MockWebhookRepo, logs *MockNotificationLogRepo, orders *MockOrderRepo, outbound egress.Config) *domain.Service {
+	return domain.NewService(&MockIntegrationRepo{}, orders, logs, telegramNotifiers(&MockTelegramClient{}), 1,
+		domain.WithWebhooks(webhooks, webhook.NewNotifier(outbound)))
+}
+
+// trustServer lets the egress guard reach server, which listens on loopback
+// with a self-signed certificate.
+func trustServer(server *httptest.Server) egress.Config {
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	return egress.Config{Timeout: time.Second, AllowedHosts: []string{"127.0.0.1"}, RootCAs: roots}
+}
+
+func waitForDelivery(t *testing.T, svc *domain.Service, webhookID int64, want domain.NotificationStatus) domain.WebhookDelivery {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		out, err := svc.ListWebhookDeliveries(context.Background(), 1, webhookID, 20, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(out.Items) > 0 && out.Items[0].Status == want {
+			return out.Items[0]
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected latest delivery to be %s, got %+v", want, out.Items)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebhookIsSignedAndDelivered(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewTLSServer(receiver)
+	defer server.Close()
+
+	webhooks := &MockWebhookRepo{}
+	svc := newWebhookService(webhooks, NewMockNotificationLogRepo(), &MockOrderRepo{}, trustServer(server))
+	router := newTestRouter(t, svc, domain.NewAPIKeyService(NewMockAPIKeyRepo(), "admin-secret"), newAuthService())
+
+	rec := serveAdmin(router, http.MethodPost, "/v2/shops/1/webhooks",
+		`{"url":"`+server.URL+`/hooks/orders","secret":"`+webhookSecret+`","events":["order.created"],"enabled":true}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), webhookSecret) || strings.Contains(rec.Body.String(), "/hooks/orders") {
+		t.Fatalf("response leaks the secret or URL path: %s", rec.Body.String())
+	}
+
+	out, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{Number: "A-1", Total: 10, CustomerName: "Anna"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.SendStatus != domain.SendStatusSkipped {
+		t.Fatalf("webhooks should not change the send status, got %s", out.SendStatus)
+	}
+
+	got := receiver.wait(t, 1)[0]
+	timestamp, err := strconv.ParseInt(got.header.Get(webhook.HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("bad timestamp header %q", got.header.Get(webhook.HeaderTimestamp))
+	}
+	if want := webhook.Sign(webhookSecret, timestamp, got.body); got.header.Get(webhook.HeaderSignature) != want {
+		t.Fatalf("signature mismatch: got %q, want %q", got.header.Get(webhook.HeaderSignature), want)
+	}
+	if got.header.Get(webhook.HeaderEvent) != domain.WebhookEventOrderCreated || got.header.Get(webhook.HeaderDelivery) == "" {
+		t.Fatalf("unexpected headers %v", got.header)
+	}
+
+	var payload domain.WebhookPayload
+	decodeJSON(t, got.body, &payload)
+	if payload.Event != domain.WebhookEventOrderCreated || payload.ShopID != 1 || payload.Order.Number != "A-1" {
+		t.Fatalf("unexpected payload %s", got.body)
+	}
+
+	waitForDelivery(t, svc, webhooks.items[0].ID, domain.NotificationStatusSent)
+}
+
+func TestWebhookReceivesOnlySubscribedEvents(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewTLSServer(receiver)
+	defer server.Close()
+
+	orders := &MockOrderRepo{}
+	svc := newWebhookService(&MockWebhookRepo{}, NewMockNotificationLogRepo(), orders, trustServer(server))
+	ctx := context.Background()
+
+	input := domain.WebhookInput{URL: server.URL, Secret: webhookSecret, Events: []string{domain.WebhookEventOrderImported}, Enabled: true}
+	if _, err := svc.CreateWebhook(ctx, 1, input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := svc.CreateOrder(ctx, 1, domain.CreateOrderInput{Number: "A-1", Total: 10, CustomerName: "Anna"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rows := []domain.ImportOrderRow{{Line: 2, Order: domain.CreateOrderInput{Number: "A-2", Total: 20, CustomerName: "Boris"}}}
+	if _, err := svc.ImportOrders(ctx, 1, rows, domain.ImportOrdersOptions{Notify: false}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := receiver.wait(t, 1)
+	time.Sleep(50 * time.Millisecond)
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if len(receiver.received) != 1 || got[0].header.Get(webhook.HeaderEvent) != domain.WebhookEventOrderImported {
+		t.Fatalf("expected only the import to be delivered, got %d requests", len(receiver.received))
+	}
+}
+
+func TestWebhookDeliveriesCanBeListedAndRedelivered(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusInternalServerError}}
+	server := httptest.NewTLSServer(receiver)
+	defer server.Close()
+
+	webhooks := &MockWebhookRepo{}
+	svc := newWebhookService(webhooks, NewMockNotificationLogRepo(), &MockOrderRepo{}, trustServer(server))
+	router := newTestRouter(t, svc, domain.NewAPIKeyService(NewMockAPIKeyRepo(), "admin-secret"), newAuthService())
+	ctx := context.Background()
+
+	subscription, err := svc.CreateWebhook(ctx, 1, domain.WebhookInput{URL: server.URL, Secret: webhookSecret, Events: []string{domain.WebhookEventOrderCreated}, Enabled: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.CreateOrder(ctx, 1, domain.CreateOrderInput{Number: "A-1", Total: 10, CustomerName: "Anna"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	failed := waitForDelivery(t, svc, subscription.ID, domain.NotificationStatusFailed)
+	if failed.Error == nil || !strings.Contains(*failed.Error, "500") {
+		t.Fatalf("expected the receiver status in the error, got %+v", failed)
+	}
+
+	path := "/v2/shops/1/webhooks/" + strconv.FormatInt(subscription.ID, 10) + "/deliveries"
+	rec := serveAdmin(router, http.MethodGet, path, "")
+	var list domain.ListWebhookDeliveriesResult
+	decodeJSON(t, rec.Body.Bytes(), &list)
+	if rec.Code != http.StatusOK || len(list.Items) != 1 || list.Items[0].Event != domain.WebhookEventOrderCreated {
+		t.Fatalf("unexpected deliveries %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = serveAdmin(router, http.MethodPost, path+"/"+strconv.FormatInt(failed.ID, 10)+"/redeliver", "")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	received := receiver.wait(t, 2)
+	if string(received[0].body) != string(received[1].body) {
+		t.Fatalf("redelivery should resend the original payload:\n%s\n%s", received[0].body, received[1].body)
+	}
+	if received[1].header.Get(webhook.HeaderDelivery) != strconv.FormatInt(failed.ID, 10) {
+		t.Fatalf("expected delivery ID %d, got %q", failed.ID, received[1].header.Get(webhook.HeaderDelivery))
+	}
+	waitForDelivery(t, svc, subscription.ID, domain.NotificationStatusSent)
+
+	rec = serveAdmin(router, http.MethodPost, path+"/999/redeliver", "")
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "delivery_not_found") {
+		t.Fatalf("expected delivery_not_found, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCreateWebhookValidatesInput(t *testing.T) {
+	svc := newWebhookService(&MockWebhookRepo{}, NewMockNotificationLogRepo(), &MockOrderRepo{}, egress.Config{})
+
+	for _, body := range []string{
+		`{"url":"ftp://example.com/hook","secret":"` + webhookSecret + `","events":["order.created"]}`,
+		`{"url":"http://example.com/hook","secret":"` + webhookSecret + `","events":["order.created"]}`,
+		`{"url":"https://example.com/hook","secret":"short","events":["order.created"]}`,
+		`{"url":"https://example.com/hook","secret":"` + webhookSecret + `","events":["order.deleted"]}`,
+	} {
+		status, problem := doProblem(t, svc, http.MethodPost, "/v2/shops/1/webhooks", body)
+		if status != http.StatusBadRequest || len(problem.Errors) == 0 {
+			t.Fatalf("expected a field error for %s, got %d %+v", body, status, problem)
+		}
+	}
+}
+
+func TestWebhookAuditHidesSecretAndURLPath(t *testing.T) {
+	audit := &MockAuditRepo{}
+	svc := domain.NewService(&MockIntegrationRepo{}, &MockOrderRepo{}, NewMockNotificationLogRepo(), telegramNotifiers(&MockTelegramClient{}), 1,
+		domain.WithAuditLog(audit), domain.WithWebhooks(&MockWebhookRepo{}, webhook.NewNotifier(egress.Config{})))
+
+	input := domain.WebhookInput{URL: "https://hooks.example.com/T000/secret-path", Secret: webhookSecret, Events: []string{domain.WebhookEventOrderCreated}, Enabled: true}
+	if _, err := svc.CreateWebhook(context.Background(), 1, input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	body, _ := json.Marshal(audit.events)
+	if strings.Contains(string(body), webhookSecret) || strings.Contains(string(body), "secret-path") {
+		t.Fatalf("audit leaks the secret or URL path: %s", body)
+	}
+	if audit.events[0].Action != domain.AuditWebhookCreated {
+		t.Fatalf("unexpected action %q", audit.events[0].Action)
+	}
+}
+
+func TestWebhookFailureDoesNotFailSavedOrders(t *testing.T) {
+	webhooks := &MockWebhookRepo{listErr: errors.New("connection reset")}
+	orders := &MockOrderRepo{}
+	svc := newWebhookService(webhooks, NewMockNotificationLogRepo(), orders, egress.Config{Timeout: time.Second})
+
+	if _, err := svc.CreateOrder(context.Background(), 1, domain.CreateOrderInput{Number: "A-1", Total: 100, CustomerName: "Anna"}); err != nil {
+		t.Fatalf("expected the created order despite the webhook failure, got %v", err)
+	}
+
+	out, err := svc.ImportOrders(context.Background(), 1, importRows(3), domain.ImportOrdersOptions{})
+	if err != nil {
+		t.Fatalf("expected the import report despite the webhook failure, got %v", err)
+	}
+	if out.Imported != 3 {
+		t.Fatalf("expected 3 imported rows, got %+v", out)
+	}
+}
@@ -1,11 +1,42 @@
+import { useEffect, useState } from 'react'
 import { Outlet } from 'react-router-dom'
+import { getMe, logout, type Me } from './api/auth'
+import { ApiError } from './api/client'
+import LoginPage from './pages/LoginPage'
 
 function App() {
+  const [me, setMe] = useState<Me | null>(null)
+  const [isLoading, setIsLoading] = useState(true)
+
+  useEffect(() => {
+    getMe()
+      .then(setMe)
+      .catch((error) => {
+        if (!(error instanceof ApiError && error.status === 401)) {
+          console.error('Failed to load session', error)
+        }
+      })
+      .finally(() => setIsLoading(false))
+  }, [])
+
+  async function onLogout() {
+    await logout()
+    setMe(null)
+  }
+
   return (
     <div className="min-h-screen bg-gradient-to-b from-slate-50 to-slate-100">
       <main className="mx-auto w-full max-w-4xl px-4 py-10 sm:px-6 lg:py-14">
+        {me ? (
+          <div className="mb-4 flex items-center justify-end gap-3 text-sm text-slate-600">
+            <span>{me.user.email}</span>
+            <button type="button" onClick={() => void onLogout()} className="font-medium text-blue-600 hover:text-blue-700">
+              Выйти
+            </button>
+          </div>
+        ) : null}
         <div className="rounded-2xl border border-slate-200/80 bg-white p-6 shadow-sm sm:p-8">
-          <Outlet />
+          {isLoading ? null : me ? <Outlet /> : <LoginPage onLogin={setMe} />}
         </div>
       </main>
     </div>
@@ -0,0 +1,38 @@
+import { apiFetch, setCsrfToken } from './client'
+
+export type Role = 'owner' | 'manager' | 'viewer'
+
+export type Me = {
+  user: {
+    id: number
+    email: string
+  }
+  memberships: {
+    shopId: number
+    role: Role
+  }[]
+  csrfToken: string
+}
+
+export async function getMe(): Promise<Me> {
+  const response = await apiFetch('/auth/me')
+  const me = (await response.json()) as Me
+  setCsrfToken(me.csrfToken)
+  return me
+}
+
+export async function login(email: string, password: string): Promise<Me> {
+  const response = await apiFetch('/auth/login', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ email, password }),
+  })
+  const me = (await response.json()) as Me
+  setCsrfToken(me.csrfToken)
+  return me
+}
+
+export async function logout(): Promise<void> {
+  await apiFetch('/auth/logout', { method: 'POST' })
+  setCsrfToken('')
+}
@@ -0,0 +1,72 @@
+export const API_BASE_URL = import.meta.env.VITE_API_BASE_URL ?? '/api'
+const API_VERSION = 'v2'
+
+let csrfToken = ''
+
+export function setCsrfToken(token: string) {
+  csrfToken = token
+}
+
+export type FieldError = {
+  field: string
+  code: string
+  message: string
+}
+
+type Problem = {
+  title?: string
+  detail?: string
+  code?: string
+  errors?: FieldError[]
+}
+
+export class ApiError extends Error {
+  status: number
+  code: string
+  fields: FieldError[]
+
+  constructor(status: number, message: string, code = '', fields: FieldError[] = []) {
+    super(message)
+    this.status = status
+    this.code = code
+    this.fields = fields
+  }
+}
+
+async function parseError(response: Response): Promise<ApiError> {
+  try {
+    const problem = (await response.json()) as Problem
+    const message = problem.errors?.length
+      ? problem.errors.map((field) => field.message).join('; ')
+      : problem.detail || problem.title
+
+    if (message) {
+      return new ApiError(response.status, message, problem.code, problem.errors)
+    }
+  } catch {
+    console.error('Failed to parse error response', response)
+  }
+
+  return new ApiError(response.status, response.statusText || 'Request failed')
+}
+
+export async function apiFetch(path: string, init: RequestInit = {}): Promise<Response> {
+  const method = (init.method ?? 'GET').toUpperCase()
+  const headers = new Headers(init.headers)
+
+  if (csrfToken && method !== 'GET' && method !== 'HEAD') {
+    headers.set('X-CSRF-Token', csrfToken)
+  }
+
+  const response = await fetch(`${API_BASE_URL}/${API_VERSION}${path}`, {
+    ...init,
+    headers,
+    credentials: 'include',
+  })
+
+  if (!response.ok) {
+    throw await parseError(response)
+  }
+
+  return response
+}
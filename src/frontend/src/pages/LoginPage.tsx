@@ -0,0 +1,86 @@
+import { useState } from 'react'
+import type { SubmitEvent } from 'react'
+import { login, type Me } from '../api/auth'
+
+type LoginPageProps = {
+  onLogin: (me: Me) => void
+}
+
+export default function LoginPage({ onLogin }: LoginPageProps) {
+  const [email, setEmail] = useState('')
+  const [password, setPassword] = useState('')
+  const [isSubmitting, setIsSubmitting] = useState(false)
+  const [error, setError] = useState<string | null>(null)
+
+  async function onSubmit(event: SubmitEvent<HTMLFormElement>) {
+    event.preventDefault()
+    setError(null)
+    setIsSubmitting(true)
+
+    try {
+      onLogin(await login(email.trim(), password))
+    } catch (loginError) {
+      const message = loginError instanceof Error ? loginError.message : 'Не удалось войти.'
+      setError(message)
+    } finally {
+      setIsSubmitting(false)
+    }
+  }
+
+  const inputClassName =
+    'w-full rounded-xl border border-slate-300 bg-white px-3 py-2.5 text-sm text-slate-900 placeholder:text-slate-400 shadow-sm outline-none transition focus:border-blue-500 focus:ring-4 focus:ring-blue-100'
+
+  return (
+    <div className="space-y-6">
+      <header className="space-y-2">
+        <h1 className="text-2xl font-semibold text-slate-900 sm:text-3xl">Вход</h1>
+      </header>
+
+      <form onSubmit={onSubmit} className="space-y-4">
+        <div className="space-y-1.5">
+          <label htmlFor="email" className="block text-sm font-medium text-slate-700">
+            Email
+          </label>
+          <input
+            id="email"
+            type="email"
+            autoComplete="username"
+            value={email}
+            onChange={(event) => setEmail(event.target.value)}
+            className={inputClassName}
+            disabled={isSubmitting}
+          />
+        </div>
+
+        <div className="space-y-1.5">
+          <label htmlFor="password" className="block text-sm font-medium text-slate-700">
+            Пароль
+          </label>
+          <input
+            id="password"
+            type="password"
+            autoComplete="current-password"
+            value={password}
+            onChange={(event) => setPassword(event.target.value)}
+            className={inputClassName}
+            disabled={isSubmitting}
+          />
+        </div>
+
+        <button
+          type="submit"
+          disabled={isSubmitting || !email.trim() || !password}
+          className="inline-flex items-center justify-center rounded-xl bg-blue-600 px-4 py-2.5 text-sm font-semibold text-white shadow-sm transition hover:bg-blue-700 disabled:cursor-not-allowed disabled:opacity-60"
+        >
+          {isSubmitting ? 'Вход...' : 'Войти'}
+        </button>
+      </form>
+
+      {error ? (
+        <p className="rounded-xl border border-red-200 border-l-4 border-l-red-400 bg-red-50 px-3 py-2.5 text-sm text-red-700">
+          {error}
+        </p>
+      ) : null}
+    </div>
+  )
+}
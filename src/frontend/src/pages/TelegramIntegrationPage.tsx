@@ -37,9 +37,9 @@ export default function TelegramIntegrationPage() {
     try {
       const data = await getTelegramStatus(currentShopId)
       setStatus(data)
+      // chatId comes back masked, so it cannot be used to prefill the form
       setForm((previous) => ({
         ...previous,
-        chatId: data.chatId || previous.chatId,
         enabled: data.enabled,
       }))
     } catch (loadError) {